@@ -0,0 +1,312 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Attachments - Lab reports, imaging and discharge summaries are too large for the ledger, so the files are kept in
+//				   external storage and the ledger anchors their metadata and SHA-256. Anyone holding a copy of a file
+//				   can then prove it is the one attached. They are called documents by the transactions and attachments
+//				   here, as document already names every stored JSON value.
+//==============================================================================================================================
+const ENTRY_ATTACHMENT = "attachment"
+
+const DOCUMENT_LAB_REPORT = "lab_report"
+const DOCUMENT_IMAGING = "imaging"
+const DOCUMENT_DISCHARGE_SUMMARY = "discharge_summary"
+const DOCUMENT_DEATH_CERTIFICATE = "death_certificate"
+const DOCUMENT_OTHER = "other"
+
+var DOCUMENT_TYPES = []string{DOCUMENT_LAB_REPORT, DOCUMENT_IMAGING, DOCUMENT_DISCHARGE_SUMMARY, DOCUMENT_DEATH_CERTIFICATE, DOCUMENT_OTHER}
+
+//==============================================================================================================================
+//	 Attachment formats - The hash is the lower case hex SHA-256 of the file, the MIME type a type/subtype without
+//						  parameters and the episode an identifier of the admission or encounter the file belongs to.
+//==============================================================================================================================
+const SHA256_PATTERN = `^[0-9a-f]{64}$`
+const SHA256_REASON = "must be the lower case hex SHA-256 of the file"
+
+var sha256_format = regexp.MustCompile(SHA256_PATTERN)
+var mime_format = regexp.MustCompile(`^[a-z]+/[a-z0-9][a-z0-9.+-]*$`)
+var episode_format = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+const MAX_URI_LENGTH = 1024
+
+//==============================================================================================================================
+//	 Attachment - The metadata of a file attached to a member, optionally within one of its episodes of care. Stored
+//				  under attachment:<ILNSID>:<sha256>. Author, Timestamp and Tx_ID are set by the chaincode.
+//==============================================================================================================================
+type Attachment struct {
+	ILNSID         string `json:"ILNSID" metadata:",optional"`
+	Episode        string `json:"episode,omitempty" metadata:",optional"`
+	Type           string `json:"type"`
+	MIME           string `json:"mime"`
+	Size           int64  `json:"size"`
+	SHA256         string `json:"sha256"`
+	URI            string `json:"uri"`
+	Author         string `json:"author" metadata:",optional"`
+	Timestamp      string `json:"timestamp" metadata:",optional"`
+	Tx_ID          string `json:"txID" metadata:",optional"`
+	Schema_Version int    `json:"schemaVersion" metadata:",optional"`
+}
+
+//==============================================================================================================================
+//	 Document_Verification - Returned by verify_document. Document is the anchored record when Verified.
+//==============================================================================================================================
+type Document_Verification struct {
+	ILNSID   string      `json:"ILNSID"`
+	SHA256   string      `json:"sha256"`
+	Verified bool        `json:"verified"`
+	Document *Attachment `json:"document,omitempty" metadata:",optional"`
+}
+
+//==============================================================================================================================
+//	 DocumentContract - Transactions that attach files held in external storage to a member and verify them.
+//==============================================================================================================================
+type DocumentContract struct {
+	contractapi.Contract
+}
+
+//==============================================================================================================================
+//	 validate_attachment - Checks the metadata of a file being attached.
+//==============================================================================================================================
+func validate_attachment(a Attachment) error {
+
+	type_ok := false
+
+	for _, document_type := range DOCUMENT_TYPES {
+		if a.Type == document_type {
+			type_ok = true
+		}
+	}
+
+	if !type_ok {
+		return invalid("type", a.Type, "must be one of "+strings.Join(DOCUMENT_TYPES, ", "))
+	}
+
+	if !mime_format.MatchString(a.MIME) {
+		return invalid("mime", a.MIME, "must be a MIME type e.g. application/pdf")
+	}
+
+	if a.Size < 1 {
+		return invalid("size", strconv.FormatInt(a.Size, 10), "must be the size of the file in bytes")
+	}
+
+	if !sha256_format.MatchString(a.SHA256) {
+		return invalid("sha256", a.SHA256, SHA256_REASON)
+	}
+
+	if u, err := url.Parse(a.URI); err != nil || u.Scheme == "" || len(a.URI) > MAX_URI_LENGTH {
+		return invalid("uri", a.URI, "must be an absolute URI of the stored file")
+	}
+
+	if a.Episode != "" && !episode_format.MatchString(a.Episode) {
+		return invalid("episode", a.Episode, "must be up to 64 letters, digits, dots, dashes or underscores")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 ValidateDocument - Checks the metadata of a file to be attached, so that clients can refuse a file before storing it.
+//==============================================================================================================================
+func ValidateDocument(document Attachment) error {
+	return validate_attachment(document)
+}
+
+//==============================================================================================================================
+//	 retrieve_attachment - Gets the file with the given hash attached to the member. Returns nil if there is none.
+//==============================================================================================================================
+func retrieve_attachment(stub shim.ChaincodeStubInterface, ILNSID string, hash string) (*Attachment, error) {
+
+	var a Attachment
+
+	found, err := read_document(stub, DOC_ATTACHMENT, attachment_key(ILNSID, hash), &a)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &a, nil
+}
+
+//=================================================================================================================================
+//	 attach_document - Anchors the metadata of a file attached to the member. Only the custodian may attach files, which
+//					   may be attached to a dead member (a death certificate, say) but not to an erased one. A file is
+//					   attached to a member once.
+//=================================================================================================================================
+func attach_document(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, a Attachment) (*Attachment, error) {
+
+	if m.Erased {
+		return nil, erased_state(m)
+	}
+
+	if m.Name != caller {
+		return nil, permission_denied("attach_document", map[string]interface{}{"ILNSID": m.ILNSID, "is_custodian": false})
+	}
+
+	if err := validate_attachment(a); err != nil {
+		return nil, err
+	}
+
+	existing, err := retrieve_attachment(stub, m.ILNSID, a.SHA256)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, conflict("Document "+a.SHA256+" is already attached to "+m.ILNSID, map[string]interface{}{"ILNSID": m.ILNSID, "sha256": a.SHA256})
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.ILNSID = m.ILNSID
+	a.Author = caller
+	a.Timestamp = now.Format(time.RFC3339)
+	a.Tx_ID = stub.GetTxID()
+	a.Schema_Version = schema_version(DOC_ATTACHMENT)
+
+	bytes, err := json.Marshal(a)
+
+	if err != nil {
+		return nil, internal("Error converting document record")
+	}
+
+	if err = stub.PutState(attachment_key(m.ILNSID, a.SHA256), bytes); err != nil {
+		return nil, internal("Error storing document record")
+	}
+
+	return &a, nil
+}
+
+//=================================================================================================================================
+//	 list_documents - Returns the files attached to the member in hash order, only those of the episode unless it is
+//					  empty. Visible to the same callers as get_member_details.
+//=================================================================================================================================
+func list_documents(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, episode string) ([]Attachment, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("list_documents", m)
+	}
+
+	keys, err := list_keys(stub, ENTRY_ATTACHMENT, m.ILNSID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []Attachment{}
+
+	for _, key := range keys {
+
+		var a Attachment
+
+		if _, err := read_document(stub, DOC_ATTACHMENT, key, &a); err != nil {
+			return nil, err
+		}
+
+		if episode == "" || a.Episode == episode {
+			attachments = append(attachments, a)
+		}
+	}
+
+	return attachments, nil
+}
+
+//=================================================================================================================================
+//	 verify_document - Checks whether a file with the given hash is attached to the member. Visible to the same callers
+//					   as get_member_details, who hash the file they hold and so learn nothing about files they lack.
+//=================================================================================================================================
+func verify_document(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, hash string) (*Document_Verification, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("verify_document", m)
+	}
+
+	if !sha256_format.MatchString(hash) {
+		return nil, invalid("sha256", hash, SHA256_REASON)
+	}
+
+	a, err := retrieve_attachment(stub, m.ILNSID, hash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document_Verification{ILNSID: m.ILNSID, SHA256: hash, Verified: a != nil, Document: a}, nil
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 AttachDocument - Anchors the metadata and hash of a file held in external storage against the member.
+//=================================================================================================================================
+func (c *DocumentContract) AttachDocument(ctx contractapi.TransactionContextInterface, ILNSID string, document Attachment) (*Attachment, error) {
+
+	var attached *Attachment
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		attached, err = attach_document(stub, m, caller, caller_affiliation, document)
+		return err
+	})
+
+	return attached, err
+}
+
+//=================================================================================================================================
+//	 ListDocuments - Returns the files attached to the member, filtered to episode unless it is empty.
+//=================================================================================================================================
+func (c *DocumentContract) ListDocuments(ctx contractapi.TransactionContextInterface, ILNSID string, episode string) ([]Attachment, error) {
+
+	var attachments []Attachment
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		attachments, err = list_documents(stub, m, caller, caller_affiliation, episode)
+		return err
+	})
+
+	return attachments, err
+}
+
+//=================================================================================================================================
+//	 VerifyDocument - Checks a file, by its SHA-256, against the documents attached to the member.
+//=================================================================================================================================
+func (c *DocumentContract) VerifyDocument(ctx contractapi.TransactionContextInterface, ILNSID string, hash string) (*Document_Verification, error) {
+
+	var verification *Document_Verification
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		verification, err = verify_document(stub, m, caller, caller_affiliation, hash)
+		return err
+	})
+
+	return verification, err
+}
+
+//=================================================================================================================================
+//	 GetEvaluateTransactions - ListDocuments and VerifyDocument are read only and are evaluated rather than submitted.
+//=================================================================================================================================
+func (c *DocumentContract) GetEvaluateTransactions() []string {
+	return []string{"ListDocuments", "VerifyDocument"}
+}
@@ -0,0 +1,149 @@
+// Package chaincode implements the medical history chaincode on the Fabric contract API. The chaincode is split into
+// namespaced contracts, transactions are invoked as <contract>:<Transaction> e.g. lifecycle:ParentsToBirthday.
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/v2/metadata"
+)
+
+//==============================================================================================================================
+//	 Participant types - Each participant type is mapped to the value of the role attribute in the user's certificate
+//==============================================================================================================================
+const PARENTS = "parents"
+const BIRTHDAY = "birthday"
+const HEALTHY = "healthy"
+const ILLNESS = "illness"
+const DEATH = "death"
+const ADMIN = "admin"
+
+//==============================================================================================================================
+//	 Status types - Asset lifecycle is broken down into 5 statuses, this is part of the business logic to determine what can
+//					be done to the member at points in it's lifecycle
+//==============================================================================================================================
+const STATE_CARRYING = 0
+const STATE_BIRTH = 1
+const STATE_HEALTHY = 2
+const STATE_ILLNESS = 3
+const STATE_DEATH = 4
+
+var STATUS_NAMES = []string{"carrying", "birth", "healthy", "illness", "death"} // Indexed by status, for clients
+
+//==============================================================================================================================
+//	 Contract names - The namespace each group of transactions is registered under.
+//==============================================================================================================================
+const MEMBER_CONTRACT = "member"
+const LIFECYCLE_CONTRACT = "lifecycle"
+const CONSENT_CONTRACT = "consent"
+const DOCUMENT_CONTRACT = "document"
+const CREDENTIAL_CONTRACT = "credential"
+const IMMUNIZATION_CONTRACT = "immunization"
+const REGISTRY_CONTRACT = "registry"
+const QUERY_CONTRACT = "query"
+
+const VERSION = "2.0.0"
+
+//==============================================================================================================================
+//	 Caller attributes - Names of the certificate attributes (issued by the Fabric CA) holding the username and role of
+//						 the client.
+//==============================================================================================================================
+const USERNAME_ATTRIBUTE = "username"
+const ROLE_ATTRIBUTE = "role"
+
+//==============================================================================================================================
+//	 get_caller_data - Resolves the username and role of the client that submitted the transaction from the attributes
+//					   of its certificate.
+//==============================================================================================================================
+func get_caller_data(ctx contractapi.TransactionContextInterface) (string, string, error) {
+
+	identity := ctx.GetClientIdentity()
+
+	if identity == nil {
+		return "", "", internal("Error retrieving caller information")
+	}
+
+	user, found, err := identity.GetAttributeValue(USERNAME_ATTRIBUTE)
+
+	if err != nil || !found {
+		return "", "", permission_denied("Couldn't get attribute '"+USERNAME_ATTRIBUTE+"'", map[string]interface{}{"attribute": USERNAME_ATTRIBUTE})
+	}
+
+	affiliation, found, err := identity.GetAttributeValue(ROLE_ATTRIBUTE)
+
+	if err != nil || !found {
+		return "", "", permission_denied("Couldn't get attribute '"+ROLE_ATTRIBUTE+"'", map[string]interface{}{"attribute": ROLE_ATTRIBUTE})
+	}
+
+	return user, affiliation, nil
+}
+
+//==============================================================================================================================
+//	 Contracts - Returns every contract of the chaincode, each registered under its own namespace. The arguments of every
+//				 transaction are checked against the FUNCTIONS registry before it is dispatched.
+//==============================================================================================================================
+func Contracts() []contractapi.ContractInterface {
+
+	member := new(MemberContract)
+	member.Name = MEMBER_CONTRACT
+	member.Info = metadata.InfoMetadata{Title: "Member", Version: VERSION, Description: "Creates members and updates their demographic fields and vitals"}
+	member.BeforeTransaction = check_arguments(MEMBER_CONTRACT)
+
+	lifecycle := new(LifecycleContract)
+	lifecycle.Name = LIFECYCLE_CONTRACT
+	lifecycle.Info = metadata.InfoMetadata{Title: "Lifecycle", Version: VERSION, Description: "Moves members between custodians and statuses"}
+	lifecycle.BeforeTransaction = check_arguments(LIFECYCLE_CONTRACT)
+
+	consent := new(ConsentContract)
+	consent.Name = CONSENT_CONTRACT
+	consent.Info = metadata.InfoMetadata{Title: "Consent", Version: VERSION, Description: "Grants and revokes read access to member records"}
+	consent.BeforeTransaction = check_arguments(CONSENT_CONTRACT)
+
+	document := new(DocumentContract)
+	document.Name = DOCUMENT_CONTRACT
+	document.Info = metadata.InfoMetadata{Title: "Document", Version: VERSION, Description: "Anchors and verifies files attached to members"}
+	document.BeforeTransaction = check_arguments(DOCUMENT_CONTRACT)
+
+	credential := new(CredentialContract)
+	credential.Name = CREDENTIAL_CONTRACT
+	credential.Info = metadata.InfoMetadata{Title: "Credential", Version: VERSION, Description: "Issues and revokes verifiable credentials of facts about members"}
+	credential.BeforeTransaction = check_arguments(CREDENTIAL_CONTRACT)
+
+	immunization := new(ImmunizationContract)
+	immunization.Name = IMMUNIZATION_CONTRACT
+	immunization.Info = metadata.InfoMetadata{Title: "Immunization", Version: VERSION, Description: "Records the vaccinations given to members"}
+	immunization.BeforeTransaction = check_arguments(IMMUNIZATION_CONTRACT)
+
+	registry := new(RegistryContract)
+	registry.Name = REGISTRY_CONTRACT
+	registry.Info = metadata.InfoMetadata{Title: "Registry", Version: VERSION, Description: "Participants, reference data and administration of the ledger"}
+	registry.BeforeTransaction = check_arguments(REGISTRY_CONTRACT)
+
+	query := new(QueryContract)
+	query.Name = QUERY_CONTRACT
+	query.Info = metadata.InfoMetadata{Title: "Query", Version: VERSION, Description: "Read only queries"}
+	query.BeforeTransaction = check_arguments(QUERY_CONTRACT)
+
+	return []contractapi.ContractInterface{member, lifecycle, consent, document, credential, immunization, registry, query}
+}
+
+//==============================================================================================================================
+//	 New - Creates the chaincode. Transaction metadata for client generation is served by the contract API under
+//		   org.hyperledger.fabric:GetMetadata.
+//==============================================================================================================================
+func New() (*contractapi.ContractChaincode, error) {
+
+	cc, err := contractapi.NewChaincode(Contracts()...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cc.DefaultContract = MEMBER_CONTRACT
+	cc.Info = metadata.InfoMetadata{
+		Title:       "Medical History",
+		Version:     VERSION,
+		Description: "Lifecycle of a member's medical history from birth to death",
+	}
+
+	return cc, nil
+}
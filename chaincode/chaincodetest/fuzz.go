@@ -0,0 +1,566 @@
+package chaincodetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+)
+
+//==============================================================================================================================
+//	 Invariants - Properties that must hold after every transaction, whoever calls it and whatever it is passed.
+//==============================================================================================================================
+const INVARIANT_NO_PANIC = "no_panic"
+const INVARIANT_DEAD_UNCHANGED = "dead_unchanged" // except by erasure
+const INVARIANT_ERASED_UNCHANGED = "erased_unchanged"
+const INVARIANT_STATUS_EDGE = "status_edge"
+const INVARIANT_INDEX = "index_matches_records"
+
+//==============================================================================================================================
+//	 STATUS_EDGES - The status changes the lifecycle allows. A member keeps its status or moves along one of these.
+//==============================================================================================================================
+var STATUS_EDGES = map[int][]int{
+	chaincode.STATE_CARRYING: {chaincode.STATE_BIRTH},
+	chaincode.STATE_BIRTH:    {chaincode.STATE_HEALTHY},
+	chaincode.STATE_HEALTHY:  {chaincode.STATE_ILLNESS, chaincode.STATE_DEATH},
+	chaincode.STATE_ILLNESS:  {chaincode.STATE_HEALTHY, chaincode.STATE_DEATH},
+	chaincode.STATE_DEATH:    {},
+}
+
+//==============================================================================================================================
+//	 Violation - An invariant that failed after the step at index Step of a sequence.
+//==============================================================================================================================
+type Violation struct {
+	Step      int
+	Invariant string
+	Detail    string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("step %d: %s: %s", v.Step+1, v.Invariant, v.Detail)
+}
+
+//==============================================================================================================================
+//	 Fuzzer - Generates random sequences of invokes and checks the invariants after each one. Callers are drawn from
+//			  Identities, ILNSIDs and every other string argument from small pools so that sequences keep hitting the
+//			  same members. Hints gives the pools for the arguments of a function that need a value of their own kind,
+//			  e.g. a recipient or a blood group, where an empty pool takes the default.
+//==============================================================================================================================
+type Fuzzer struct {
+	Rand       *rand.Rand
+	Seed       int64
+	Length     int
+	Identities map[string]string
+	ILNSIDs    []string
+	Strings    []string
+	Hints      map[string][][]string
+	Functions  []string
+
+	usernames []string
+	params    map[string][]reflect.Type
+}
+
+//==============================================================================================================================
+//	 NewFuzzer - Creates a fuzzer over every transaction of every contract with the default pools.
+//==============================================================================================================================
+func NewFuzzer(seed int64, length int) *Fuzzer {
+
+	f := &Fuzzer{
+		Rand:   rand.New(rand.NewSource(seed)),
+		Seed:   seed,
+		Length: length,
+		Identities: map[string]string{
+			"parents1": chaincode.PARENTS, "parents2": chaincode.PARENTS,
+			"birthday1": chaincode.BIRTHDAY, "birthday2": chaincode.BIRTHDAY,
+			"healthy1": chaincode.HEALTHY, "healthy2": chaincode.HEALTHY,
+			"illness1": chaincode.ILLNESS, "illness2": chaincode.ILLNESS,
+			"death1": chaincode.DEATH, "admin1": chaincode.ADMIN,
+			"norole": "", "intruder": "unknown",
+		},
+		ILNSIDs: []string{"AB12345679", "CD76543215", "EF11111116"},
+		Strings: []string{
+			"", "junk", "ab", "ILNSIDs", "Participants", "2023-06-01", "2023-12-31", "2030-01-01", "not a date",
+			"male", "female", "other", "O+", "AB-", "Z+", "3.5kg", "3500 g", "900kg", "-1kg",
+			`{"DOB":"2023-06-01","gender":"female","BloodGrp":"A+","Weight":"3.2kg"}`, `{"status":4}`, `{`,
+			"2024-06-01T00:00:00Z", "2023-01-01T00:00:00Z",
+			`[{"ILNSID":"GH22222221"}]`, `[{"ILNSID":"AB12345679","parents":["CD76543215"]}]`, "ILNSID\nIJ33333336\n",
+			"all_or_nothing", "best_effort", "0",
+		},
+		params: map[string][]reflect.Type{},
+	}
+
+	usernames := []string{}
+
+	for username := range f.Identities {
+		usernames = append(usernames, username)
+	}
+
+	sort.Strings(usernames)
+
+	f.usernames = usernames
+
+	timestamps := []string{"2024-06-01T00:00:00Z", "2023-01-01T00:00:00Z", "tomorrow"}
+
+	f.Hints = map[string][][]string{
+		"member:UpdateDOB":      {nil, {"2023-06-01", "2023-06-01", "2023-12-31", "2030-01-01", "not a date"}},
+		"member:UpdateGender":   {nil, {"male", "female", "other", "x"}},
+		"member:UpdateBloodGrp": {nil, {"O+", "AB-", "Z+"}},
+		"member:UpdateWeight":   {nil, {"7kg", "7kg", "7000 g", "3.5kg", "900kg", "-1kg"}},
+		"member:UpdateMember":   {nil, {`{"DOB":"2023-06-01","gender":"female","BloodGrp":"A+","Weight":"7kg"}`, `{"DOB":"2023-06-01","gender":"female","BloodGrp":"A+","Weight":"7kg"}`, `{"DOB":"2030-01-01"}`, `{"status":4}`, `{`}},
+		"member:ImportMembers":  {{`[{"ILNSID":"GH22222221"}]`, `[{"ILNSID":"AB12345679","parents":["CD76543215"]}]`, "ILNSID\nIJ33333336\n", "["}, {"all_or_nothing", "best_effort", ""}},
+		"consent:GrantConsent":  {nil, usernames, timestamps},
+		"consent:RevokeConsent": {nil, usernames},
+		"document:AttachDocument": {nil, {`{"type":"lab_report","mime":"application/pdf","size":17,"sha256":"edfea949798474aa50ca79259443cc574f9f835c19a7809879169569a331c6a9","uri":"file:///documents/a"}`,
+			`{"type":"x-ray","mime":"image/png","size":1,"sha256":"0","uri":"x"}`, `{`}},
+		"document:VerifyDocument":     {nil, {"edfea949798474aa50ca79259443cc574f9f835c19a7809879169569a331c6a9", "EDFEA949"}},
+		"credential:IssueCredential":  {nil, append(append([]string{}, chaincode.CREDENTIAL_TYPES...), "allergy"), {"", "2030-01-01T00:00:00Z", "2023-01-01T00:00:00Z"}},
+		"credential:RevokeCredential": {nil, {"urn:medhist:credential:tx1", "tx1"}, {""}},
+		"immunization:RecordImmunization": {nil, {`{"vaccine":"MMR","dose":1,"lot":"A1","practitioner":"Dr. Osei","date":"2023-12-01","site":"left_arm"}`,
+			`{"vaccine":"MMR","dose":2,"lot":"A2","practitioner":"Dr. Osei","date":"2030-01-01","site":"left_arm"}`, `{"vaccine":"MMR","dose":0}`, `{`}},
+		"immunization:ListImmunizations":    {nil, {"", "MMR"}},
+		"registry:LoadImmunizationSchedule": {{`{"vaccine":"MMR","name":"MMR","doses":[{"dose":1,"ageDays":365,"graceDays":30}]}`, `{"vaccine":"MMR","doses":[]}`}},
+		"query:GetOverdueMembers":           {{"MMR", "BCG", "?"}},
+		"registry:AddEcert":                 {append([]string{"ILNSIDs", "index:ILNSIDs", "member:AB12345679", "AB12345679", "consent:AB12345679:x"}, usernames...), {"-----BEGIN CERTIFICATE-----", ""}},
+		"query:CheckImport":                 {{`[{"ILNSID":"GH22222221"}]`, "["}, {"all_or_nothing", "best_effort"}},
+		"query:GetImportReport":             {{"tx1", "tx2", "tx3"}},
+		"query:ProveFields":                 {nil, {`["BloodGrp"]`, `["diagnoses","notes"]`, `["name"]`, `[`}},
+		"query:VerifyCredential":            {{"e30.e30.", "not a jwt"}},
+		"lifecycle:DeadMember":              {nil},
+	}
+
+	transitions := map[string]string{
+		"ParentsToBirthday": chaincode.BIRTHDAY, "BirthdayToHealthy": chaincode.HEALTHY,
+		"HealthyToIllness": chaincode.ILLNESS, "IllnessToIllness": chaincode.ILLNESS, "IllnessToHealthy": chaincode.HEALTHY,
+		"HealthyToDeath": chaincode.DEATH, "IllnessToDeath": chaincode.DEATH,
+	}
+
+	for transition, role := range transitions { // The recipient usually has the role the transition hands over to
+		recipients := append([]string{}, usernames...)
+		for _, username := range usernames {
+			if f.Identities[username] == role {
+				recipients = append(recipients, username, username, username)
+			}
+		}
+		f.Hints[chaincode.LIFECYCLE_CONTRACT+":"+transition] = [][]string{nil, recipients}
+	}
+
+	for _, contract := range chaincode.Contracts() {
+
+		name := contract.GetName()
+		t := reflect.TypeOf(contract)
+
+		for i := 0; i < t.NumMethod(); i++ {
+
+			method := t.Method(i)
+
+			if !is_transaction(method.Type, 1) {
+				continue
+			}
+
+			function := name + ":" + method.Name
+			params := []reflect.Type{}
+
+			for j := 2; j < method.Type.NumIn(); j++ {
+				params = append(params, method.Type.In(j))
+			}
+
+			f.Functions = append(f.Functions, function)
+			f.params[function] = params
+		}
+	}
+
+	sort.Strings(f.Functions)
+
+	return f
+}
+
+//==============================================================================================================================
+//	 Run - Generates and runs one sequence. Returns the sequence up to and including the first step that broke an
+//		   invariant, or the whole sequence and nil.
+//==============================================================================================================================
+func (f *Fuzzer) Run() (*Scenario, *Violation) {
+
+	sc := &Scenario{
+		Name:        fmt.Sprintf("fuzz-%d", f.Seed),
+		Description: fmt.Sprintf("generated with seed %d", f.Seed),
+		Identities:  f.Identities,
+		Transient:   map[string]string{chaincode.TRANSIENT_ENTROPY: fmt.Sprintf("fuzz entropy, seed %d", f.Seed)},
+	}
+
+	h, _ := sc.Harness()
+
+	for i := 0; i < f.Length; i++ {
+
+		step := f.next(h)
+
+		sc.Steps = append(sc.Steps, step)
+
+		if v := check_step(h, step); v != nil {
+			v.Step = i
+			return sc, v
+		}
+	}
+
+	return sc, nil
+}
+
+//==============================================================================================================================
+//	 PROGRESS - The transactions that move a member on from each status when made by its custodian. Guided steps pick
+//				from these so that sequences reach the later statuses. A dead member has none, guided steps on it
+//				are made with a random transaction.
+//==============================================================================================================================
+var PROGRESS = map[int][]string{
+	chaincode.STATE_CARRYING: {"lifecycle:ParentsToBirthday", "member:UpdateBloodGrp"},
+	chaincode.STATE_BIRTH:    {"member:UpdateDOB", "member:UpdateGender", "member:UpdateBloodGrp", "member:UpdateWeight", "member:UpdateMember", "lifecycle:BirthdayToHealthy"},
+	chaincode.STATE_HEALTHY:  {"lifecycle:HealthyToIllness", "lifecycle:HealthyToDeath", "member:RecordObservation", "consent:GrantConsent"},
+	chaincode.STATE_ILLNESS:  {"lifecycle:IllnessToIllness", "lifecycle:IllnessToHealthy", "lifecycle:IllnessToDeath"},
+	chaincode.STATE_DEATH:    {"lifecycle:DeadMember"},
+}
+
+//==============================================================================================================================
+//	 next - Generates a step. Half the steps are random, the other half are guided: made on a member from the pool by its
+//			custodian with a transaction that can move it on, or creating it if it does not exist yet.
+//==============================================================================================================================
+func (f *Fuzzer) next(h *Harness) Step {
+
+	if f.Rand.Intn(2) == 0 {
+		return f.random_step()
+	}
+
+	ILNSID := f.ILNSIDs[f.Rand.Intn(len(f.ILNSIDs))]
+
+	var m chaincode.Member
+
+	stored, exists := h.Stub.State[chaincode.Key(chaincode.ENTRY_MEMBER, ILNSID)]
+
+	if !exists || json.Unmarshal(stored, &m) != nil {
+		step := f.step("member:CreateMember", f.with_role(chaincode.PARENTS))
+		step.Args[0], _ = json.Marshal(ILNSID)
+		step.Args[1] = json.RawMessage("[]")
+		return step
+	}
+
+	pool := PROGRESS[m.Status]
+
+	if m.Dead || m.Erased {
+		pool = f.Functions
+	}
+
+	function := pool[f.Rand.Intn(len(pool))]
+	step := f.step(function, m.Name)
+
+	if len(step.Args) > 0 && f.params[function][0].Kind() == reflect.String {
+		step.Args[0], _ = json.Marshal(ILNSID)
+	}
+
+	return step
+}
+
+//==============================================================================================================================
+//	 random_step - Any transaction, by any caller, with random arguments.
+//==============================================================================================================================
+func (f *Fuzzer) random_step() Step {
+	return f.step(f.Functions[f.Rand.Intn(len(f.Functions))], f.usernames[f.Rand.Intn(len(f.usernames))])
+}
+
+//==============================================================================================================================
+//	 step - A step calling the function as the caller with random arguments.
+//==============================================================================================================================
+func (f *Fuzzer) step(function string, caller string) Step {
+
+	step := Step{Invoke: function, As: caller, Args: []json.RawMessage{}}
+
+	for i, t := range f.params[function] {
+		step.Args = append(step.Args, f.arg(function, i, t))
+	}
+
+	return step
+}
+
+//==============================================================================================================================
+//	 with_role - A random caller with the role given.
+//==============================================================================================================================
+func (f *Fuzzer) with_role(role string) string {
+
+	usernames := []string{}
+
+	for _, username := range f.usernames {
+		if f.Identities[username] == role {
+			usernames = append(usernames, username)
+		}
+	}
+
+	if len(usernames) == 0 {
+		return f.usernames[f.Rand.Intn(len(f.usernames))]
+	}
+
+	return usernames[f.Rand.Intn(len(usernames))]
+}
+
+//==============================================================================================================================
+//	 arg - A random argument for a parameter. Strings are usually drawn from the hint for the parameter and the first
+//		   string parameter is otherwise usually an ILNSID.
+//==============================================================================================================================
+func (f *Fuzzer) arg(function string, i int, t reflect.Type) json.RawMessage {
+
+	var v interface{}
+
+	var hint []string
+
+	if hints := f.Hints[function]; i < len(hints) {
+		hint = hints[i]
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		switch {
+		case len(hint) > 0 && f.Rand.Intn(5) != 0:
+			v = hint[f.Rand.Intn(len(hint))]
+		case i == 0 && f.Rand.Intn(5) != 0:
+			v = f.ILNSIDs[f.Rand.Intn(len(f.ILNSIDs))]
+		case f.Rand.Intn(2) == 0:
+			v = f.usernames[f.Rand.Intn(len(f.usernames))]
+		default:
+			v = f.Strings[f.Rand.Intn(len(f.Strings))]
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v = []int{0, 1, 2, -1, 1000}[f.Rand.Intn(5)]
+	case reflect.Slice:
+		v = [][]string{{}, {}, {}, {f.ILNSIDs[0]}, {f.ILNSIDs[1], f.ILNSIDs[2]}, {"junk"}}[f.Rand.Intn(6)]
+	default:
+		return json.RawMessage([]string{
+			`{}`,
+			`{"type":"weight","value":3.4,"unit":"kg"}`,
+			`{"type":"blood_pressure","systolic":120,"diastolic":80,"unit":"mmHg"}`,
+			`{"indicator":"weight","sex":"female","ageUnit":"days","points":[{"age":0,"L":0.38,"M":3.23,"S":0.14}]}`,
+		}[f.Rand.Intn(4)])
+	}
+
+	raw, _ := json.Marshal(v)
+
+	return raw
+}
+
+//==============================================================================================================================
+//	 Check - Replays a sequence against a fresh ledger and returns the first invariant it breaks. Expectations of the
+//			 steps are ignored.
+//==============================================================================================================================
+func Check(sc *Scenario) *Violation {
+
+	h, err := sc.Harness()
+
+	if err != nil {
+		return &Violation{Invariant: "scenario", Detail: err.Error()}
+	}
+
+	for i, step := range sc.Steps {
+		if v := check_step(h, step); v != nil {
+			v.Step = i
+			return v
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 Shrink - Removes as many steps as possible while the sequence still breaks the same invariant, first in large
+//			  chunks and then one step at a time.
+//==============================================================================================================================
+func Shrink(sc *Scenario, v *Violation) (*Scenario, *Violation) {
+
+	best := *sc
+	best.Steps = append([]Step{}, sc.Steps[:v.Step+1]...)
+
+	for chunk := len(best.Steps) / 2; chunk >= 1; chunk /= 2 {
+
+		for start := 0; start+chunk <= len(best.Steps); {
+
+			candidate := best
+			candidate.Steps = append(append([]Step{}, best.Steps[:start]...), best.Steps[start+chunk:]...)
+
+			if cv := Check(&candidate); cv != nil && cv.Invariant == v.Invariant {
+				best, v = candidate, cv
+				best.Steps = best.Steps[:cv.Step+1]
+				continue
+			}
+
+			start++
+		}
+	}
+
+	best.Description = fmt.Sprintf("%s, shrunk from %d steps. %s", sc.Description, len(sc.Steps), v.Error())
+
+	return &best, v
+}
+
+//==============================================================================================================================
+//	 member_record - What the invariants look at in a stored member.
+//==============================================================================================================================
+type member_record struct {
+	bytes  []byte
+	status int
+	dead   bool
+	erased bool
+}
+
+//==============================================================================================================================
+//	 member_records - Every member stored in the world state, found without using the index: any document whose key is
+//					  the member key of its ILNSID field. Records are keyed by ILNSID.
+//==============================================================================================================================
+func member_records(state map[string][]byte) map[string]member_record {
+
+	records := map[string]member_record{}
+
+	for key, value := range state {
+
+		var m chaincode.Member
+
+		if json.Unmarshal(value, &m) != nil || key != chaincode.Key(chaincode.ENTRY_MEMBER, m.ILNSID) {
+			continue
+		}
+
+		records[m.ILNSID] = member_record{bytes: value, status: m.Status, dead: m.Dead, erased: m.Erased}
+	}
+
+	return records
+}
+
+//==============================================================================================================================
+//	 check_step - Invokes a step, recovering any panic, and checks the invariants against the ledger before and after.
+//==============================================================================================================================
+func check_step(h *Harness, step Step) (v *Violation) {
+
+	before := member_records(h.Stub.State)
+
+	caller, _ := h.Identity(step.As)
+	args, err := step_args(step.Args)
+
+	if err != nil {
+		return &Violation{Invariant: "scenario", Detail: err.Error()}
+	}
+
+	function := step.Invoke + step.Query
+
+	panicked := func() (p interface{}) {
+		defer func() {
+			if p = recover(); p != nil {
+				h.Stub.RollbackTx()
+			}
+		}()
+		h.Invoke(caller, function, args...)
+		return nil
+	}()
+
+	if panicked != nil {
+		return &Violation{Invariant: INVARIANT_NO_PANIC, Detail: fmt.Sprintf("%s %s panicked: %v", function, strings.Join(args, " "), panicked)}
+	}
+
+	after := member_records(h.Stub.State)
+
+	ILNSIDs := sorted_keys(before, after)
+
+	for _, ILNSID := range ILNSIDs {
+
+		old, existed := before[ILNSID]
+		now, exists := after[ILNSID]
+
+		changed := existed && (!exists || !bytes.Equal(old.bytes, now.bytes))
+
+		if changed && old.erased {
+			return &Violation{Invariant: INVARIANT_ERASED_UNCHANGED, Detail: fmt.Sprintf("%s changed erased member %s from %s to %s", function, ILNSID, old.bytes, now.bytes)}
+		}
+
+		if changed && old.dead && !(exists && now.erased) {
+			return &Violation{Invariant: INVARIANT_DEAD_UNCHANGED, Detail: fmt.Sprintf("%s changed dead member %s from %s to %s", function, ILNSID, old.bytes, now.bytes)}
+		}
+
+		if exists && now.dead && now.status != chaincode.STATE_DEATH {
+			return &Violation{Invariant: INVARIANT_STATUS_EDGE, Detail: fmt.Sprintf("%s left %s dead with status %d", function, ILNSID, now.status)}
+		}
+
+		if !exists || (existed && old.status == now.status) {
+			continue
+		}
+
+		if !existed {
+			if now.status != chaincode.STATE_CARRYING {
+				return &Violation{Invariant: INVARIANT_STATUS_EDGE, Detail: fmt.Sprintf("%s created %s with status %d", function, ILNSID, now.status)}
+			}
+			continue
+		}
+
+		allowed := false
+
+		for _, next := range STATUS_EDGES[old.status] {
+			allowed = allowed || next == now.status
+		}
+
+		if !allowed {
+			return &Violation{Invariant: INVARIANT_STATUS_EDGE, Detail: fmt.Sprintf("%s moved %s from status %d to %d", function, ILNSID, old.status, now.status)}
+		}
+	}
+
+	return check_index(h, function, after)
+}
+
+//==============================================================================================================================
+//	 check_index - The ILNSIDs index lists every stored member exactly once and nothing else.
+//==============================================================================================================================
+func check_index(h *Harness, function string, records map[string]member_record) *Violation {
+
+	var index chaincode.ILNS_Holder
+
+	if stored, ok := h.Stub.State[chaincode.Key(chaincode.ENTRY_INDEX, chaincode.INDEX_ILNSIDS)]; ok {
+		if err := json.Unmarshal(stored, &index); err != nil {
+			return &Violation{Invariant: INVARIANT_INDEX, Detail: fmt.Sprintf("%s left an unreadable index %s", function, stored)}
+		}
+	}
+
+	listed := map[string]bool{}
+
+	for _, ILNSID := range index.ILNSs {
+
+		if listed[ILNSID] {
+			return &Violation{Invariant: INVARIANT_INDEX, Detail: fmt.Sprintf("%s listed %s twice", function, ILNSID)}
+		}
+
+		if _, ok := records[ILNSID]; !ok {
+			return &Violation{Invariant: INVARIANT_INDEX, Detail: fmt.Sprintf("%s left %s in the index with no record", function, ILNSID)}
+		}
+
+		listed[ILNSID] = true
+	}
+
+	for ILNSID := range records {
+		if !listed[ILNSID] {
+			return &Violation{Invariant: INVARIANT_INDEX, Detail: fmt.Sprintf("%s stored %s without indexing it", function, ILNSID)}
+		}
+	}
+
+	return nil
+}
+
+func sorted_keys(a map[string]member_record, b map[string]member_record) []string {
+
+	keys := []string{}
+
+	for key := range a {
+		keys = append(keys, key)
+	}
+
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
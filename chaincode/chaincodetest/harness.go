@@ -0,0 +1,306 @@
+package chaincodetest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+)
+
+var context_type = reflect.TypeOf((*contractapi.TransactionContextInterface)(nil)).Elem()
+var error_type = reflect.TypeOf((*error)(nil)).Elem()
+
+//==============================================================================================================================
+//	 Harness - Runs transactions of the chaincode contracts against a MockStub, as the contract API would on a peer.
+//			   Functions are named <contract>:<Transaction>, a name without a contract goes to the default contract.
+//			   Arguments are passed as strings and converted to the parameter types of the transaction the same way
+//			   the contract API converts them: strings as is, numbers and booleans parsed and anything else as JSON.
+//			   The before transaction handler of the contract runs first, as it does on a peer. Transient is passed as
+//			   the transient map of every transaction given none, as a client passes the same entries with each
+//			   transaction; it is empty unless set. Result is the payload of the last step run by RunStep.
+//==============================================================================================================================
+type Harness struct {
+	Stub       *MockStub
+	Contracts  map[string]contractapi.ContractInterface
+	Default    string
+	Identities map[string]*Identity
+	Transient  map[string][]byte
+	Result     []byte
+}
+
+//==============================================================================================================================
+//	 New - Creates a harness over an empty ledger with the clock at the time given.
+//==============================================================================================================================
+func New(clock time.Time) *Harness {
+
+	h := &Harness{
+		Stub:       NewMockStub("medhist", clock),
+		Contracts:  map[string]contractapi.ContractInterface{},
+		Default:    chaincode.MEMBER_CONTRACT,
+		Identities: map[string]*Identity{},
+	}
+
+	for _, contract := range chaincode.Contracts() {
+		h.Contracts[contract.GetName()] = contract
+	}
+
+	return h
+}
+
+//==============================================================================================================================
+//	 AddIdentity - Registers a caller that can then be referred to by username.
+//==============================================================================================================================
+func (h *Harness) AddIdentity(username string, role string) *Identity {
+
+	id := NewIdentity(username, role)
+
+	h.Identities[username] = id
+
+	return id
+}
+
+//==============================================================================================================================
+//	 Identity - The registered caller with the username given.
+//==============================================================================================================================
+func (h *Harness) Identity(username string) (*Identity, error) {
+
+	id, ok := h.Identities[username]
+
+	if !ok {
+		return nil, errors.New("no identity registered for " + username)
+	}
+
+	return id, nil
+}
+
+//==============================================================================================================================
+//	 Invoke - Submits a transaction as the caller. The write set is committed if the transaction succeeds and discarded
+//			  if it fails. Returns the response payload as the contract API would serialize it.
+//==============================================================================================================================
+func (h *Harness) Invoke(caller *Identity, function string, args ...string) ([]byte, error) {
+	return h.Transact(caller, nil, true, function, args...)
+}
+
+//==============================================================================================================================
+//	 Query - Evaluates a transaction as the caller. Nothing is ever written.
+//==============================================================================================================================
+func (h *Harness) Query(caller *Identity, function string, args ...string) ([]byte, error) {
+	return h.Transact(caller, nil, false, function, args...)
+}
+
+//==============================================================================================================================
+//	 Transact - Runs a transaction with a transient map, committing its writes if submit is set and it succeeds.
+//==============================================================================================================================
+func (h *Harness) Transact(caller *Identity, transient map[string][]byte, submit bool, function string, args ...string) ([]byte, error) {
+
+	contract, method, err := h.lookup(function)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var creator []byte
+
+	if caller != nil {
+		creator = caller.creator()
+	}
+
+	if transient == nil {
+		transient = h.Transient
+	}
+
+	h.Stub.BeginTx(creator, transient, function, args...)
+
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(h.Stub)
+
+	if caller != nil {
+		ctx.SetClientIdentity(caller)
+	}
+
+	var payload []byte
+
+	if before, ok := contract.GetBeforeTransaction().(func(contractapi.TransactionContextInterface) error); ok && before != nil {
+		err = before(ctx)
+	}
+
+	if err == nil {
+		payload, err = call(ctx, method, args)
+	}
+
+	if err == nil && submit {
+		h.Stub.CommitTx()
+	} else {
+		h.Stub.RollbackTx()
+	}
+
+	return payload, err
+}
+
+//==============================================================================================================================
+//	 Functions - Every transaction of every contract, by the name used to invoke it.
+//==============================================================================================================================
+func (h *Harness) Functions() []string {
+
+	names := []string{}
+
+	for name, contract := range h.Contracts {
+
+		t := reflect.TypeOf(contract)
+
+		for i := 0; i < t.NumMethod(); i++ {
+			if is_transaction(t.Method(i).Type, 1) {
+				names = append(names, name+":"+t.Method(i).Name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+//==============================================================================================================================
+//	 lookup - Finds the contract and method implementing a transaction.
+//==============================================================================================================================
+func (h *Harness) lookup(function string) (contractapi.ContractInterface, reflect.Value, error) {
+
+	contract_name, name := h.Default, function
+
+	if i := strings.LastIndex(function, ":"); i >= 0 {
+		contract_name, name = function[:i], function[i+1:]
+	}
+
+	contract, ok := h.Contracts[contract_name]
+
+	if !ok {
+		return nil, reflect.Value{}, errors.New("Contract not found with name " + contract_name)
+	}
+
+	method := reflect.ValueOf(contract).MethodByName(name)
+
+	if !method.IsValid() || !is_transaction(method.Type(), 0) {
+		return nil, reflect.Value{}, fmt.Errorf("Function %s not found in contract %s", name, contract_name)
+	}
+
+	return contract, method, nil
+}
+
+//==============================================================================================================================
+//	 is_transaction - A transaction is a method taking the transaction context first. first is the index of the context
+//					  parameter, 1 for method types that include the receiver.
+//==============================================================================================================================
+func is_transaction(t reflect.Type, first int) bool {
+	return t.NumIn() > first && t.In(first) == context_type
+}
+
+//==============================================================================================================================
+//	 call - Converts the arguments, calls the transaction and serializes its result.
+//==============================================================================================================================
+func call(ctx *contractapi.TransactionContext, method reflect.Value, args []string) ([]byte, error) {
+
+	t := method.Type()
+
+	if len(args) != t.NumIn()-1 {
+		return nil, fmt.Errorf("Incorrect number of params. Expected %d, received %d", t.NumIn()-1, len(args))
+	}
+
+	in := []reflect.Value{reflect.ValueOf(ctx)}
+
+	for i, arg := range args {
+
+		value, err := convert_arg(arg, t.In(i+1))
+
+		if err != nil {
+			return nil, fmt.Errorf("Error managing parameter param%d. %s", i, err.Error())
+		}
+
+		in = append(in, value)
+	}
+
+	out := method.Call(in)
+
+	if len(out) > 0 && t.Out(len(out)-1) == error_type {
+
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	return serialize(out[0])
+}
+
+//==============================================================================================================================
+//	 convert_arg - Converts a string argument to a parameter type.
+//==============================================================================================================================
+func convert_arg(arg string, t reflect.Type) (reflect.Value, error) {
+
+	value := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(arg)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(arg, 10, t.Bits())
+		if err != nil {
+			return value, fmt.Errorf("Conversion error. Cannot convert passed value %s to %s", arg, t.Kind())
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(arg, 10, t.Bits())
+		if err != nil {
+			return value, fmt.Errorf("Conversion error. Cannot convert passed value %s to %s", arg, t.Kind())
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(arg, t.Bits())
+		if err != nil {
+			return value, fmt.Errorf("Conversion error. Cannot convert passed value %s to %s", arg, t.Kind())
+		}
+		value.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(arg)
+		if err != nil {
+			return value, fmt.Errorf("Conversion error. Cannot convert passed value %s to bool", arg)
+		}
+		value.SetBool(b)
+	default:
+		if err := json.Unmarshal([]byte(arg), value.Addr().Interface()); err != nil {
+			return value, fmt.Errorf("Value did not match schema. %s", err.Error())
+		}
+	}
+
+	return value, nil
+}
+
+//==============================================================================================================================
+//	 serialize - Strings and numbers are returned as their text, everything else as JSON. A nil pointer returns nothing.
+//==============================================================================================================================
+func serialize(v reflect.Value) ([]byte, error) {
+
+	switch v.Kind() {
+	case reflect.String:
+		return []byte(v.String()), nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return []byte(fmt.Sprint(v.Interface())), nil
+	}
+
+	return json.Marshal(v.Interface())
+}
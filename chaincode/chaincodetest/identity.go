@@ -0,0 +1,82 @@
+package chaincodetest
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+const DEFAULT_MSPID = "Org1MSP"
+
+//==============================================================================================================================
+//	 Identity - A programmable caller implementing cid.ClientIdentity. Attributes stand in for the attributes the Fabric
+//				CA writes into the client's certificate.
+//==============================================================================================================================
+type Identity struct {
+	ID         string
+	MSPID      string
+	Attributes map[string]string
+}
+
+//==============================================================================================================================
+//	 NewIdentity - Creates a caller with the username and role attributes the chaincode reads. An empty role leaves the
+//				   attribute unset.
+//==============================================================================================================================
+func NewIdentity(username string, role string) *Identity {
+
+	attrs := map[string]string{"username": username}
+
+	if role != "" {
+		attrs["role"] = role
+	}
+
+	return &Identity{
+		ID:         "x509::CN=" + username + ",OU=client::CN=ca.org1.example.com",
+		MSPID:      DEFAULT_MSPID,
+		Attributes: attrs,
+	}
+}
+
+func (id *Identity) GetID() (string, error) {
+	return id.ID, nil
+}
+
+func (id *Identity) GetMSPID() (string, error) {
+	return id.MSPID, nil
+}
+
+func (id *Identity) GetAttributeValue(attrName string) (string, bool, error) {
+
+	value, found := id.Attributes[attrName]
+
+	return value, found, nil
+}
+
+func (id *Identity) AssertAttributeValue(attrName string, attrValue string) error {
+
+	value, found := id.Attributes[attrName]
+
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 GetX509Certificate - Mock identities have no certificate.
+//==============================================================================================================================
+func (id *Identity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, errors.New("mock identity " + id.ID + " has no certificate")
+}
+
+//==============================================================================================================================
+//	 creator - Stands in for the serialized identity returned by GetCreator.
+//==============================================================================================================================
+func (id *Identity) creator() []byte {
+	return []byte(id.MSPID + "/" + id.ID)
+}
@@ -0,0 +1,120 @@
+package chaincodetest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//==============================================================================================================================
+//	 Ledger_File - A mock ledger saved between runs, so that tools can work offline across several invocations. Keys may
+//				   hold any bytes and so are saved with their values base64 encoded. Events are not kept.
+//==============================================================================================================================
+type Ledger_File struct {
+	Clock        time.Time                    `json:"clock"`
+	Transactions int                          `json:"transactions"`
+	State        map[string][]byte            `json:"state"`
+	Private      map[string]map[string][]byte `json:"private,omitempty"`
+	History      map[string][]Modification    `json:"history"`
+}
+
+type Modification struct {
+	Tx_ID     string    `json:"txID"`
+	Value     []byte    `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+//==============================================================================================================================
+//	 SaveLedger - Writes the committed state of the stub to path, replacing the file atomically.
+//==============================================================================================================================
+func (s *MockStub) SaveLedger(path string) error {
+
+	if s.in_tx {
+		return errors.New("can not save the ledger during a transaction")
+	}
+
+	file := Ledger_File{Clock: s.Clock, Transactions: s.Transactions, State: s.State, Private: s.Private, History: map[string][]Modification{}}
+
+	for key, modifications := range s.History {
+		for _, km := range modifications {
+			file.History[key] = append(file.History[key], Modification{Tx_ID: km.TxId, Value: km.Value, Timestamp: km.Timestamp.AsTime(), Deleted: km.IsDelete})
+		}
+	}
+
+	bytes, err := json.Marshal(file)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(bytes)
+
+	if close_err := tmp.Close(); err == nil {
+		err = close_err
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+//==============================================================================================================================
+//	 LoadLedger - Replaces the committed state of the stub with a ledger saved by SaveLedger. A missing file leaves the
+//				  stub as it is and returns false.
+//==============================================================================================================================
+func (s *MockStub) LoadLedger(path string) (bool, error) {
+
+	bytes, err := os.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	var file Ledger_File
+
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return false, errors.New(path + ": " + err.Error())
+	}
+
+	s.Clock = file.Clock.UTC()
+	s.Transactions = file.Transactions
+	s.State = map[string][]byte{}
+	s.Private = map[string]map[string][]byte{}
+	s.History = map[string][]*queryresult.KeyModification{}
+	s.Events = nil
+
+	for key, value := range file.State {
+		s.State[key] = value
+	}
+
+	for collection, state := range file.Private {
+		s.Private[collection] = state
+	}
+
+	for key, modifications := range file.History {
+		for _, m := range modifications {
+			s.History[key] = append(s.History[key], &queryresult.KeyModification{TxId: m.Tx_ID, Value: m.Value, Timestamp: timestamppb.New(m.Timestamp), IsDelete: m.Deleted})
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,666 @@
+// Package chaincodetest provides an in-memory ledger, programmable caller identities and a scenario runner for testing
+// the medical history chaincode without a Fabric network.
+package chaincodetest
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//==============================================================================================================================
+//	 Composite keys - Same encoding as the Fabric shim: a 0x00 namespace byte, the object type and each attribute, each
+//					  followed by 0x00. Simple keys may not start with 0x00.
+//==============================================================================================================================
+const COMPOSITE_KEY_NAMESPACE = "\x00"
+const MIN_UNICODE_RUNE = "\x00"
+const MAX_UNICODE_RUNE = string(utf8.MaxRune)
+const IMPLICIT_COLLECTION_PREFIX = "_implicit_org_"
+
+//==============================================================================================================================
+//	 Event - A chaincode event emitted by a committed transaction.
+//==============================================================================================================================
+type Event struct {
+	Tx_ID   string
+	Name    string
+	Payload []byte
+}
+
+//==============================================================================================================================
+//	 write - A pending write in the write set of the current transaction. A nil value is a delete.
+//==============================================================================================================================
+type write struct {
+	value []byte
+}
+
+//==============================================================================================================================
+//	 MockStub - An in-memory implementation of shim.ChaincodeStubInterface. As on a peer, writes made by a transaction are
+//				held in its write set and are not visible to reads made by the same transaction. They are applied to
+//				the world state when the transaction commits and discarded when it is rolled back, so a failed invoke
+//				leaves the ledger untouched.
+//
+//				Every transaction is stamped with Clock, which then moves on by Step. Members lists the MSP IDs of the
+//				organisations of a private data collection; as with memberOnlyRead and memberOnlyWrite, clients of
+//				other organisations can not read or write a collection listed there, though they can read the hashes
+//				of its values. The implicit collection of an organisation, _implicit_org_<MSP ID>, is read only by
+//				its clients and written by anyone, as on a peer. Methods of the interface that the chaincode does not
+//				use are not implemented and panic if called.
+//==============================================================================================================================
+type MockStub struct {
+	shim.ChaincodeStubInterface
+
+	Name         string
+	Channel_ID   string
+	State        map[string][]byte
+	Private      map[string]map[string][]byte
+	Members      map[string][]string
+	History      map[string][]*queryresult.KeyModification
+	Events       []Event
+	Clock        time.Time
+	Step         time.Duration
+	Transactions int
+
+	args      [][]byte
+	tx_ID     string
+	tx_time   time.Time
+	transient map[string][]byte
+	creator   []byte
+	writes    map[string]*write
+	priv      map[string]map[string]*write
+	event     *Event
+	in_tx     bool
+}
+
+//==============================================================================================================================
+//	 NewMockStub - Creates an empty ledger. The clock starts at the time given and moves on a second per transaction.
+//==============================================================================================================================
+func NewMockStub(name string, clock time.Time) *MockStub {
+	return &MockStub{
+		Name:       name,
+		Channel_ID: "mychannel",
+		State:      map[string][]byte{},
+		Private:    map[string]map[string][]byte{},
+		Members:    map[string][]string{},
+		History:    map[string][]*queryresult.KeyModification{},
+		Clock:      clock.UTC(),
+		Step:       time.Second,
+	}
+}
+
+//==============================================================================================================================
+//	 BeginTx - Starts a transaction with the function and arguments given. The creator is the serialized identity of the
+//			   caller and transient is the transient map of the proposal, both may be nil.
+//==============================================================================================================================
+func (s *MockStub) BeginTx(creator []byte, transient map[string][]byte, function string, args ...string) {
+
+	s.Transactions++
+
+	s.args = [][]byte{[]byte(function)}
+
+	for _, arg := range args {
+		s.args = append(s.args, []byte(arg))
+	}
+
+	s.tx_ID = "tx" + strconv.Itoa(s.Transactions)
+	s.tx_time = s.Clock
+	s.Clock = s.Clock.Add(s.Step)
+	s.creator = creator
+	s.transient = transient
+	s.writes = map[string]*write{}
+	s.priv = map[string]map[string]*write{}
+	s.event = nil
+	s.in_tx = true
+}
+
+//==============================================================================================================================
+//	 CommitTx - Applies the write set of the current transaction to the world state, records the history of every key
+//				written and publishes the event set by the transaction.
+//==============================================================================================================================
+func (s *MockStub) CommitTx() {
+
+	ts := timestamppb.New(s.tx_time)
+
+	for _, key := range sorted_writes(s.writes) {
+
+		w := s.writes[key]
+
+		if w.value == nil {
+			delete(s.State, key)
+		} else {
+			s.State[key] = w.value
+		}
+
+		s.History[key] = append(s.History[key], &queryresult.KeyModification{TxId: s.tx_ID, Value: w.value, Timestamp: ts, IsDelete: w.value == nil})
+	}
+
+	for collection, writes := range s.priv {
+
+		if s.Private[collection] == nil {
+			s.Private[collection] = map[string][]byte{}
+		}
+
+		for key, w := range writes {
+			if w.value == nil {
+				delete(s.Private[collection], key)
+			} else {
+				s.Private[collection][key] = w.value
+			}
+		}
+	}
+
+	if s.event != nil {
+		s.Events = append(s.Events, *s.event)
+	}
+
+	s.end_tx()
+}
+
+//==============================================================================================================================
+//	 RollbackTx - Discards the write set and event of the current transaction.
+//==============================================================================================================================
+func (s *MockStub) RollbackTx() {
+	s.end_tx()
+}
+
+func (s *MockStub) end_tx() {
+	s.writes = nil
+	s.priv = nil
+	s.event = nil
+	s.transient = nil
+	s.in_tx = false
+}
+
+//==============================================================================================================================
+//	 sorted_writes - Keys of a write set in order, so that history is recorded deterministically.
+//==============================================================================================================================
+func sorted_writes(writes map[string]*write) []string {
+
+	keys := make([]string, 0, len(writes))
+
+	for key := range writes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+//==============================================================================================================================
+//	 check_tx - The ledger may only be used inside a transaction.
+//==============================================================================================================================
+func (s *MockStub) check_tx() error {
+
+	if !s.in_tx {
+		return errors.New("no transaction in progress, call BeginTx first")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 check_read / check_write - The client of the current transaction must belong to an organisation of the collection,
+//								if its members are listed. The MSP ID is the part of the creator before the first "/".
+//==============================================================================================================================
+func (s *MockStub) check_read(collection string) error {
+	return s.check_member(collection, "read")
+}
+
+func (s *MockStub) check_write(collection string) error {
+	return s.check_member(collection, "write")
+}
+
+func (s *MockStub) check_member(collection string, access string) error {
+
+	if err := s.check_tx(); err != nil {
+		return err
+	}
+
+	members, ok := s.Members[collection]
+
+	if org, implicit := strings.CutPrefix(collection, IMPLICIT_COLLECTION_PREFIX); implicit {
+
+		if access == "write" {
+			return nil
+		}
+
+		members, ok = []string{org}, true
+	}
+
+	if !ok {
+		return nil
+	}
+
+	msp_ID, _, _ := strings.Cut(string(s.creator), "/")
+
+	for _, member := range members {
+		if member == msp_ID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("tx creator does not have %s access permission on privatedata in chaincodeName:%s collectionName: %s", access, s.Name, collection)
+}
+
+//==============================================================================================================================
+//	 Transaction details
+//==============================================================================================================================
+func (s *MockStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *MockStub) GetStringArgs() []string {
+
+	args := []string{}
+
+	for _, arg := range s.args {
+		args = append(args, string(arg))
+	}
+
+	return args
+}
+
+func (s *MockStub) GetFunctionAndParameters() (string, []string) {
+
+	args := s.GetStringArgs()
+
+	if len(args) == 0 {
+		return "", []string{}
+	}
+
+	return args[0], args[1:]
+}
+
+func (s *MockStub) GetTxID() string {
+	return s.tx_ID
+}
+
+func (s *MockStub) GetChannelID() string {
+	return s.Channel_ID
+}
+
+func (s *MockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+
+	if err := s.check_tx(); err != nil {
+		return nil, err
+	}
+
+	return timestamppb.New(s.tx_time), nil
+}
+
+func (s *MockStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *MockStub) GetTransient() (map[string][]byte, error) {
+
+	transient := map[string][]byte{}
+
+	for k, v := range s.transient {
+		transient[k] = v
+	}
+
+	return transient, nil
+}
+
+func (s *MockStub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+//==============================================================================================================================
+//	 SetEvent - Sets the event of the transaction. As on a peer a transaction has at most one event, a later call
+//				replaces an earlier one.
+//==============================================================================================================================
+func (s *MockStub) SetEvent(name string, payload []byte) error {
+
+	if err := s.check_tx(); err != nil {
+		return err
+	}
+
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+
+	s.event = &Event{Tx_ID: s.tx_ID, Name: name, Payload: payload}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 TxEvent - The event published by the committed transaction tx_ID, nil if it set none or did not commit.
+//==============================================================================================================================
+func (s *MockStub) TxEvent(tx_ID string) *Event {
+
+	for i := range s.Events {
+		if s.Events[i].Tx_ID == tx_ID {
+			return &s.Events[i]
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 World state
+//==============================================================================================================================
+func (s *MockStub) GetState(key string) ([]byte, error) {
+
+	if err := s.check_tx(); err != nil {
+		return nil, err
+	}
+
+	return s.State[key], nil
+}
+
+func (s *MockStub) GetMultipleStates(keys ...string) ([][]byte, error) {
+
+	values := [][]byte{}
+
+	for _, key := range keys {
+
+		value, err := s.GetState(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+func (s *MockStub) PutState(key string, value []byte) error {
+
+	if err := s.check_tx(); err != nil {
+		return err
+	}
+
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+
+	if value == nil {
+		value = []byte{}
+	}
+
+	s.writes[key] = &write{value: append([]byte{}, value...)}
+
+	return nil
+}
+
+func (s *MockStub) DelState(key string) error {
+
+	if err := s.check_tx(); err != nil {
+		return err
+	}
+
+	s.writes[key] = &write{}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 GetStateByRange - Iterates the simple keys in [startKey, endKey) in key order. An empty endKey is unbounded.
+//==============================================================================================================================
+func (s *MockStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+
+	if err := s.check_tx(); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(startKey, COMPOSITE_KEY_NAMESPACE) || strings.HasPrefix(endKey, COMPOSITE_KEY_NAMESPACE) {
+		return nil, errors.New("range query keys must not be composite keys")
+	}
+
+	if endKey == "" {
+		endKey = MAX_UNICODE_RUNE
+	}
+
+	return s.range_iterator(s.State, startKey, endKey, false), nil
+}
+
+//==============================================================================================================================
+//	 range_iterator - Iterator over the keys of state in [start, end). Composite keys are only included when composite
+//					  is set, and simple keys only when it is not.
+//==============================================================================================================================
+func (s *MockStub) range_iterator(state map[string][]byte, start string, end string, composite bool) *Iterator {
+
+	keys := []string{}
+
+	for key := range state {
+		if strings.HasPrefix(key, COMPOSITE_KEY_NAMESPACE) != composite {
+			continue
+		}
+		if key >= start && key < end {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	it := &Iterator{}
+
+	for _, key := range keys {
+		it.items = append(it.items, &queryresult.KV{Namespace: s.Name, Key: key, Value: state[key]})
+	}
+
+	return it
+}
+
+//==============================================================================================================================
+//	 Composite keys
+//==============================================================================================================================
+func (s *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+
+	if err := validate_composite_key_attribute(objectType); err != nil {
+		return "", err
+	}
+
+	key := COMPOSITE_KEY_NAMESPACE + objectType + MIN_UNICODE_RUNE
+
+	for _, attribute := range attributes {
+
+		if err := validate_composite_key_attribute(attribute); err != nil {
+			return "", err
+		}
+
+		key += attribute + MIN_UNICODE_RUNE
+	}
+
+	return key, nil
+}
+
+func (s *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+
+	if !strings.HasPrefix(compositeKey, COMPOSITE_KEY_NAMESPACE) {
+		return "", nil, fmt.Errorf("invalid composite key %q", compositeKey)
+	}
+
+	parts := strings.Split(strings.TrimSuffix(compositeKey[1:], MIN_UNICODE_RUNE), MIN_UNICODE_RUNE)
+
+	return parts[0], parts[1:], nil
+}
+
+func validate_composite_key_attribute(attribute string) error {
+
+	if !utf8.ValidString(attribute) {
+		return fmt.Errorf("not a valid utf8 string: [%x]", attribute)
+	}
+
+	if strings.Contains(attribute, MIN_UNICODE_RUNE) || strings.Contains(attribute, MAX_UNICODE_RUNE) {
+		return fmt.Errorf("input contains unicode %#U or %#U starting at position [0]", []rune(MIN_UNICODE_RUNE)[0], utf8.MaxRune)
+	}
+
+	return nil
+}
+
+func (s *MockStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+
+	if err := s.check_tx(); err != nil {
+		return nil, err
+	}
+
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.range_iterator(s.State, prefix, prefix+MAX_UNICODE_RUNE, true), nil
+}
+
+//==============================================================================================================================
+//	 GetHistoryForKey - Iterates the committed modifications of a key, oldest first.
+//==============================================================================================================================
+func (s *MockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+
+	if err := s.check_tx(); err != nil {
+		return nil, err
+	}
+
+	return &History_Iterator{items: append([]*queryresult.KeyModification{}, s.History[key]...)}, nil
+}
+
+//==============================================================================================================================
+//	 Private data - Collections are held separately from the world state. Hashes are SHA-256 of the value as on a peer.
+//==============================================================================================================================
+func (s *MockStub) GetPrivateData(collection string, key string) ([]byte, error) {
+
+	if err := s.check_read(collection); err != nil {
+		return nil, err
+	}
+
+	return s.Private[collection][key], nil
+}
+
+func (s *MockStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+
+	if err := s.check_tx(); err != nil {
+		return nil, err
+	}
+
+	value := s.Private[collection][key]
+
+	if value == nil {
+		return nil, nil
+	}
+
+	hash := sha256.Sum256(value)
+
+	return hash[:], nil
+}
+
+func (s *MockStub) PutPrivateData(collection string, key string, value []byte) error {
+
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+
+	if err := s.check_write(collection); err != nil {
+		return err
+	}
+
+	if s.priv[collection] == nil {
+		s.priv[collection] = map[string]*write{}
+	}
+
+	s.priv[collection][key] = &write{value: append([]byte{}, value...)}
+
+	return nil
+}
+
+func (s *MockStub) DelPrivateData(collection string, key string) error {
+
+	if err := s.check_write(collection); err != nil {
+		return err
+	}
+
+	if s.priv[collection] == nil {
+		s.priv[collection] = map[string]*write{}
+	}
+
+	s.priv[collection][key] = &write{}
+
+	return nil
+}
+
+func (s *MockStub) PurgePrivateData(collection string, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+func (s *MockStub) GetPrivateDataByRange(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+
+	if err := s.check_read(collection); err != nil {
+		return nil, err
+	}
+
+	if endKey == "" {
+		endKey = MAX_UNICODE_RUNE
+	}
+
+	return s.range_iterator(s.Private[collection], startKey, endKey, false), nil
+}
+
+//==============================================================================================================================
+//	 Iterator - Iterator over a snapshot of key/value pairs.
+//==============================================================================================================================
+type Iterator struct {
+	items []*queryresult.KV
+	pos   int
+}
+
+func (it *Iterator) HasNext() bool {
+	return it.pos < len(it.items)
+}
+
+func (it *Iterator) Next() (*queryresult.KV, error) {
+
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+
+	it.pos++
+
+	return it.items[it.pos-1], nil
+}
+
+func (it *Iterator) Close() error {
+	return nil
+}
+
+//==============================================================================================================================
+//	 History_Iterator - Iterator over the modifications of a key.
+//==============================================================================================================================
+type History_Iterator struct {
+	items []*queryresult.KeyModification
+	pos   int
+}
+
+func (it *History_Iterator) HasNext() bool {
+	return it.pos < len(it.items)
+}
+
+func (it *History_Iterator) Next() (*queryresult.KeyModification, error) {
+
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+
+	it.pos++
+
+	return it.items[it.pos-1], nil
+}
+
+func (it *History_Iterator) Close() error {
+	return nil
+}
@@ -0,0 +1,200 @@
+package chaincodetest
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestWritesAreNotVisibleUntilCommit(t *testing.T) {
+
+	s := NewMockStub("test", epoch)
+
+	s.BeginTx(nil, nil, "put")
+
+	if err := s.PutState("k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := s.GetState("k"); v != nil {
+		t.Fatalf("read own write %q before commit", v)
+	}
+
+	s.CommitTx()
+
+	s.BeginTx(nil, nil, "get")
+
+	if v, _ := s.GetState("k"); string(v) != "v" {
+		t.Fatalf("expected committed value, got %q", v)
+	}
+
+	s.RollbackTx()
+}
+
+func TestRollbackDiscardsWritesAndEvents(t *testing.T) {
+
+	s := NewMockStub("test", epoch)
+
+	s.BeginTx(nil, nil, "put")
+	s.PutState("k", []byte("v"))
+	s.SetEvent("changed", []byte("{}"))
+	s.RollbackTx()
+
+	if len(s.State) != 0 || len(s.Events) != 0 || len(s.History) != 0 {
+		t.Fatalf("rolled back transaction left state %v events %v", s.State, s.Events)
+	}
+}
+
+func TestHistoryAndClock(t *testing.T) {
+
+	s := NewMockStub("test", epoch)
+
+	for _, v := range []string{"a", "b"} {
+		s.BeginTx(nil, nil, "put")
+		s.PutState("k", []byte(v))
+		s.CommitTx()
+	}
+
+	s.BeginTx(nil, nil, "del")
+	s.DelState("k")
+	s.CommitTx()
+
+	s.BeginTx(nil, nil, "history")
+	defer s.RollbackTx()
+
+	it, err := s.GetHistoryForKey("k")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mods []string
+
+	for it.HasNext() {
+		m, _ := it.Next()
+		mods = append(mods, m.TxId+":"+string(m.Value))
+		if m.IsDelete != (m.Value == nil) {
+			t.Errorf("%s: IsDelete %v for value %q", m.TxId, m.IsDelete, m.Value)
+		}
+	}
+
+	if len(mods) != 3 || mods[0] != "tx1:a" || mods[1] != "tx2:b" || mods[2] != "tx3:" {
+		t.Fatalf("unexpected history %v", mods)
+	}
+
+	ts, _ := s.GetTxTimestamp()
+
+	if !ts.AsTime().Equal(epoch.Add(3 * time.Second)) {
+		t.Fatalf("fourth transaction stamped %v", ts.AsTime())
+	}
+}
+
+func TestRangeAndCompositeKeys(t *testing.T) {
+
+	s := NewMockStub("test", epoch)
+
+	s.BeginTx(nil, nil, "put")
+
+	key, err := s.CreateCompositeKey("member", []string{"AB12345679", "vitals"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.PutState(key, []byte("1"))
+	s.PutState("A", []byte("2"))
+	s.PutState("B", []byte("3"))
+	s.PutState("C", []byte("4"))
+	s.CommitTx()
+
+	s.BeginTx(nil, nil, "query")
+	defer s.RollbackTx()
+
+	it, _ := s.GetStateByRange("A", "C")
+
+	var keys []string
+
+	for it.HasNext() {
+		kv, _ := it.Next()
+		keys = append(keys, kv.Key)
+	}
+
+	if len(keys) != 2 || keys[0] != "A" || keys[1] != "B" {
+		t.Fatalf("range returned %v", keys)
+	}
+
+	it, _ = s.GetStateByPartialCompositeKey("member", []string{"AB12345679"})
+
+	if !it.HasNext() {
+		t.Fatal("partial composite key query returned nothing")
+	}
+
+	kv, _ := it.Next()
+
+	object, attrs, err := s.SplitCompositeKey(kv.Key)
+
+	if err != nil || object != "member" || len(attrs) != 2 || attrs[1] != "vitals" {
+		t.Fatalf("split %q into %q %v (%v)", kv.Key, object, attrs, err)
+	}
+}
+
+func TestCollectionMembersOnlyRead(t *testing.T) {
+
+	s := NewMockStub("test", epoch)
+	s.Members["clinical"] = []string{"Org1MSP"}
+
+	s.BeginTx(NewIdentity("alice", "").creator(), nil, "put")
+
+	if err := s.PutPrivateData("clinical", "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	s.CommitTx()
+
+	s.BeginTx(NewIdentity("alice", "").creator(), nil, "get")
+
+	if v, err := s.GetPrivateData("clinical", "k"); err != nil || string(v) != "v" {
+		t.Fatalf("expected a member to read the value, got %q, %v", v, err)
+	}
+
+	outsider := NewIdentity("rita", "")
+	outsider.MSPID = "Org3MSP"
+
+	s.BeginTx(outsider.creator(), nil, "get")
+
+	if _, err := s.GetPrivateData("clinical", "k"); err == nil {
+		t.Fatal("expected a client of another organisation to be refused")
+	}
+
+	if hash, err := s.GetPrivateDataHash("clinical", "k"); err != nil || len(hash) != 32 {
+		t.Fatalf("expected any client to read the hash, got %x, %v", hash, err)
+	}
+}
+
+func TestCollectionMembersOnlyWrite(t *testing.T) {
+
+	s := NewMockStub("test", epoch)
+	s.Members["clinical"] = []string{"Org1MSP"}
+
+	outsider := NewIdentity("rita", "")
+	outsider.MSPID = "Org3MSP"
+
+	s.BeginTx(outsider.creator(), nil, "put")
+
+	if err := s.PutPrivateData("clinical", "k", []byte("v")); err == nil {
+		t.Fatal("expected a client of another organisation to be refused")
+	}
+
+	if err := s.DelPrivateData("clinical", "k"); err == nil {
+		t.Fatal("expected a client of another organisation to be refused a delete")
+	}
+
+	s.RollbackTx()
+
+	s.BeginTx(NewIdentity("alice", "").creator(), nil, "put")
+
+	if err := s.PutPrivateData("clinical", "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+}
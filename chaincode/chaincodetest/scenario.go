@@ -0,0 +1,583 @@
+package chaincodetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+)
+
+const DEFAULT_CLOCK = "2024-01-01T00:00:00Z"
+
+//==============================================================================================================================
+//	 Scenario - A script of transactions run in order against a fresh ledger. Identities maps each username to its role
+//				and Orgs to its MSP ID where it is not DEFAULT_MSPID. Collections lists the organisations of private
+//				data collections whose reads and writes are restricted, see MockStub.Members. Ledger holds entries
+//				written to the world state before the first step, e.g. records in an old layout: a JSON string is
+//				stored as the string, any other value as its JSON text. Private holds those written to private data
+//				collections, by collection, stored the same way. Transient is passed as the transient map of every
+//				step that has none of its own, as a client passes the same entries with each transaction, e.g. the
+//				entropy that salts the fields of members.
+//
+//	{
+//	  "name": "create and hand over",
+//	  "clock": "2024-06-01T09:00:00Z",
+//	  "identities": {"alice": "parents", "bob": "birthday"},
+//	  "steps": [
+//	    {"as": "alice", "invoke": "member:CreateMember", "args": ["AB12345679", []]},
+//	    {"as": "alice", "invoke": "lifecycle:ParentsToBirthday", "args": ["AB12345679", "bob"],
+//	     "expect": {"state": {"member:AB12345679": {"name": "bob", "status": 1}}}},
+//	    {"as": "alice", "query": "query:GetMemberDetails", "args": ["AB12345679"], "expect": {"result": {"name": "bob"}}}
+//	  ]
+//	}
+//==============================================================================================================================
+type Scenario struct {
+	Name        string                                `json:"name"`
+	Description string                                `json:"description,omitempty"`
+	Clock       string                                `json:"clock,omitempty"`
+	Identities  map[string]string                     `json:"identities"`
+	Orgs        map[string]string                     `json:"orgs,omitempty"`
+	Collections map[string][]string                   `json:"collections,omitempty"`
+	Ledger      map[string]json.RawMessage            `json:"ledger,omitempty"`
+	Private     map[string]map[string]json.RawMessage `json:"private,omitempty"`
+	Transient   map[string]string                     `json:"transient,omitempty"`
+	Steps       []Step                                `json:"steps"`
+}
+
+//==============================================================================================================================
+//	 Step - One transaction. Exactly one of Invoke (submitted) or Query (evaluated) names the function. Arguments that are
+//			JSON strings are passed as the string, any other JSON value is passed as its JSON text. At moves the clock
+//			before the step. Transient is passed as the transient map in place of the scenario's. Key is the base64
+//			private key of the caller's organisation, used as a client would: the data keys it unwraps from
+//			DATA_KEY_COLLECTION are added to the transient map and the key itself is not passed.
+//==============================================================================================================================
+type Step struct {
+	Name      string            `json:"name,omitempty"`
+	As        string            `json:"as"`
+	Invoke    string            `json:"invoke,omitempty"`
+	Query     string            `json:"query,omitempty"`
+	Args      []json.RawMessage `json:"args"`
+	Transient map[string]string `json:"transient,omitempty"`
+	Key       string            `json:"key,omitempty"`
+	At        string            `json:"at,omitempty"`
+	Expect    Expectation       `json:"expect"`
+}
+
+//==============================================================================================================================
+//	 data_keys - The data keys in DATA_KEY_COLLECTION that key unwraps, as passed in TRANSIENT_DATA_KEYS.
+//==============================================================================================================================
+func (h *Harness) data_keys(key string) ([]byte, error) {
+
+	keys := map[string][]byte{}
+
+	for _, value := range h.Stub.Private[chaincode.DATA_KEY_COLLECTION] {
+
+		var envelope chaincode.Data_Key
+
+		if err := json.Unmarshal(value, &envelope); err != nil {
+			return nil, err
+		}
+
+		if dk, err := chaincode.UnwrapDataKey(envelope, key); err == nil {
+			keys[envelope.ILNSID] = dk
+		}
+	}
+
+	return json.Marshal(keys)
+}
+
+//==============================================================================================================================
+//	 Expectation - What must hold after a step. Error is a substring of the expected error, when empty the step must
+//				   succeed. Result and each State entry are matched against the payload and the stored document: objects
+//				   match if every expected field matches, a null field matching a missing one, other values must be
+//				   equal. A null State entry expects the key to be absent. Private holds the expected entries of private
+//				   data collections, by collection, matched in the same way. Event is matched against {"name": ...,
+//				   "payload": ...} of the event the step emitted, null expects none.
+//==============================================================================================================================
+type Expectation struct {
+	Error   string                                `json:"error,omitempty"`
+	Result  json.RawMessage                       `json:"result,omitempty"`
+	State   map[string]json.RawMessage            `json:"state,omitempty"`
+	Private map[string]map[string]json.RawMessage `json:"private,omitempty"`
+	Event   json.RawMessage                       `json:"event,omitempty"`
+}
+
+//==============================================================================================================================
+//	 LoadScenario - Reads a scenario from a JSON file.
+//==============================================================================================================================
+func LoadScenario(path string) (*Scenario, error) {
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var sc Scenario
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err = decoder.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return &sc, nil
+}
+
+//==============================================================================================================================
+//	 LoadScenarios - Reads every *.json scenario in a directory, in file name order.
+//==============================================================================================================================
+func LoadScenarios(dir string) ([]*Scenario, error) {
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	scenarios := []*Scenario{}
+
+	for _, path := range paths {
+
+		sc, err := LoadScenario(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		scenarios = append(scenarios, sc)
+	}
+
+	return scenarios, nil
+}
+
+//==============================================================================================================================
+//	 Save - Writes the scenario as indented JSON.
+//==============================================================================================================================
+func (sc *Scenario) Save(path string) error {
+
+	data, err := json.MarshalIndent(sc, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+//==============================================================================================================================
+//	 Harness - Creates the harness a scenario runs against, with its clock and identities.
+//==============================================================================================================================
+func (sc *Scenario) Harness() (*Harness, error) {
+
+	clock := sc.Clock
+
+	if clock == "" {
+		clock = DEFAULT_CLOCK
+	}
+
+	start, err := time.Parse(time.RFC3339, clock)
+
+	if err != nil {
+		return nil, errors.New("clock must be an RFC 3339 timestamp")
+	}
+
+	h := New(start)
+
+	for username, role := range sc.Identities {
+		h.AddIdentity(username, role)
+	}
+
+	for username, org := range sc.Orgs {
+
+		id, err := h.Identity(username)
+
+		if err != nil {
+			return nil, err
+		}
+
+		id.MSPID = org
+	}
+
+	for collection, orgs := range sc.Collections {
+		h.Stub.Members[collection] = orgs
+	}
+
+	for key, value := range sc.Ledger {
+		h.Stub.State[key] = ledger_value(value)
+	}
+
+	for k, v := range sc.Transient {
+
+		if h.Transient == nil {
+			h.Transient = map[string][]byte{}
+		}
+
+		h.Transient[k] = []byte(v)
+	}
+
+	for collection, values := range sc.Private {
+
+		h.Stub.Private[collection] = map[string][]byte{}
+
+		for key, value := range values {
+			h.Stub.Private[collection][key] = ledger_value(value)
+		}
+	}
+
+	return h, nil
+}
+
+//==============================================================================================================================
+//	 ledger_value - The bytes stored for an entry of Ledger or Private.
+//==============================================================================================================================
+func ledger_value(value json.RawMessage) []byte {
+
+	var s string
+
+	if json.Unmarshal(value, &s) == nil {
+		return []byte(s)
+	}
+
+	return append([]byte{}, value...)
+}
+
+//==============================================================================================================================
+//	 Run - Runs every step against a fresh ledger and returns the first failed expectation.
+//==============================================================================================================================
+func (sc *Scenario) Run() error {
+
+	h, err := sc.Harness()
+
+	if err != nil {
+		return err
+	}
+
+	for i, step := range sc.Steps {
+		if err := h.RunStep(step); err != nil {
+			return fmt.Errorf("step %d (%s): %v", i+1, step.Label(), err)
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 Label - Name of the step for failure messages.
+//==============================================================================================================================
+func (step Step) Label() string {
+
+	if step.Name != "" {
+		return step.Name
+	}
+
+	return step.As + " " + step.Invoke + step.Query
+}
+
+//==============================================================================================================================
+//	 RunStep - Runs one step and checks its expectations.
+//==============================================================================================================================
+func (h *Harness) RunStep(step Step) error {
+
+	if (step.Invoke == "") == (step.Query == "") {
+		return errors.New("a step must have exactly one of invoke or query")
+	}
+
+	if step.At != "" {
+
+		at, err := time.Parse(time.RFC3339, step.At)
+
+		if err != nil {
+			return errors.New("at must be an RFC 3339 timestamp")
+		}
+
+		h.Stub.Clock = at
+	}
+
+	caller, err := h.Identity(step.As)
+
+	if err != nil {
+		return err
+	}
+
+	args, err := step_args(step.Args)
+
+	if err != nil {
+		return err
+	}
+
+	transient := map[string][]byte{}
+
+	if step.Transient == nil {
+		for k, v := range h.Transient {
+			transient[k] = v
+		}
+	}
+
+	for k, v := range step.Transient {
+		transient[k] = []byte(v)
+	}
+
+	if step.Key != "" {
+
+		if transient[chaincode.TRANSIENT_DATA_KEYS], err = h.data_keys(step.Key); err != nil {
+			return err
+		}
+	}
+
+	payload, err := h.Transact(caller, transient, step.Invoke != "", step.Invoke+step.Query, args...)
+	h.Result = payload
+
+	if step.Expect.Error == "" && err != nil {
+		return fmt.Errorf("unexpected error: %v", err)
+	}
+
+	if step.Expect.Error != "" {
+
+		if err == nil {
+			return fmt.Errorf("expected an error containing %q, succeeded", step.Expect.Error)
+		}
+
+		if !strings.Contains(err.Error(), step.Expect.Error) {
+			return fmt.Errorf("expected an error containing %q, got: %v", step.Expect.Error, err)
+		}
+	}
+
+	if len(step.Expect.Result) > 0 {
+		if err := match_json(step.Expect.Result, payload, "result"); err != nil {
+			return err
+		}
+	}
+
+	if len(step.Expect.Event) > 0 {
+		if err := h.match_event(step.Expect.Event); err != nil {
+			return err
+		}
+	}
+
+	if err := match_entries(step.Expect.State, h.Stub.State, "state"); err != nil {
+		return err
+	}
+
+	collections := []string{}
+
+	for collection := range step.Expect.Private {
+		collections = append(collections, collection)
+	}
+
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		if err := match_entries(step.Expect.Private[collection], h.Stub.Private[collection], "private "+collection); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 match_entries - Matches the expected entries of the world state or a private data collection, in key order.
+//==============================================================================================================================
+func match_entries(entries map[string]json.RawMessage, state map[string][]byte, name string) error {
+
+	keys := []string{}
+
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		expected := entries[key]
+		stored, ok := state[key]
+
+		if string(expected) == "null" {
+			if ok {
+				return fmt.Errorf("%s %s: expected no value, found %s", name, key, stored)
+			}
+			continue
+		}
+
+		if !ok {
+			return fmt.Errorf("%s %s: expected a value, found none", name, key)
+		}
+
+		if err := match_json(expected, stored, name+" "+key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 match_event - Matches the event emitted by the last transaction.
+//==============================================================================================================================
+func (h *Harness) match_event(expected json.RawMessage) error {
+
+	event := h.Stub.TxEvent(h.Stub.GetTxID())
+
+	if string(expected) == "null" {
+		if event != nil {
+			return fmt.Errorf("event: expected none, found %s", event.Name)
+		}
+		return nil
+	}
+
+	if event == nil {
+		return errors.New("event: expected an event, found none")
+	}
+
+	actual, err := json.Marshal(map[string]interface{}{"name": event.Name, "payload": json.RawMessage(event.Payload)})
+
+	if err != nil {
+		return fmt.Errorf("event: payload is not JSON: %v", err)
+	}
+
+	return match_json(expected, actual, "event")
+}
+
+//==============================================================================================================================
+//	 step_args - Converts the JSON arguments of a step to the strings passed to the transaction.
+//==============================================================================================================================
+func step_args(raw []json.RawMessage) ([]string, error) {
+
+	args := []string{}
+
+	for i, arg := range raw {
+
+		var s string
+
+		if err := json.Unmarshal(arg, &s); err == nil {
+			args = append(args, s)
+			continue
+		}
+
+		var v interface{}
+
+		if err := json.Unmarshal(arg, &v); err != nil {
+			return nil, fmt.Errorf("argument %d is not valid JSON", i)
+		}
+
+		compact, _ := json.Marshal(v)
+
+		args = append(args, string(compact))
+	}
+
+	return args, nil
+}
+
+//==============================================================================================================================
+//	 match_json - Matches an expected JSON value against an actual payload. A payload that is not JSON (e.g. a string
+//				  returned by a transaction) matches an expected JSON string with the same text.
+//==============================================================================================================================
+func match_json(expected json.RawMessage, actual []byte, path string) error {
+
+	var want, got interface{}
+
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return fmt.Errorf("%s: expectation is not valid JSON", path)
+	}
+
+	if err := json.Unmarshal(actual, &got); err != nil {
+		got = string(actual)
+	}
+
+	return match(want, got, path)
+}
+
+//==============================================================================================================================
+//	 match - Objects match if every expected field matches, a null field also matching a missing one, arrays if they
+//			 have the same length and every element matches, anything else if it is equal.
+//==============================================================================================================================
+func match(want interface{}, got interface{}, path string) error {
+
+	switch w := want.(type) {
+
+	case map[string]interface{}:
+
+		g, ok := got.(map[string]interface{})
+
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %s", path, render(got))
+		}
+
+		fields := []string{}
+
+		for field := range w {
+			fields = append(fields, field)
+		}
+
+		sort.Strings(fields)
+
+		for _, field := range fields {
+
+			value, ok := g[field]
+
+			if !ok && w[field] == nil {
+				continue
+			}
+
+			if !ok {
+				return fmt.Errorf("%s.%s: missing", path, field)
+			}
+
+			if err := match(w[field], value, path+"."+field); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case []interface{}:
+
+		g, ok := got.([]interface{})
+
+		if !ok || len(g) != len(w) {
+			return fmt.Errorf("%s: expected %s, got %s", path, render(want), render(got))
+		}
+
+		for i := range w {
+			if err := match(w[i], g[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Errorf("%s: expected %s, got %s", path, render(want), render(got))
+	}
+
+	return nil
+}
+
+func render(v interface{}) string {
+
+	bytes, err := json.Marshal(v)
+
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(bytes)
+}
@@ -0,0 +1,260 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Consent - Read access to a member's record granted to a user other than its custodian. Expires is an RFC 3339
+//			   timestamp and is empty for consent that does not expire. Stored under consent:<ILNSID>:<grantee>.
+//==============================================================================================================================
+type Consent struct {
+	ILNSID         string `json:"ILNSID"`
+	Grantee        string `json:"grantee"`
+	Granted_By     string `json:"grantedBy"`
+	Expires        string `json:"expires,omitempty" metadata:",optional"`
+	Tx_ID          string `json:"txID"`
+	Schema_Version int    `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 ConsentContract - Transactions that grant and revoke read access to a member's record.
+//==============================================================================================================================
+type ConsentContract struct {
+	contractapi.Contract
+}
+
+//==============================================================================================================================
+//	 retrieve_consent - Gets the consent granted to grantee on the member. Returns nil if none has been granted.
+//==============================================================================================================================
+func retrieve_consent(stub shim.ChaincodeStubInterface, ILNSID string, grantee string) (*Consent, error) {
+
+	var c Consent
+
+	found, err := read_document(stub, DOC_CONSENT, consent_key(ILNSID, grantee), &c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &c, nil
+}
+
+//==============================================================================================================================
+//	 is_guardian - Whether the caller is a parent named as one of the member's guardians. The parent who creates a
+//				   member is its guardian, members created before guardians were recorded have none.
+//==============================================================================================================================
+func is_guardian(m Member, caller string, caller_affiliation string) bool {
+
+	if caller_affiliation != PARENTS {
+		return false
+	}
+
+	for _, guardian := range m.Guardians {
+		if guardian == caller {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================================================================
+//	 can_view - The custodian of a member, its guardians and anyone holding unexpired consent may read the member's
+//				record. Anyone may read the tombstone of an erased member.
+//==============================================================================================================================
+func can_view(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) bool {
+
+	if m.Erased || m.Name == caller || is_guardian(m, caller, caller_affiliation) {
+		return true
+	}
+
+	c, err := retrieve_consent(stub, m.ILNSID, caller)
+
+	if err != nil || c == nil {
+		return false
+	}
+
+	if c.Expires == "" {
+		return true
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return false
+	}
+
+	expires, err := time.Parse(time.RFC3339, c.Expires)
+
+	return err == nil && now.Before(expires)
+}
+
+//=================================================================================================================================
+//	 grant_consent - Grants grantee read access to the member. Only the custodian of a living member may grant consent.
+//=================================================================================================================================
+func grant_consent(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, grantee string, expires string) error {
+
+	if m.Erased {
+		return erased_state(m)
+	}
+
+	if m.Dead {
+		return invalid_state("grant_consent", m, -1)
+	}
+
+	if m.Name != caller {
+		return permission_denied("grant_consent", map[string]interface{}{"ILNSID": m.ILNSID, "is_custodian": false})
+	}
+
+	if grantee == "" {
+		return invalid("grantee", grantee, "must name the user being granted access")
+	}
+
+	if expires != "" {
+
+		at, err := time.Parse(time.RFC3339, expires)
+
+		if err != nil {
+			return invalid("expires", expires, "must be an RFC 3339 timestamp")
+		}
+
+		now, err := get_tx_time(stub)
+
+		if err != nil {
+			return err
+		}
+
+		if !at.After(now) {
+			return invalid("expires", expires, "must be in the future")
+		}
+	}
+
+	c := Consent{ILNSID: m.ILNSID, Grantee: grantee, Granted_By: caller, Expires: expires, Tx_ID: stub.GetTxID(), Schema_Version: schema_version(DOC_CONSENT)}
+
+	bytes, err := json.Marshal(c)
+
+	if err != nil {
+		return internal("Error converting consent record")
+	}
+
+	err = stub.PutState(consent_key(m.ILNSID, grantee), bytes)
+
+	if err != nil {
+		return internal("Error storing consent record")
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 revoke_consent - Removes the consent granted to grantee. The custodian may revoke any consent, a grantee may give up
+//					  their own.
+//=================================================================================================================================
+func revoke_consent(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, grantee string) error {
+
+	if m.Name != caller && grantee != caller {
+		return permission_denied("revoke_consent", map[string]interface{}{"ILNSID": m.ILNSID, "is_custodian": false, "is_grantee": false})
+	}
+
+	c, err := retrieve_consent(stub, m.ILNSID, grantee)
+
+	if err != nil {
+		return err
+	}
+
+	if c == nil {
+		return not_found("No consent granted to "+grantee+" on "+m.ILNSID, map[string]interface{}{"ILNSID": m.ILNSID, "grantee": grantee})
+	}
+
+	err = stub.DelState(consent_key(m.ILNSID, grantee))
+
+	if err != nil {
+		return internal("Error removing consent record")
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 list_consents - Returns every consent granted on the member, expired or not. Visible to the custodian and guardians,
+//					 and to anyone once the member is erased as its consents are deleted with it.
+//=================================================================================================================================
+func list_consents(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) ([]Consent, error) {
+
+	if !m.Erased && m.Name != caller && !is_guardian(m, caller, caller_affiliation) {
+		return nil, permission_denied("list_consents", map[string]interface{}{"ILNSID": m.ILNSID, "required_role": PARENTS, "caller_role": caller_affiliation, "is_custodian": false})
+	}
+
+	keys, err := list_keys(stub, ENTRY_CONSENT, m.ILNSID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	consents := []Consent{}
+
+	for _, key := range keys {
+
+		var c Consent
+
+		if _, err := read_document(stub, DOC_CONSENT, key, &c); err != nil {
+			return nil, err
+		}
+
+		consents = append(consents, c)
+	}
+
+	return consents, nil
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 GrantConsent - Grants grantee read access to the member until expires (RFC 3339), or indefinitely if expires is empty.
+//=================================================================================================================================
+func (c *ConsentContract) GrantConsent(ctx contractapi.TransactionContextInterface, ILNSID string, grantee string, expires string) error {
+	return with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		return grant_consent(stub, m, caller, caller_affiliation, grantee, expires)
+	})
+}
+
+//=================================================================================================================================
+//	 RevokeConsent - Removes the read access granted to grantee.
+//=================================================================================================================================
+func (c *ConsentContract) RevokeConsent(ctx contractapi.TransactionContextInterface, ILNSID string, grantee string) error {
+	return with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		return revoke_consent(stub, m, caller, caller_affiliation, grantee)
+	})
+}
+
+//=================================================================================================================================
+//	 ListConsents - Returns the consents granted on the member.
+//=================================================================================================================================
+func (c *ConsentContract) ListConsents(ctx contractapi.TransactionContextInterface, ILNSID string) ([]Consent, error) {
+
+	var consents []Consent
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		consents, err = list_consents(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return consents, err
+}
+
+//=================================================================================================================================
+//	 GetEvaluateTransactions - ListConsents is read only and is evaluated rather than submitted.
+//=================================================================================================================================
+func (c *ConsentContract) GetEvaluateTransactions() []string {
+	return []string{"ListConsents"}
+}
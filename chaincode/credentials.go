@@ -0,0 +1,740 @@
+package chaincode
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Verifiable credentials - Facts about a member (its birth registration, blood group, vaccinations or death) are
+//							  issued to it as W3C Verifiable Credentials, encoded as JWTs signed with the Ed25519 key of
+//							  the issuing organisation. An administrator registers each organisation's public key with
+//							  registry:SetIssuerKey, and the custodian issuing a credential passes the private key,
+//							  base64 encoded, in the transient map under TRANSIENT_SIGNING_KEY. Ed25519 signatures are
+//							  deterministic, so every endorsing peer signs the same JWT, and the key never reaches the
+//							  ledger.
+//
+//							  The ledger anchors the SHA-256 of each JWT and its revocation status under
+//							  credential:<ILNSID>:<txID>, the txID being that of the issuing transaction. Anyone holding a
+//							  credential can check it with query:VerifyCredential, which needs no access to the member.
+//==============================================================================================================================
+const ENTRY_CREDENTIAL = "credential"
+const ENTRY_ISSUER_KEY = "issuer_key"
+
+const TRANSIENT_SIGNING_KEY = "medhist.signing_key"
+
+const CREDENTIAL_BIRTH_REGISTRATION = "birth_registration"
+const CREDENTIAL_BLOOD_GROUP = "blood_group"
+const CREDENTIAL_VACCINATION = "vaccination"
+const CREDENTIAL_DEATH = "death"
+
+var CREDENTIAL_TYPES = []string{CREDENTIAL_BIRTH_REGISTRATION, CREDENTIAL_BLOOD_GROUP, CREDENTIAL_VACCINATION, CREDENTIAL_DEATH}
+
+var CREDENTIAL_CLASSES = map[string]string{
+	CREDENTIAL_BIRTH_REGISTRATION: "BirthRegistrationCredential",
+	CREDENTIAL_BLOOD_GROUP:        "BloodGroupCredential",
+	CREDENTIAL_VACCINATION:        "VaccinationCredential",
+	CREDENTIAL_DEATH:              "DeathCredential",
+}
+
+//==============================================================================================================================
+//	 Credential identifiers - Issuers, members and credentials are named by URNs, the credential by the transaction that
+//							  issued it.
+//==============================================================================================================================
+const VC_CONTEXT = "https://www.w3.org/2018/credentials/v1"
+const VC_STATUS_TYPE = "MedhistLedgerStatus"
+
+const ISSUER_PREFIX = "urn:medhist:issuer:"
+const SUBJECT_PREFIX = "urn:medhist:member:"
+const CREDENTIAL_PREFIX = "urn:medhist:credential:"
+
+const CREDENTIAL_ID_PATTERN = `^urn:medhist:credential:[0-9A-Za-z]{1,64}$`
+const CREDENTIAL_ID_REASON = "must be the id of a credential, " + CREDENTIAL_PREFIX + "<txID>"
+
+var credential_id_format = regexp.MustCompile(CREDENTIAL_ID_PATTERN)
+
+const MAX_REVOCATION_REASON = 256
+
+//==============================================================================================================================
+//	 Issuer_Key - The Ed25519 public key, base64 encoded, an organisation signs credentials with. Version counts the keys
+//				  the organisation has registered; credentials keep the key they were signed with.
+//==============================================================================================================================
+type Issuer_Key struct {
+	Org            string `json:"org"`
+	Public_Key     string `json:"publicKey"`
+	Version        int    `json:"version"`
+	Schema_Version int    `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Verifiable_Credential - A credential in the W3C Verifiable Credentials data model, as carried in the vc claim of its
+//							 JWT. The subject holds the facts of the credential's type only.
+//==============================================================================================================================
+type Verifiable_Credential struct {
+	Context            []string           `json:"@context"`
+	ID                 string             `json:"id"`
+	Type               []string           `json:"type"`
+	Issuer             string             `json:"issuer"`
+	Issuance_Date      string             `json:"issuanceDate"`
+	Expiration_Date    string             `json:"expirationDate,omitempty" metadata:",optional"`
+	Credential_Subject Credential_Subject `json:"credentialSubject"`
+	Credential_Status  Credential_Status  `json:"credentialStatus"`
+}
+
+type Credential_Subject struct {
+	ID           string                   `json:"id"`
+	ILNSID       string                   `json:"ILNSID"`
+	Birth_Date   string                   `json:"birthDate,omitempty" metadata:",optional"`
+	Gender       string                   `json:"gender,omitempty" metadata:",optional"`
+	Blood_Group  string                   `json:"bloodGroup,omitempty" metadata:",optional"`
+	Deceased     bool                     `json:"deceased,omitempty" metadata:",optional"`
+	Vaccinations []Credential_Vaccination `json:"vaccinations,omitempty" metadata:",optional"`
+}
+
+type Credential_Vaccination struct {
+	Vaccine string `json:"vaccine"`
+	Dose    int    `json:"dose"`
+	Date    string `json:"date"`
+}
+
+type Credential_Status struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+//==============================================================================================================================
+//	 jwt_header / jwt_claims - The header and payload of a credential's JWT. Kid names the issuer key and its version.
+//==============================================================================================================================
+type jwt_header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type jwt_claims struct {
+	Issuer     string                `json:"iss"`
+	Subject    string                `json:"sub"`
+	ID         string                `json:"jti"`
+	Not_Before int64                 `json:"nbf"`
+	Expires    int64                 `json:"exp,omitempty"`
+	VC         Verifiable_Credential `json:"vc"`
+}
+
+//==============================================================================================================================
+//	 Credential_Record - The anchor of an issued credential, stored under credential:<ILNSID>:<txID>. SHA256 is the hex
+//						 SHA-256 of the JWT and Public_Key the issuer key it was signed with.
+//==============================================================================================================================
+type Credential_Record struct {
+	ID             string `json:"id"`
+	ILNSID         string `json:"ILNSID"`
+	Type           string `json:"type"`
+	Issuer         string `json:"issuer"`
+	Public_Key     string `json:"publicKey"`
+	Key_Version    int    `json:"keyVersion"`
+	Issued_By      string `json:"issuedBy"`
+	SHA256         string `json:"sha256"`
+	Issued         string `json:"issued"`
+	Expires        string `json:"expires,omitempty" metadata:",optional"`
+	Revoked        bool   `json:"revoked"`
+	Revoked_At     string `json:"revokedAt,omitempty" metadata:",optional"`
+	Revoked_By     string `json:"revokedBy,omitempty" metadata:",optional"`
+	Reason         string `json:"reason,omitempty" metadata:",optional"`
+	Tx_ID          string `json:"txID"`
+	Schema_Version int    `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Issued_Credential - Returned by issue_credential: the anchor, the credential and the signed JWT to hand to the holder.
+//==============================================================================================================================
+type Issued_Credential struct {
+	Record     Credential_Record     `json:"record"`
+	Credential Verifiable_Credential `json:"credential"`
+	JWT        string                `json:"jwt"`
+}
+
+//==============================================================================================================================
+//	 Credential_Verification - Returned by verify_credential. Anchored is set when the ledger holds the JWT's hash, and
+//							   Verified when it is also correctly signed, not revoked and not expired.
+//==============================================================================================================================
+type Credential_Verification struct {
+	ID       string             `json:"id"`
+	Anchored bool               `json:"anchored"`
+	Revoked  bool               `json:"revoked"`
+	Expired  bool               `json:"expired"`
+	Verified bool               `json:"verified"`
+	Record   *Credential_Record `json:"record,omitempty" metadata:",optional"`
+}
+
+//==============================================================================================================================
+//	 CredentialContract - Transactions that issue, list and revoke verifiable credentials of members.
+//==============================================================================================================================
+type CredentialContract struct {
+	contractapi.Contract
+}
+
+//==============================================================================================================================
+//	 caller_signing_key - The Ed25519 private key the caller passed in the transient map, nil if none was passed.
+//==============================================================================================================================
+func caller_signing_key(stub shim.ChaincodeStubInterface) (ed25519.PrivateKey, error) {
+
+	transient, err := stub.GetTransient()
+
+	if err != nil {
+		return nil, internal("Unable to read the transient map")
+	}
+
+	encoded, ok := transient[TRANSIENT_SIGNING_KEY]
+
+	if !ok {
+		return nil, nil
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(string(encoded))
+
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, invalid(TRANSIENT_SIGNING_KEY, "", "must be a base64 Ed25519 private key seed")
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+//==============================================================================================================================
+//	 retrieve_issuer_key - The issuer key registered for org, nil if there is none.
+//==============================================================================================================================
+func retrieve_issuer_key(stub shim.ChaincodeStubInterface, org string) (*Issuer_Key, error) {
+
+	var k Issuer_Key
+
+	found, err := read_document(stub, DOC_ISSUER_KEY, issuer_key_key(org), &k)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+//=================================================================================================================================
+//	 set_issuer_key - Registers the public key an organisation signs credentials with. Registering a new key raises its
+//					  version; credentials already issued keep verifying against the key they were signed with. Only
+//					  administrators may register keys.
+//=================================================================================================================================
+func set_issuer_key(stub shim.ChaincodeStubInterface, caller_affiliation string, org string, public_key string) (*Issuer_Key, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("set_issuer_key", ADMIN, caller_affiliation)
+	}
+
+	if raw, err := base64.StdEncoding.DecodeString(public_key); err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, invalid("public_key", public_key, "must be a base64 Ed25519 public key")
+	}
+
+	existing, err := retrieve_issuer_key(stub, org)
+
+	if err != nil {
+		return nil, err
+	}
+
+	k := Issuer_Key{Org: org, Public_Key: public_key, Version: 1}
+
+	if existing != nil {
+
+		if existing.Public_Key == public_key {
+			return existing, nil
+		}
+
+		k.Version = existing.Version + 1
+	}
+
+	k.Schema_Version = schema_version(DOC_ISSUER_KEY)
+
+	bytes, err := json.Marshal(k)
+
+	if err != nil {
+		return nil, internal("Error converting issuer key")
+	}
+
+	if err = stub.PutState(issuer_key_key(org), bytes); err != nil {
+		return nil, internal("Error storing issuer key")
+	}
+
+	return &k, nil
+}
+
+//==============================================================================================================================
+//	 credential_subject - The facts of m a credential of credential_type attests. A fact that is not yet recorded, or that
+//						  is encrypted and was not opened, can not be attested.
+//==============================================================================================================================
+func credential_subject(stub shim.ChaincodeStubInterface, m Member, credential_type string) (Credential_Subject, error) {
+
+	subject := Credential_Subject{ID: SUBJECT_PREFIX + m.ILNSID, ILNSID: m.ILNSID}
+
+	switch credential_type {
+	case CREDENTIAL_BIRTH_REGISTRATION:
+
+		if m.DOB == ENCRYPTED {
+			return subject, invalid("DOB", m.DOB, "the DOB is encrypted, pass its data key in "+TRANSIENT_DATA_KEYS)
+		}
+
+		if m.DOB == UNDEFINED {
+			return subject, invalid("DOB", m.DOB, "a DOB is required to attest a birth registration")
+		}
+
+		subject.Birth_Date = m.DOB
+
+		if m.Gender != UNDEFINED {
+			subject.Gender = m.Gender
+		}
+
+	case CREDENTIAL_BLOOD_GROUP:
+
+		if m.BloodGrp == UNDEFINED {
+			return subject, invalid("BloodGrp", m.BloodGrp, "a blood group is required to attest it")
+		}
+
+		subject.Blood_Group = m.BloodGrp
+
+	case CREDENTIAL_VACCINATION:
+
+		history, err := retrieve_immunizations(stub, m)
+
+		if err != nil {
+			return subject, err
+		}
+
+		if history.Redacted {
+			return subject, details_denied("credential_subject", m)
+		}
+
+		if len(history.Immunizations) == 0 {
+			return subject, invalid("type", credential_type, "no immunizations are recorded to attest")
+		}
+
+		for _, i := range history.Immunizations {
+			subject.Vaccinations = append(subject.Vaccinations, Credential_Vaccination{Vaccine: i.Vaccine, Dose: i.Dose, Date: i.Date})
+		}
+
+	case CREDENTIAL_DEATH:
+
+		if !m.Dead {
+			return subject, invalid("type", credential_type, "only the death of a dead member can be attested")
+		}
+
+		subject.Deceased = true
+
+	default:
+		return subject, invalid("type", credential_type, "must be one of "+strings.Join(CREDENTIAL_TYPES, ", "))
+	}
+
+	return subject, nil
+}
+
+//==============================================================================================================================
+//	 encode_jwt / decode_jwt - The compact serialisation of a JWT signed with EdDSA, and its parts. decode_jwt does not
+//							   check the signature.
+//==============================================================================================================================
+func encode_jwt(header jwt_header, claims jwt_claims, key ed25519.PrivateKey) (string, error) {
+
+	h, err := json.Marshal(header)
+
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(input))), nil
+}
+
+func decode_jwt(token string) (jwt_header, jwt_claims, []byte, bool) {
+
+	var header jwt_header
+	var claims jwt_claims
+
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return header, claims, nil, false
+	}
+
+	h, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+	c, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+	signature, err3 := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err1 != nil || err2 != nil || err3 != nil || json.Unmarshal(h, &header) != nil || json.Unmarshal(c, &claims) != nil {
+		return header, claims, nil, false
+	}
+
+	return header, claims, signature, true
+}
+
+//==============================================================================================================================
+//	 retrieve_credential - Gets the anchor of the credential issued to the member by the transaction tx_ID. Returns nil
+//						   if there is none.
+//==============================================================================================================================
+func retrieve_credential(stub shim.ChaincodeStubInterface, ILNSID string, tx_ID string) (*Credential_Record, error) {
+
+	var r Credential_Record
+
+	found, err := read_document(stub, DOC_CREDENTIAL, credential_key(ILNSID, tx_ID), &r)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func store_credential(stub shim.ChaincodeStubInterface, r Credential_Record) error {
+
+	bytes, err := json.Marshal(r)
+
+	if err != nil {
+		return internal("Error converting credential record")
+	}
+
+	if err = stub.PutState(credential_key(r.ILNSID, r.Tx_ID), bytes); err != nil {
+		return internal("Error storing credential record")
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 issue_credential - Issues a credential of credential_type about the member, signed with the key of the caller's
+//						organisation, and anchors it. Only the custodian may issue credentials, with the signing key
+//						registered for its organisation. expires is an RFC 3339 timestamp, or empty for a credential
+//						that does not expire.
+//=================================================================================================================================
+func issue_credential(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, caller_org string, credential_type string, expires string) (*Issued_Credential, error) {
+
+	if m.Erased {
+		return nil, erased_state(m)
+	}
+
+	if m.Name != caller {
+		return nil, permission_denied("issue_credential", map[string]interface{}{"ILNSID": m.ILNSID, "is_custodian": false})
+	}
+
+	if m.Redacted {
+		return nil, details_denied("issue_credential", m)
+	}
+
+	subject, err := credential_subject(stub, m, credential_type)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var expires_at time.Time
+
+	if expires != "" {
+
+		if expires_at, err = time.Parse(time.RFC3339, expires); err != nil {
+			return nil, invalid("expires", expires, "must be an RFC 3339 timestamp")
+		}
+
+		if !expires_at.After(now) {
+			return nil, invalid("expires", expires, "must be in the future")
+		}
+	}
+
+	key, err := caller_signing_key(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, permission_denied("A signing key is needed to issue credentials", map[string]interface{}{"ILNSID": m.ILNSID, "transient": TRANSIENT_SIGNING_KEY})
+	}
+
+	issuer_key, err := retrieve_issuer_key(stub, caller_org)
+
+	if err != nil {
+		return nil, err
+	}
+
+	public_key := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+
+	if issuer_key == nil || issuer_key.Public_Key != public_key {
+		return nil, permission_denied("The key passed in "+TRANSIENT_SIGNING_KEY+" is not the registered issuer key of "+caller_org, map[string]interface{}{"ILNSID": m.ILNSID, "org": caller_org})
+	}
+
+	tx_ID := stub.GetTxID()
+	issuer := ISSUER_PREFIX + caller_org
+	id := CREDENTIAL_PREFIX + tx_ID
+
+	vc := Verifiable_Credential{
+		Context:            []string{VC_CONTEXT},
+		ID:                 id,
+		Type:               []string{"VerifiableCredential", CREDENTIAL_CLASSES[credential_type]},
+		Issuer:             issuer,
+		Issuance_Date:      now.Format(time.RFC3339),
+		Credential_Subject: subject,
+		Credential_Status:  Credential_Status{ID: id + "#status", Type: VC_STATUS_TYPE},
+	}
+
+	claims := jwt_claims{Issuer: issuer, Subject: subject.ID, ID: id, Not_Before: now.Unix()}
+
+	if expires != "" {
+		vc.Expiration_Date = expires_at.UTC().Format(time.RFC3339)
+		claims.Expires = expires_at.Unix()
+	}
+
+	claims.VC = vc
+
+	token, err := encode_jwt(jwt_header{Alg: "EdDSA", Typ: "JWT", Kid: issuer + "#" + strconv.Itoa(issuer_key.Version)}, claims, key)
+
+	if err != nil {
+		return nil, internal("Error encoding credential")
+	}
+
+	hash := sha256.Sum256([]byte(token))
+
+	r := Credential_Record{
+		ID:             id,
+		ILNSID:         m.ILNSID,
+		Type:           credential_type,
+		Issuer:         caller_org,
+		Public_Key:     public_key,
+		Key_Version:    issuer_key.Version,
+		Issued_By:      caller,
+		SHA256:         hex.EncodeToString(hash[:]),
+		Issued:         vc.Issuance_Date,
+		Expires:        vc.Expiration_Date,
+		Tx_ID:          tx_ID,
+		Schema_Version: schema_version(DOC_CREDENTIAL),
+	}
+
+	if err = store_credential(stub, r); err != nil {
+		return nil, err
+	}
+
+	return &Issued_Credential{Record: r, Credential: vc, JWT: token}, nil
+}
+
+//=================================================================================================================================
+//	 revoke_credential - Marks a credential issued to the member revoked. The user who issued it, the custodian if it
+//						 belongs to the issuing organisation, and administrators may revoke it. A credential is revoked
+//						 once.
+//=================================================================================================================================
+func revoke_credential(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, caller_org string, id string, reason string) (*Credential_Record, error) {
+
+	if !credential_id_format.MatchString(id) {
+		return nil, invalid("id", id, CREDENTIAL_ID_REASON)
+	}
+
+	if len(reason) > MAX_REVOCATION_REASON {
+		return nil, invalid("reason", reason, "must be at most "+strconv.Itoa(MAX_REVOCATION_REASON)+" characters")
+	}
+
+	r, err := retrieve_credential(stub, m.ILNSID, strings.TrimPrefix(id, CREDENTIAL_PREFIX))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, not_found("No credential "+id+" was issued to "+m.ILNSID, map[string]interface{}{"ILNSID": m.ILNSID, "id": id})
+	}
+
+	if caller != r.Issued_By && !(m.Name == caller && caller_org == r.Issuer) && caller_affiliation != ADMIN {
+		return nil, permission_denied("revoke_credential", map[string]interface{}{"ILNSID": m.ILNSID, "id": id, "issuer": r.Issuer})
+	}
+
+	if r.Revoked {
+		return nil, conflict("Credential "+id+" is already revoked", map[string]interface{}{"ILNSID": m.ILNSID, "id": id})
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.Revoked = true
+	r.Revoked_At = now.Format(time.RFC3339)
+	r.Revoked_By = caller
+	r.Reason = reason
+	r.Schema_Version = schema_version(DOC_CREDENTIAL)
+
+	if err = store_credential(stub, *r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+//=================================================================================================================================
+//	 list_credentials - Returns the credentials issued to the member in key order. Visible to the same callers as
+//						get_member_details.
+//=================================================================================================================================
+func list_credentials(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) ([]Credential_Record, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("list_credentials", m)
+	}
+
+	keys, err := list_keys(stub, ENTRY_CREDENTIAL, m.ILNSID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := []Credential_Record{}
+
+	for _, key := range keys {
+
+		var r Credential_Record
+
+		if _, err := read_document(stub, DOC_CREDENTIAL, key, &r); err != nil {
+			return nil, err
+		}
+
+		credentials = append(credentials, r)
+	}
+
+	return credentials, nil
+}
+
+//=================================================================================================================================
+//	 verify_credential - Checks a credential JWT against its anchor: that the ledger holds its hash, that it is signed
+//						 with the key it was issued with, and that it is neither revoked nor expired. Open to every
+//						 caller, who learns nothing beyond the status of the credential they hold.
+//=================================================================================================================================
+func verify_credential(stub shim.ChaincodeStubInterface, token string) (*Credential_Verification, error) {
+
+	header, claims, signature, ok := decode_jwt(token)
+
+	if !ok || header.Alg != "EdDSA" || !credential_id_format.MatchString(claims.ID) || !strings.HasPrefix(claims.Subject, SUBJECT_PREFIX) {
+		return nil, invalid("jwt", "", "must be a credential JWT issued by credential:IssueCredential")
+	}
+
+	ILNSID := strings.TrimPrefix(claims.Subject, SUBJECT_PREFIX)
+
+	if err := validate_ILNSID(ILNSID); err != nil {
+		return nil, invalid("jwt", "", "must be a credential JWT issued by credential:IssueCredential")
+	}
+
+	result := Credential_Verification{ID: claims.ID}
+
+	r, err := retrieve_credential(stub, ILNSID, strings.TrimPrefix(claims.ID, CREDENTIAL_PREFIX))
+
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(token))
+
+	if r == nil || r.SHA256 != hex.EncodeToString(hash[:]) {
+		return &result, nil
+	}
+
+	result.Anchored = true
+	result.Record = r
+	result.Revoked = r.Revoked
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Expires != "" {
+		expires, err := time.Parse(time.RFC3339, r.Expires)
+		result.Expired = err != nil || !now.Before(expires)
+	}
+
+	public_key, err := base64.StdEncoding.DecodeString(r.Public_Key)
+
+	if err != nil || len(public_key) != ed25519.PublicKeySize {
+		return nil, internal("Corrupt issuer key of credential " + r.ID)
+	}
+
+	input := token[:strings.LastIndex(token, ".")]
+	signed := ed25519.Verify(ed25519.PublicKey(public_key), []byte(input), signature) && claims.Issuer == ISSUER_PREFIX+r.Issuer
+
+	result.Verified = signed && !result.Revoked && !result.Expired
+
+	return &result, nil
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 IssueCredential - Issues a signed credential of a fact about the member and anchors its hash.
+//=================================================================================================================================
+func (c *CredentialContract) IssueCredential(ctx contractapi.TransactionContextInterface, ILNSID string, credential_type string, expires string) (*Issued_Credential, error) {
+
+	var issued *Issued_Credential
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		issued, err = issue_credential(stub, m, caller, caller_affiliation, get_caller_org(ctx), credential_type, expires)
+		return err
+	})
+
+	return issued, err
+}
+
+//=================================================================================================================================
+//	 RevokeCredential - Marks a credential issued to the member revoked.
+//=================================================================================================================================
+func (c *CredentialContract) RevokeCredential(ctx contractapi.TransactionContextInterface, ILNSID string, id string, reason string) (*Credential_Record, error) {
+
+	var revoked *Credential_Record
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		revoked, err = revoke_credential(stub, m, caller, caller_affiliation, get_caller_org(ctx), id, reason)
+		return err
+	})
+
+	return revoked, err
+}
+
+//=================================================================================================================================
+//	 ListCredentials - Returns the credentials issued to the member with their revocation status.
+//=================================================================================================================================
+func (c *CredentialContract) ListCredentials(ctx contractapi.TransactionContextInterface, ILNSID string) ([]Credential_Record, error) {
+
+	var credentials []Credential_Record
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		credentials, err = list_credentials(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return credentials, err
+}
+
+//=================================================================================================================================
+//	 GetEvaluateTransactions - ListCredentials is read only and is evaluated rather than submitted.
+//=================================================================================================================================
+func (c *CredentialContract) GetEvaluateTransactions() []string {
+	return []string{"ListCredentials"}
+}
@@ -0,0 +1,504 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Clinical details - A member is stored in two parts. Its public stub, on the world state, holds what every
+//						organisation on the channel may see: the ILNSID, the lifecycle (status, dead and erased), the
+//						custodian, the custodian's organisation and the treating organisations, those that have held the
+//						member. Its clinical details (DOB, gender, blood group, weight, parents, diagnoses and notes) are
+//						kept in the implicit private data collection of each treating organisation, see
+//						clinical_collections, so that only their peers hold a copy, and the stub carries the SHA-256 of
+//						the details as stored so that anyone holding the ledger can check them.
+//
+//						The member's vitals and immunizations are kept beside the details, see read_clinical, with their
+//						hashes on the stub. An organisation that starts treating the member is sent a copy of each when
+//						a caller who can read them hands the member over or writes it, see share_clinical.
+//
+//						Only the member's custodian and clients of its treating organisations read its details, see
+//						may_read_details, from the collection of the caller's own organisation, so reads must be sent
+//						to one of its peers. Reads made elsewhere fall back to the stub with every detail REDACTED and
+//						redacted set; such callers may move the member through its lifecycle but not write its details.
+//						Members stored before the split carry no details hash and are read whole from the world state
+//						until they are next written. Members whose treating organisations were not yet recorded keep
+//						their details in DETAILS_COLLECTION, shared by every organisation, until a caller who can read
+//						them writes the member.
+//==============================================================================================================================
+const DETAILS_COLLECTION = "medhistClinical"
+const ENTRY_DETAILS = "details" // Kept in the clinical collections
+const IMPLICIT_COLLECTION_PREFIX = "_implicit_org_"
+
+const REDACTED = "REDACTED"
+
+//==============================================================================================================================
+//	 Member_Details - The part of a member stored in its clinical collections under details:<ILNSID>.
+//==============================================================================================================================
+type Member_Details struct {
+	ILNSID         string       `json:"ILNSID"`
+	DOB            string       `json:"DOB"`
+	Gender         string       `json:"gender"`
+	BloodGrp       string       `json:"BloodGrp"`
+	Weight         Weight       `json:"Weight"`
+	Parents        []string     `json:"parents"`
+	Diagnoses      []string     `json:"diagnoses,omitempty"`
+	Notes          string       `json:"notes,omitempty"`
+	Sealed         *Sealed      `json:"sealed,omitempty"`
+	Field_Leaves   []Field_Leaf `json:"fieldLeaves,omitempty"`
+	Schema_Version int          `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 details_of - The clinical details of m.
+//==============================================================================================================================
+func details_of(m Member) Member_Details {
+
+	return Member_Details{
+		ILNSID:       m.ILNSID,
+		DOB:          m.DOB,
+		Gender:       m.Gender,
+		BloodGrp:     m.BloodGrp,
+		Weight:       m.Weight,
+		Parents:      m.Parents,
+		Diagnoses:    m.Diagnoses,
+		Notes:        m.Notes,
+		Sealed:       m.Sealed,
+		Field_Leaves: m.Field_Leaves,
+	}
+}
+
+//==============================================================================================================================
+//	 apply_details - Sets the clinical details of m to d.
+//==============================================================================================================================
+func apply_details(m *Member, d Member_Details) {
+
+	m.DOB = d.DOB
+	m.Gender = d.Gender
+	m.BloodGrp = d.BloodGrp
+	m.Weight = d.Weight
+	m.Parents = d.Parents
+	m.Diagnoses = d.Diagnoses
+	m.Notes = d.Notes
+	m.Sealed = d.Sealed
+	m.Field_Leaves = d.Field_Leaves
+}
+
+//==============================================================================================================================
+//	 redact_details - Replaces the clinical details of m with what a caller who may not read them is shown.
+//==============================================================================================================================
+func redact_details(m *Member) {
+
+	apply_details(m, Member_Details{
+		DOB:      REDACTED,
+		Gender:   REDACTED,
+		BloodGrp: REDACTED,
+		Weight:   Weight{Unit: "kg"},
+		Parents:  []string{},
+	})
+}
+
+//==============================================================================================================================
+//	 details_hash - The hex SHA-256 of stored details, the hash a peer holds for the private data.
+//==============================================================================================================================
+func details_hash(value []byte) string {
+
+	hash := sha256.Sum256(value)
+
+	return hex.EncodeToString(hash[:])
+}
+
+//==============================================================================================================================
+//	 org_collection - The implicit private data collection of org, held only by the peers of org.
+//==============================================================================================================================
+func org_collection(org string) string {
+	return IMPLICIT_COLLECTION_PREFIX + org
+}
+
+//==============================================================================================================================
+//	 clinical_collections - The collections the clinical records of m are written to: the implicit collection of each of
+//							its treating organisations, or DETAILS_COLLECTION for members that have none recorded.
+//==============================================================================================================================
+func clinical_collections(m Member) []string {
+
+	if len(m.Treating_Orgs) == 0 {
+		return []string{DETAILS_COLLECTION}
+	}
+
+	collections := []string{}
+
+	for _, org := range m.Treating_Orgs {
+		collections = append(collections, org_collection(org))
+	}
+
+	return collections
+}
+
+//==============================================================================================================================
+//	 reading_collection - The collection the caller reads the clinical records of m from: that of their own organisation,
+//						  or DETAILS_COLLECTION for members with no treating organisations. Empty for a stub that does
+//						  not carry the caller.
+//==============================================================================================================================
+func reading_collection(stub shim.ChaincodeStubInterface, m Member) string {
+
+	if len(m.Treating_Orgs) == 0 {
+		return DETAILS_COLLECTION
+	}
+
+	if c, ok := stub.(*caller_stub); ok {
+		return org_collection(c.org)
+	}
+
+	return ""
+}
+
+//==============================================================================================================================
+//	 put_clinical - Writes a clinical record of m to each of its clinical collections.
+//==============================================================================================================================
+func put_clinical(stub shim.ChaincodeStubInterface, m Member, key string, value []byte) error {
+
+	for _, collection := range clinical_collections(m) {
+		if err := stub.PutPrivateData(collection, key, value); err != nil {
+			return internal("Error storing " + key + " in " + collection)
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 may_read_details - Whether the caller of the transaction may read the details of m: they are its custodian or a
+//						client of one of its treating organisations. Members with no treating organisations were
+//						stored before they were recorded and may be read by any member of the collection. A stub that
+//						does not carry the caller may not read the details of the others.
+//==============================================================================================================================
+func may_read_details(stub shim.ChaincodeStubInterface, m Member) bool {
+
+	if len(m.Treating_Orgs) == 0 {
+		return true
+	}
+
+	c, ok := stub.(*caller_stub)
+
+	if !ok {
+		return false
+	}
+
+	if c.caller == m.Name {
+		return true
+	}
+
+	for _, org := range m.Treating_Orgs {
+		if org == c.org {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================================================================
+//	 read_details - Fills in the clinical details of m, read from its stub, from the caller's collection, see
+//					reading_collection. Callers outside the member's treating organisations may not read them, see
+//					may_read_details, and the peer may not hold them, so in either case m is left redacted. Details
+//					that do not match the hash on the stub are an error.
+//==============================================================================================================================
+func read_details(stub shim.ChaincodeStubInterface, m *Member) error {
+
+	if m.Details_Hash == "" { // Stored whole before the split, or erased
+		return nil
+	}
+
+	if !may_read_details(stub, *m) {
+		redact_details(m)
+		m.Redacted = true
+		return nil
+	}
+
+	key := details_key(m.ILNSID)
+
+	value, err := stub.GetPrivateData(reading_collection(stub, *m), key)
+
+	if err != nil || value == nil {
+		redact_details(m)
+		m.Redacted = true
+		return nil
+	}
+
+	if details_hash(value) != m.Details_Hash {
+		return internal("The clinical details of member " + m.ILNSID + " do not match the hash on the ledger")
+	}
+
+	value, _, err = upgrade_document(DOC_DETAILS, key, value)
+
+	if err != nil {
+		return err
+	}
+
+	var d Member_Details
+
+	if err = json.Unmarshal(value, &d); err != nil {
+		return internal("Corrupt clinical details of member " + m.ILNSID)
+	}
+
+	apply_details(m, d)
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 save_details - Stores the clinical details of m in its clinical collections and leaves m as its public stub, redacted
+//					and carrying their hash. A member read redacted keeps the details already stored, which it may not
+//					change.
+//==============================================================================================================================
+func save_details(stub shim.ChaincodeStubInterface, m *Member) error {
+
+	if m.Redacted {
+
+		stored := *m
+		redact_details(&stored)
+
+		if !reflect.DeepEqual(details_of(stored), details_of(*m)) {
+			return details_denied("save_details", *m)
+		}
+
+		m.Redacted = false
+
+		return nil
+	}
+
+	d := details_of(*m)
+	d.Schema_Version = schema_version(DOC_DETAILS)
+
+	value, err := json.Marshal(d)
+
+	if err != nil {
+		return internal("Error converting the clinical details of member " + m.ILNSID)
+	}
+
+	if err = put_clinical(stub, *m, details_key(m.ILNSID), value); err != nil {
+		return err
+	}
+
+	redact_details(m)
+	m.Details_Hash = details_hash(value)
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 read_clinical - Reads a clinical record of m kept beside its details, e.g. its vitals, into v. hash is the hash of the
+//					 record on m's stub. The record is readable by the same callers as the details, see
+//					 may_read_details, and not at all when m was read redacted. Records written before they moved to
+//					 the collections carry no hash and are read from the world state until they are next written.
+//					 Returns false if the caller may not read the record, v is left as it was if there is none.
+//==============================================================================================================================
+func read_clinical(stub shim.ChaincodeStubInterface, m Member, doc_type string, key string, hash string, v interface{}) (bool, error) {
+
+	if m.Redacted || !may_read_details(stub, m) {
+		return false, nil
+	}
+
+	value, err := stub.GetPrivateData(reading_collection(stub, m), key)
+
+	if err != nil {
+		return false, nil
+	}
+
+	if value == nil {
+
+		if hash != "" { // The peer does not hold the record
+			return false, nil
+		}
+
+		_, err = read_document(stub, doc_type, key, v)
+
+		return true, err
+	}
+
+	if hash != "" && details_hash(value) != hash {
+		return false, internal("The " + doc_type + " record of member " + m.ILNSID + " does not match the hash on the ledger")
+	}
+
+	value, _, err = upgrade_document(doc_type, key, value)
+
+	if err != nil {
+		return false, err
+	}
+
+	if err = json.Unmarshal(value, v); err != nil {
+		return false, internal("Corrupt " + doc_type + " record of member " + m.ILNSID)
+	}
+
+	return true, nil
+}
+
+//==============================================================================================================================
+//	 save_clinical - Stores a clinical record of m and returns the hash to keep on its stub. A transaction run for a
+//					 caller only writes the record when it saves the member, see share_clinical, once the member's
+//					 treating organisations are known. A copy left in the world state from before the record moved
+//					 to the collections is deleted, its earlier versions stay in the public history.
+//==============================================================================================================================
+func save_clinical(stub shim.ChaincodeStubInterface, m Member, doc_type string, key string, hash string, v interface{}) (string, error) {
+
+	value, err := json.Marshal(v)
+
+	if err != nil {
+		return "", internal("Error converting the " + doc_type + " record of member " + m.ILNSID)
+	}
+
+	if c, ok := stub.(*caller_stub); ok {
+
+		if c.clinical == nil {
+			c.clinical = map[string][]byte{}
+		}
+
+		c.clinical[key] = value
+	} else if err = put_clinical(stub, m, key, value); err != nil {
+		return "", err
+	}
+
+	if hash == "" {
+
+		public, err := stub.GetState(key)
+
+		if err != nil {
+			return "", internal("Error retrieving " + doc_type + " record " + key)
+		}
+
+		if public != nil {
+			if err = stub.DelState(key); err != nil {
+				return "", internal("Unable to delete " + key)
+			}
+		}
+	}
+
+	return details_hash(value), nil
+}
+
+//==============================================================================================================================
+//	 share_clinical - Writes the vitals and immunizations of m saved by the transaction to its clinical collections. When
+//					  m has gained a treating organisation, and the caller could read it as it was, see previous, the
+//					  records it already had are copied to the new collections from the caller's, and a member that had
+//					  no treating organisations has its records purged from DETAILS_COLLECTION.
+//==============================================================================================================================
+func share_clinical(stub shim.ChaincodeStubInterface, previous Member, m Member) error {
+
+	c, ok := stub.(*caller_stub)
+
+	if !ok {
+		return nil
+	}
+
+	shared := !previous.Redacted && len(m.Treating_Orgs) != len(previous.Treating_Orgs)
+	from := reading_collection(stub, previous)
+
+	moved := []string{}
+
+	if shared && previous.Details_Hash != "" {
+		moved = append(moved, details_key(m.ILNSID)) // Written by save_details
+	}
+
+	records := []struct{ key, previous, hash string }{
+		{vitals_key(m.ILNSID), previous.Vitals_Hash, m.Vitals_Hash},
+		{immunizations_key(m.ILNSID), previous.Immunizations_Hash, m.Immunizations_Hash},
+	}
+
+	for _, r := range records {
+
+		value, saved := c.clinical[r.key]
+
+		if !saved && shared && r.hash != "" {
+
+			var err error
+
+			if value, err = stub.GetPrivateData(from, r.key); err != nil {
+				return internal("Unable to read " + r.key + " from " + from)
+			}
+
+			if value != nil && details_hash(value) != r.hash {
+				return internal("The record " + r.key + " does not match the hash on the ledger")
+			}
+		}
+
+		if shared && r.previous != "" && (saved || value != nil) {
+			moved = append(moved, r.key)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		if err := put_clinical(stub, m, r.key, value); err != nil {
+			return err
+		}
+
+		delete(c.clinical, r.key)
+	}
+
+	if len(previous.Treating_Orgs) > 0 || len(m.Treating_Orgs) == 0 {
+		return nil
+	}
+
+	for _, key := range moved {
+		if err := stub.PurgePrivateData(DETAILS_COLLECTION, key); err != nil {
+			return internal("Unable to purge " + key)
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 Details_Verification - Returned by verify_member_details. Details_Hash is the hash on the member's stub and
+//							Collection_Hash that of the details held in the collection of the custodian's organisation,
+//							which every peer of the channel has whether or not it is a member of the collection.
+//==============================================================================================================================
+type Details_Verification struct {
+	ILNSID          string `json:"ILNSID"`
+	Details_Hash    string `json:"detailsHash"`
+	Collection_Hash string `json:"collectionHash"`
+	Verified        bool   `json:"verified"`
+}
+
+//=================================================================================================================================
+//	 verify_member_details - Checks the clinical details held for the member match the hash on its public stub. Members
+//							 stored before the split, and erased members, have no details to verify.
+//=================================================================================================================================
+func verify_member_details(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) (*Details_Verification, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("verify_member_details", m)
+	}
+
+	result := Details_Verification{ILNSID: m.ILNSID, Details_Hash: m.Details_Hash}
+
+	if m.Details_Hash == "" {
+		return &result, nil
+	}
+
+	collection := DETAILS_COLLECTION
+
+	if len(m.Treating_Orgs) > 0 {
+		collection = org_collection(m.Custodian_Org)
+	}
+
+	hash, err := stub.GetPrivateDataHash(collection, details_key(m.ILNSID))
+
+	if err != nil {
+		return nil, internal("Unable to read the hash of the clinical details of member " + m.ILNSID)
+	}
+
+	if hash != nil {
+		result.Collection_Hash = hex.EncodeToString(hash)
+	}
+
+	result.Verified = result.Collection_Hash == result.Details_Hash
+
+	return &result, nil
+}
@@ -0,0 +1,291 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/ravivarmakv/SampleChainCode/disclosure"
+)
+
+//==============================================================================================================================
+//	 Selective disclosure - Each time a member is saved a Merkle tree is built over its salted clinical details and the
+//							ILNSID, see package disclosure, and the root is stored on the public stub as fieldsRoot. A
+//							caller who may read the member can then have query:ProveFields prove some of its fields to
+//							a third party, who checks the proof against the root on the ledger without seeing the rest.
+//
+//							The salts and leaves are kept with the clinical details in their collections. A field's
+//							salt is made each time the field is saved with a new value, so that a leaf disclosed once
+//							says nothing of later values, from the transaction ID and at least MIN_ENTROPY random bytes
+//							the client passes under TRANSIENT_ENTROPY. A save that would make a salt without them is
+//							refused, as a salt derived from the transaction alone could be derived again and the leaves
+//							of a proof's paths guessed at: clients that create members or change their details must
+//							pass the entropy, as the gateway and the command line do. The leaf of an encrypted field
+//							saved by a caller without its key is kept as it was; members encrypted before the tree
+//							existed have none until they are next saved by a caller who can open them.
+//==============================================================================================================================
+var DISCLOSED_FIELDS = []string{"ILNSID", "DOB", "gender", "BloodGrp", "Weight", "parents", "diagnoses", "notes"}
+
+//==============================================================================================================================
+//	 Field_Leaf - The hex salt and leaf of one of the DISCLOSED_FIELDS.
+//==============================================================================================================================
+type Field_Leaf struct {
+	Field string `json:"field"`
+	Salt  string `json:"salt"`
+	Leaf  string `json:"leaf"`
+}
+
+//==============================================================================================================================
+//	 Member_Root - Returned by get_fields_root.
+//==============================================================================================================================
+type Member_Root struct {
+	ILNSID string `json:"ILNSID"`
+	Root   string `json:"root"`
+}
+
+//==============================================================================================================================
+//	 disclosed_value - The JSON value of one of the DISCLOSED_FIELDS of m. Empty lists are written as [] whether or not
+//					   they were stored.
+//==============================================================================================================================
+func disclosed_value(m Member, field string) (string, error) {
+
+	value := member_field(m, field)
+
+	if field == "ILNSID" {
+		value = m.ILNSID
+	}
+
+	if list, ok := value.([]string); ok && len(list) == 0 {
+		value = []string{}
+	}
+
+	bytes, err := json.Marshal(value)
+
+	if err != nil {
+		return "", internal("Error converting " + field + " of member " + m.ILNSID)
+	}
+
+	return string(bytes), nil
+}
+
+//==============================================================================================================================
+//	 is_encrypted - Whether the field of m is sealed and was read without a key that opens it.
+//==============================================================================================================================
+func is_encrypted(m Member, field string) bool {
+
+	if m.Sealed == nil {
+		return false
+	}
+
+	_, sealed := m.Sealed.field(field)
+
+	return sealed && is_redacted(m, field)
+}
+
+//==============================================================================================================================
+//	 field_leaves - The leaves of m in DISCLOSED_FIELDS order, decoded. ok is false if m has no complete tree.
+//==============================================================================================================================
+func field_leaves(m Member) ([][]byte, bool, error) {
+
+	if len(m.Field_Leaves) != len(DISCLOSED_FIELDS) {
+		return nil, false, nil
+	}
+
+	leaves := [][]byte{}
+
+	for i, field := range DISCLOSED_FIELDS {
+
+		leaf, err := hex.DecodeString(m.Field_Leaves[i].Leaf)
+
+		if err != nil || m.Field_Leaves[i].Field != field {
+			return nil, false, internal("Corrupt field tree of member " + m.ILNSID)
+		}
+
+		leaves = append(leaves, leaf)
+	}
+
+	return leaves, true, nil
+}
+
+//==============================================================================================================================
+//	 update_field_tree - Recomputes the leaves and root of m before it is stored, making a fresh salt from the caller's
+//						 entropy for each field saved for the first time or with a new value. Erased members have no
+//						 tree, and a member read redacted keeps the one stored.
+//==============================================================================================================================
+func update_field_tree(stub shim.ChaincodeStubInterface, m *Member) error {
+
+	if m.Erased || m.Redacted {
+		return nil
+	}
+
+	stored := map[string]Field_Leaf{}
+
+	for _, l := range m.Field_Leaves {
+		stored[l.Field] = l
+	}
+
+	entropy, err := caller_entropy(stub)
+
+	if err != nil {
+		return err
+	}
+
+	tree := []Field_Leaf{}
+	leaves := [][]byte{}
+
+	for _, field := range DISCLOSED_FIELDS {
+
+		previous, ok := stored[field]
+
+		if is_encrypted(*m, field) {
+
+			if !ok { // Encrypted before the tree existed
+				return nil
+			}
+
+			leaf, err := hex.DecodeString(previous.Leaf)
+
+			if err != nil {
+				return internal("Corrupt field tree of member " + m.ILNSID)
+			}
+
+			tree = append(tree, previous)
+			leaves = append(leaves, leaf)
+
+			continue
+		}
+
+		value, err := disclosed_value(*m, field)
+
+		if err != nil {
+			return err
+		}
+
+		if ok { // Kept while the field holds the value it was salted for
+
+			salt, err := hex.DecodeString(previous.Salt)
+
+			if err != nil {
+				return internal("Corrupt field tree of member " + m.ILNSID)
+			}
+
+			if leaf := disclosure.Leaf(salt, field, value); hex.EncodeToString(leaf) == previous.Leaf {
+
+				tree = append(tree, previous)
+				leaves = append(leaves, leaf)
+
+				continue
+			}
+		}
+
+		if len(entropy) < MIN_ENTROPY {
+			return invalid(TRANSIENT_ENTROPY, "", fmt.Sprintf("at least %d random bytes must be passed to salt the fields of member %s", MIN_ENTROPY, m.ILNSID))
+		}
+
+		salt := derive([]byte(entropy), "field salt", m.ILNSID, stub.GetTxID(), field)
+		leaf := disclosure.Leaf(salt, field, value)
+
+		tree = append(tree, Field_Leaf{Field: field, Salt: hex.EncodeToString(salt), Leaf: hex.EncodeToString(leaf)})
+		leaves = append(leaves, leaf)
+	}
+
+	m.Field_Leaves = tree
+	m.Fields_Root = hex.EncodeToString(disclosure.Root(leaves))
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 prove_fields - Returns a proof of the named fields of the member against its fields root. The ILNSID is always
+//					disclosed, binding the proof to the member. Visible to the same callers as get_member_details;
+//					encrypted fields can be proven only with a key that opens them.
+//=================================================================================================================================
+func prove_fields(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, fields []string) (*disclosure.Proof, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("prove_fields", m)
+	}
+
+	if m.Erased {
+		return nil, erased_state(m)
+	}
+
+	if m.Redacted {
+		return nil, details_denied("prove_fields", m)
+	}
+
+	wanted := map[string]bool{"ILNSID": true}
+
+	for _, field := range fields {
+
+		known := false
+
+		for _, disclosed := range DISCLOSED_FIELDS {
+			known = known || field == disclosed
+		}
+
+		if !known {
+			return nil, invalid("fields", field, "must be among "+strings.Join(DISCLOSED_FIELDS, ", "))
+		}
+
+		wanted[field] = true
+	}
+
+	leaves, ok, err := field_leaves(m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok || m.Fields_Root == "" {
+		return nil, not_found("Member "+m.ILNSID+" has no fields root, one is computed when it is next saved", map[string]interface{}{"ILNSID": m.ILNSID})
+	}
+
+	if hex.EncodeToString(disclosure.Root(leaves)) != m.Fields_Root {
+		return nil, internal("The field tree of member " + m.ILNSID + " does not match the root on the ledger")
+	}
+
+	proof := disclosure.Proof{ILNSID: m.ILNSID, Root: m.Fields_Root, Fields: []disclosure.Field{}}
+
+	for i, field := range DISCLOSED_FIELDS {
+
+		if !wanted[field] {
+			continue
+		}
+
+		if is_encrypted(m, field) {
+			return nil, invalid(field, ENCRYPTED, "the field is encrypted, pass its data key in "+TRANSIENT_DATA_KEYS)
+		}
+
+		value, err := disclosed_value(m, field)
+
+		if err != nil {
+			return nil, err
+		}
+
+		salt, _ := hex.DecodeString(m.Field_Leaves[i].Salt)
+
+		if !bytes.Equal(disclosure.Leaf(salt, field, value), leaves[i]) {
+			return nil, internal("The " + field + " of member " + m.ILNSID + " does not match its leaf")
+		}
+
+		proof.Fields = append(proof.Fields, disclosure.Field{Field: field, Value: value, Salt: m.Field_Leaves[i].Salt, Path: disclosure.Path(leaves, i)})
+	}
+
+	return &proof, nil
+}
+
+//=================================================================================================================================
+//	 get_fields_root - Returns the fields root of the member, which is on its public stub and so open to every caller.
+//=================================================================================================================================
+func get_fields_root(m Member) (*Member_Root, error) {
+
+	if m.Fields_Root == "" {
+		return nil, not_found("Member "+m.ILNSID+" has no fields root", map[string]interface{}{"ILNSID": m.ILNSID})
+	}
+
+	return &Member_Root{ILNSID: m.ILNSID, Root: m.Fields_Root}, nil
+}
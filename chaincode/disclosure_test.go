@@ -0,0 +1,51 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/disclosure"
+)
+
+// A proof from query:ProveFields must check out against the root from query:GetFieldsRoot with nothing but the
+// disclosure package, and stop doing so once the field it proves changes. The steps are those of disclosure.json.
+func TestProvenFieldsVerifyAgainstTheLedger(t *testing.T) {
+
+	r := replay_scenario(t, "disclosure.json")
+	r.until("any caller reads the root")
+
+	var root chaincode.Member_Root
+
+	if err := json.Unmarshal(r.h.Result, &root); err != nil {
+		t.Fatal(err)
+	}
+
+	r.until("a proof discloses the ILNSID and the fields asked for")
+
+	var proof disclosure.Proof
+
+	if err := json.Unmarshal(r.h.Result, &proof); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := disclosure.Verify(proof, root.Root); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if _, ok := proof.Field("notes"); ok || len(proof.Fields) != 2 {
+		t.Errorf("disclosed %+v", proof.Fields)
+	}
+
+	r.until("so a leaf disclosed before says nothing of the new value")
+
+	payload, err := r.h.Query(r.identity("gina"), "query:GetFieldsRoot", "AB12345679")
+
+	if err != nil || json.Unmarshal(payload, &root) != nil {
+		t.Fatalf("root: %v", err)
+	}
+
+	if err = disclosure.Verify(proof, root.Root); err != disclosure.ErrRootMismatch {
+		t.Errorf("a proof of the old blood group verified against the new root: %v", err)
+	}
+}
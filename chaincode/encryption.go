@@ -0,0 +1,989 @@
+package chaincode
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Field encryption - Once any organisation has registered an encryption key the sensitive fields of a member are stored
+//						encrypted. Each member has a data key that encrypts its fields (AES-256-GCM), and the data key is
+//						stored wrapped for every registered organisation (X25519 and AES-256-GCM), so that each can open
+//						the record with its own private key and no organisation learns another's.
+//
+//						The organisation's private key never leaves the organisation. Clients fetch the wrapped data keys
+//						with get_data_keys, unwrap them with UnwrapDataKey and pass the data keys of the members they name,
+//						base64 encoded by ILNSID, in the transient map under TRANSIENT_DATA_KEYS. They never reach the
+//						ledger. Reads open the record when passed its data key, other reads see ENCRYPTED in place of each
+//						encrypted field. Writes of a sensitive field need the data key while other fields stay sealed under
+//						it. Endorsing peers must reach the same ciphertext, so a new data key, nonces and ephemeral keys are
+//						derived from the random bytes the client passes under TRANSIENT_ENTROPY, the member and the
+//						transaction ID rather than drawn at random. A new data key is refused without MIN_ENTROPY bytes, so
+//						that nothing left on the ledger derives it again once erase_member has purged it.
+//
+//						The wrapped data keys are kept off the public state in DATA_KEY_COLLECTION, a private data
+//						collection of the organisations, so that erase_member can purge them.
+//==============================================================================================================================
+const TRANSIENT_DATA_KEYS = "medhist.data_keys"
+const TRANSIENT_ENTROPY = "medhist.entropy"
+const MIN_ENTROPY = 16
+
+const DATA_KEY_COLLECTION = "medhistDataKeys"
+const ENTRY_DATA_KEY = "data_key" // Private, exported wrapped
+
+const ENCRYPTED = "ENCRYPTED"
+
+var SENSITIVE_FIELDS = []string{"DOB", "diagnoses", "notes"}
+
+const DEFAULT_ROTATION_BATCH = 50
+const MAX_ROTATION_BATCH = 500
+
+//==============================================================================================================================
+//	 Org_Key - The X25519 public key, base64 encoded, data keys are wrapped for an organisation. Version counts the keys the
+//			   organisation has registered, members are moved to a new key by rotate_keys.
+//==============================================================================================================================
+type Org_Key struct {
+	Org            string `json:"org"`
+	Public_Key     string `json:"publicKey"`
+	Version        int    `json:"version"`
+	Schema_Version int    `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Sealed / Sealed_Field / Wrapped_Key - The encrypted fields of a member and its data key wrapped for each organisation.
+//										   A field is named by its JSON name, its ciphertext is the base64 nonce followed
+//										   by the sealed JSON value of the field. The wrapped keys are kept apart, in
+//										   DATA_KEY_COLLECTION, see Data_Key.
+//==============================================================================================================================
+type Sealed struct {
+	Fields []Sealed_Field `json:"fields"`
+}
+
+//==============================================================================================================================
+//	 Data_Key - The data key of a member wrapped for each organisation, stored in DATA_KEY_COLLECTION.
+//==============================================================================================================================
+type Data_Key struct {
+	ILNSID         string        `json:"ILNSID"`
+	Keys           []Wrapped_Key `json:"keys"`
+	Schema_Version int           `json:"schemaVersion"`
+}
+
+type Sealed_Field struct {
+	Field      string `json:"field"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+type Wrapped_Key struct {
+	Org        string `json:"org"`
+	Version    int    `json:"version"`
+	Public_Key string `json:"publicKey"`
+	Ephemeral  string `json:"ephemeral"`
+	Key        string `json:"key"`
+}
+
+func (s *Sealed) field(name string) (string, bool) {
+
+	for _, f := range s.Fields {
+		if f.Field == name {
+			return f.Ciphertext, true
+		}
+	}
+
+	return "", false
+}
+
+//==============================================================================================================================
+//	 set_field - Sets the ciphertext of a field, or removes the field if ciphertext is empty. Fields are kept in
+//				 SENSITIVE_FIELDS order.
+//==============================================================================================================================
+func (s *Sealed) set_field(name string, ciphertext string) {
+
+	fields := []Sealed_Field{}
+
+	for _, field := range SENSITIVE_FIELDS {
+
+		value, ok := s.field(field)
+
+		if field == name {
+			value, ok = ciphertext, ciphertext != ""
+		}
+
+		if ok {
+			fields = append(fields, Sealed_Field{field, value})
+		}
+	}
+
+	s.Fields = fields
+}
+
+//==============================================================================================================================
+//	 derive - HMAC-SHA256 of the label and parts, each terminated by a zero byte so that parts can not run together.
+//==============================================================================================================================
+func derive(key []byte, label string, parts ...string) []byte {
+
+	mac := hmac.New(sha256.New, key)
+
+	for _, part := range append([]string{label}, parts...) {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+
+	return mac.Sum(nil)
+}
+
+func seal(key []byte, nonce []byte, plaintext []byte, additional string) ([]byte, error) {
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nil, nonce[:gcm.NonceSize()], plaintext, []byte(additional)), nil
+}
+
+func unseal(key []byte, nonce []byte, ciphertext []byte, additional string) ([]byte, error) {
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, nonce[:gcm.NonceSize()], ciphertext, []byte(additional))
+}
+
+//==============================================================================================================================
+//	 caller_data_key - The data key of a member the caller passed in the transient map, nil if none was passed.
+//==============================================================================================================================
+func caller_data_key(stub shim.ChaincodeStubInterface, ILNSID string) ([]byte, error) {
+
+	transient, err := stub.GetTransient()
+
+	if err != nil {
+		return nil, internal("Unable to read the transient map")
+	}
+
+	encoded, ok := transient[TRANSIENT_DATA_KEYS]
+
+	if !ok {
+		return nil, nil
+	}
+
+	var keys map[string]string
+
+	if err = json.Unmarshal(encoded, &keys); err != nil {
+		return nil, invalid(TRANSIENT_DATA_KEYS, "", "must be a JSON object of base64 data keys by ILNSID")
+	}
+
+	value, ok := keys[ILNSID]
+
+	if !ok {
+		return nil, nil
+	}
+
+	dk, err := base64.StdEncoding.DecodeString(value)
+
+	if err != nil || len(dk) != 32 {
+		return nil, invalid(TRANSIENT_DATA_KEYS, ILNSID, "must be a base64 256 bit data key")
+	}
+
+	return dk, nil
+}
+
+//==============================================================================================================================
+//	 caller_entropy - The random bytes the caller passed in the transient map, empty if none were passed.
+//==============================================================================================================================
+func caller_entropy(stub shim.ChaincodeStubInterface) (string, error) {
+
+	transient, err := stub.GetTransient()
+
+	if err != nil {
+		return "", internal("Unable to read the transient map")
+	}
+
+	return string(transient[TRANSIENT_ENTROPY]), nil
+}
+
+func encode_public_key(key *ecdh.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes())
+}
+
+//==============================================================================================================================
+//	 list_org_keys - Every registered organisation key, in organisation order.
+//==============================================================================================================================
+func list_org_keys(stub shim.ChaincodeStubInterface) ([]Org_Key, error) {
+
+	keys, err := list_keys(stub, ENTRY_ORG_KEY)
+
+	if err != nil {
+		return nil, err
+	}
+
+	org_keys := []Org_Key{}
+
+	for _, key := range keys {
+
+		var k Org_Key
+
+		if _, err := read_document(stub, DOC_ORG_KEY, key, &k); err != nil {
+			return nil, err
+		}
+
+		org_keys = append(org_keys, k)
+	}
+
+	return org_keys, nil
+}
+
+//=================================================================================================================================
+//	 set_org_key - Registers the public key data keys are wrapped for an organisation. Registering a new key for an
+//				   organisation raises its version, members stay wrapped for the old key until rotate_keys reaches them.
+//				   Only administrators may register keys, and only for their own organisation, caller_org.
+//=================================================================================================================================
+func set_org_key(stub shim.ChaincodeStubInterface, caller_affiliation string, caller_org string, org string, public_key string) (*Org_Key, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("set_org_key", ADMIN, caller_affiliation)
+	}
+
+	if org != caller_org {
+		return nil, permission_denied("set_org_key", map[string]interface{}{"org": org, "caller_org": caller_org})
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(public_key)
+
+	if err == nil {
+		_, err = ecdh.X25519().NewPublicKey(raw)
+	}
+
+	if err != nil {
+		return nil, invalid("public_key", public_key, "must be a base64 X25519 public key")
+	}
+
+	k := Org_Key{Org: org, Public_Key: public_key, Version: 1}
+
+	var existing Org_Key
+
+	found, err := read_document(stub, DOC_ORG_KEY, org_key_key(org), &existing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+
+		if existing.Public_Key == public_key {
+			return &existing, nil
+		}
+
+		k.Version = existing.Version + 1
+	}
+
+	k.Schema_Version = schema_version(DOC_ORG_KEY)
+
+	bytes, err := json.Marshal(k)
+
+	if err != nil {
+		return nil, internal("Error converting org key")
+	}
+
+	if err = stub.PutState(org_key_key(org), bytes); err != nil {
+		return nil, internal("Error storing org key")
+	}
+
+	return &k, nil
+}
+
+//==============================================================================================================================
+//	 wrap_key / unwrap_key - Wraps a data key for an organisation's public key with an ephemeral key derived from the data
+//							 key and transaction, and unwraps it again with the organisation's private key.
+//==============================================================================================================================
+func wrap_key(data_key []byte, tx_ID string, org Org_Key) (Wrapped_Key, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(org.Public_Key)
+
+	if err != nil {
+		return Wrapped_Key{}, internal("Corrupt key of " + org.Org)
+	}
+
+	public, err := ecdh.X25519().NewPublicKey(raw)
+
+	if err != nil {
+		return Wrapped_Key{}, internal("Corrupt key of " + org.Org)
+	}
+
+	ephemeral, _ := ecdh.X25519().NewPrivateKey(derive(data_key, "ephemeral", tx_ID, org.Org, strconv.Itoa(org.Version)))
+
+	shared, err := ephemeral.ECDH(public)
+
+	if err != nil {
+		return Wrapped_Key{}, internal("Unable to wrap the data key for " + org.Org)
+	}
+
+	ephemeral_public := base64.StdEncoding.EncodeToString(ephemeral.PublicKey().Bytes())
+
+	wrapped, err := seal(derive(shared, "wrap", ephemeral_public, org.Public_Key), make([]byte, 12), data_key, org.Org)
+
+	if err != nil {
+		return Wrapped_Key{}, internal("Unable to wrap the data key for " + org.Org)
+	}
+
+	return Wrapped_Key{Org: org.Org, Version: org.Version, Public_Key: org.Public_Key, Ephemeral: ephemeral_public, Key: base64.StdEncoding.EncodeToString(wrapped)}, nil
+}
+
+func unwrap_key(w Wrapped_Key, key *ecdh.PrivateKey) ([]byte, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(w.Ephemeral)
+
+	if err != nil {
+		return nil, internal("Corrupt wrapped key of " + w.Org)
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(raw)
+
+	if err != nil {
+		return nil, internal("Corrupt wrapped key of " + w.Org)
+	}
+
+	shared, err := key.ECDH(ephemeral)
+
+	if err != nil {
+		return nil, internal("Unable to unwrap the data key of " + w.Org)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(w.Key)
+
+	if err != nil {
+		return nil, internal("Corrupt wrapped key of " + w.Org)
+	}
+
+	data_key, err := unseal(derive(shared, "wrap", w.Ephemeral, w.Public_Key), make([]byte, 12), wrapped, w.Org)
+
+	if err != nil {
+		return nil, internal("Unable to unwrap the data key of " + w.Org)
+	}
+
+	return data_key, nil
+}
+
+//==============================================================================================================================
+//	 envelope - The wrapped data keys of a sealed member, from DATA_KEY_COLLECTION. Empty once the member has been erased.
+//==============================================================================================================================
+func envelope(stub shim.ChaincodeStubInterface, m Member) ([]Wrapped_Key, error) {
+
+	var dk Data_Key
+
+	value, err := stub.GetPrivateData(DATA_KEY_COLLECTION, data_key_key(m.ILNSID))
+
+	if err != nil {
+		return nil, internal("Unable to read the data key of member " + m.ILNSID)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	if err = json.Unmarshal(value, &dk); err != nil {
+		return nil, internal("Corrupt data key of member " + m.ILNSID)
+	}
+
+	return dk.Keys, nil
+}
+
+//==============================================================================================================================
+//	 save_envelope - Stores the wrapped data keys of m in DATA_KEY_COLLECTION.
+//==============================================================================================================================
+func save_envelope(stub shim.ChaincodeStubInterface, m *Member, keys []Wrapped_Key) error {
+
+	bytes, err := json.Marshal(Data_Key{ILNSID: m.ILNSID, Keys: keys, Schema_Version: schema_version(DOC_DATA_KEY)})
+
+	if err != nil {
+		return internal("Error converting the data key of member " + m.ILNSID)
+	}
+
+	if err = stub.PutPrivateData(DATA_KEY_COLLECTION, data_key_key(m.ILNSID), bytes); err != nil {
+		return internal("Error storing the data key of member " + m.ILNSID)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 UnwrapDataKey - The data key of a member unwrapped with the base64 X25519 private key of the caller's organisation,
+//					 for clients to pass back in TRANSIENT_DATA_KEYS. Fails if the member is not wrapped for the key.
+//==============================================================================================================================
+func UnwrapDataKey(dk Data_Key, private_key string) ([]byte, error) {
+
+	raw, err := base64.StdEncoding.DecodeString(private_key)
+
+	if err != nil {
+		return nil, invalid("key", "", "must be a base64 X25519 private key")
+	}
+
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+
+	if err != nil {
+		return nil, invalid("key", "", "must be a base64 X25519 private key")
+	}
+
+	public_key := encode_public_key(key)
+
+	for _, w := range dk.Keys {
+		if w.Public_Key == public_key {
+			return unwrap_key(w, key)
+		}
+	}
+
+	return nil, not_found("Member "+dk.ILNSID+" is not wrapped for the key", map[string]interface{}{"ILNSID": dk.ILNSID})
+}
+
+//==============================================================================================================================
+//	 get_data_keys - The wrapped data keys of the members named that the caller may read, and of their parents, whose DOBs
+//					 are checked when a member's DOB is written. With none named, those of every member the caller may
+//					 read, or of every member for administrators, who rotate them. Members that do not exist or that the
+//					 caller may not read are left out, the transaction they are fetched for reports them.
+//==============================================================================================================================
+func get_data_keys(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, ILNSIDs []string) ([]Data_Key, error) {
+
+	named := len(ILNSIDs) > 0
+
+	if !named {
+
+		holder, err := retrieve_ILNS_holder(stub)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ILNSIDs = holder.ILNSs
+	}
+
+	result := []Data_Key{}
+	seen := map[string]bool{}
+
+	add := func(m Member) error {
+
+		if seen[m.ILNSID] || m.Sealed == nil {
+			return nil
+		}
+
+		seen[m.ILNSID] = true
+
+		keys, err := envelope(stub, m)
+
+		if err != nil || len(keys) == 0 {
+			return err
+		}
+
+		result = append(result, Data_Key{ILNSID: m.ILNSID, Keys: keys, Schema_Version: schema_version(DOC_DATA_KEY)})
+
+		return nil
+	}
+
+	for _, ILNSID := range ILNSIDs {
+
+		m, err := read_member(stub, ILNSID)
+
+		if error_code(err) == NOT_FOUND {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if caller_affiliation != ADMIN && !can_view(stub, m, caller, caller_affiliation) {
+			continue
+		}
+
+		if err = add(m); err != nil {
+			return nil, err
+		}
+
+		if !named {
+			continue
+		}
+
+		for _, parent := range m.Parents {
+
+			p, err := read_member(stub, parent)
+
+			if error_code(err) == NOT_FOUND {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err = add(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+//==============================================================================================================================
+//	 Sensitive field values - A field is unset (and stored in the clear) when it has its initial value, and redacted when
+//							  it holds what a reader without the key is shown.
+//==============================================================================================================================
+func sensitive_value(m *Member, field string) interface{} {
+
+	switch field {
+	case "DOB":
+		return &m.DOB
+	case "diagnoses":
+		return &m.Diagnoses
+	case "notes":
+		return &m.Notes
+	}
+
+	return nil
+}
+
+func is_unset(m Member, field string) bool {
+
+	switch field {
+	case "DOB":
+		return m.DOB == UNDEFINED
+	case "diagnoses":
+		return len(m.Diagnoses) == 0
+	case "notes":
+		return m.Notes == ""
+	}
+
+	return true
+}
+
+func redact(m *Member, field string) {
+
+	switch field {
+	case "DOB":
+		m.DOB = ENCRYPTED
+	case "diagnoses":
+		m.Diagnoses = []string{ENCRYPTED}
+	case "notes":
+		m.Notes = ENCRYPTED
+	}
+}
+
+func is_redacted(m Member, field string) bool {
+
+	redacted := m
+
+	redact(&redacted, field)
+
+	return reflect.DeepEqual(member_field(m, field), member_field(redacted, field))
+}
+
+//==============================================================================================================================
+//	 unseal_field - The JSON value of a sealed field of m decrypted with a data key.
+//==============================================================================================================================
+func unseal_field(m Member, dk []byte, field string) ([]byte, error) {
+
+	encoded, _ := m.Sealed.field(field)
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+
+	if err != nil || len(raw) < 12 {
+		return nil, internal("Corrupt " + field + " of member " + m.ILNSID)
+	}
+
+	return unseal(dk, raw[:12], raw[12:], m.ILNSID+"/"+field)
+}
+
+//==============================================================================================================================
+//	 opens - Whether dk is the data key m is sealed under, tried on the first sealed field of m.
+//==============================================================================================================================
+func opens(m Member, dk []byte) bool {
+
+	if m.Sealed == nil || len(m.Sealed.Fields) == 0 {
+		return false
+	}
+
+	_, err := unseal_field(m, dk, m.Sealed.Fields[0].Field)
+
+	return err == nil
+}
+
+//==============================================================================================================================
+//	 open_member - Decrypts the sealed fields of m in place if the caller passed the data key m is sealed under. Otherwise
+//				   the fields stay redacted.
+//==============================================================================================================================
+func open_member(stub shim.ChaincodeStubInterface, m *Member) error {
+
+	if m.Sealed == nil {
+		return nil
+	}
+
+	dk, err := caller_data_key(stub, m.ILNSID)
+
+	if err != nil || dk == nil || !opens(*m, dk) {
+		return err
+	}
+
+	for _, field := range SENSITIVE_FIELDS {
+
+		if _, ok := m.Sealed.field(field); !ok {
+			continue
+		}
+
+		plaintext, err := unseal_field(*m, dk, field)
+
+		if err != nil || json.Unmarshal(plaintext, sensitive_value(m, field)) != nil {
+			return internal("Unable to decrypt " + field + " of member " + m.ILNSID)
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 seal_member - Encrypts the sensitive fields of m that are set and not already sealed, and replaces them with
+//				   ENCRYPTED, before m is stored. While other fields stay sealed the caller must pass the data key they
+//				   are sealed under. Otherwise, and when rotate is set, a new data key is made. The data key is wrapped
+//				   again whenever it is new or the registered organisation keys differ from those it was wrapped for.
+//				   Does nothing until an organisation key has been registered. Members whose sensitive fields are all
+//				   unset are not sealed.
+//==============================================================================================================================
+func seal_member(stub shim.ChaincodeStubInterface, m *Member, rotate bool) error {
+
+	if m.Erased || m.Redacted {
+		return nil
+	}
+
+	org_keys, err := list_org_keys(stub)
+
+	if err != nil {
+		return err
+	}
+
+	if len(org_keys) == 0 && m.Sealed == nil {
+		return nil
+	}
+
+	if m.Sealed == nil {
+		m.Sealed = &Sealed{Fields: []Sealed_Field{}}
+	}
+
+	plain := []string{}
+
+	for _, field := range SENSITIVE_FIELDS {
+
+		_, sealed := m.Sealed.field(field)
+
+		if is_unset(*m, field) {
+			m.Sealed.set_field(field, "")
+		} else if !sealed || !is_redacted(*m, field) {
+			plain = append(plain, field)
+		} else if rotate {
+			return internal("Member " + m.ILNSID + " must be opened before its data key is rotated")
+		}
+	}
+
+	if len(m.Sealed.Fields) == 0 && len(plain) == 0 {
+		m.Sealed = nil
+		return nil
+	}
+
+	if len(plain) == 0 && !rotate {
+		return nil
+	}
+
+	key, err := caller_data_key(stub, m.ILNSID)
+
+	if err != nil {
+		return err
+	}
+
+	kept := 0 // Fields that stay sealed under the current data key
+
+	for _, f := range m.Sealed.Fields {
+
+		resealed := false
+
+		for _, field := range plain {
+			resealed = resealed || field == f.Field
+		}
+
+		if !resealed {
+			kept++
+		}
+	}
+
+	minted := rotate || kept == 0
+
+	if key != nil && !rotate && len(m.Sealed.Fields) > 0 {
+
+		if !opens(*m, key) {
+			return permission_denied("The data key passed in "+TRANSIENT_DATA_KEYS+" does not open member "+m.ILNSID, map[string]interface{}{"ILNSID": m.ILNSID})
+		}
+
+		minted = false
+	}
+
+	if key == nil && !rotate {
+
+		changed, err := changed_fields_since_stored(stub, *m, plain)
+
+		if err != nil {
+			return err
+		}
+
+		if len(changed) == 0 { // Fields stored in the clear before encryption was enabled stay so until rotate_keys
+
+			if len(m.Sealed.Fields) == 0 {
+				m.Sealed = nil
+			}
+
+			return nil
+		}
+
+		if !minted {
+			return permission_denied("The data key of member "+m.ILNSID+" is needed to write "+fmt.Sprint(changed), map[string]interface{}{"ILNSID": m.ILNSID, "fields": changed, "transient": TRANSIENT_DATA_KEYS})
+		}
+	}
+
+	keys, err := envelope(stub, *m)
+
+	if err != nil {
+		return err
+	}
+
+	wrapped_for := map[string]int{}
+
+	for _, w := range keys {
+		wrapped_for[w.Org] = w.Version
+	}
+
+	current := len(wrapped_for) == len(org_keys)
+
+	for _, k := range org_keys {
+		current = current && wrapped_for[k.Org] == k.Version
+	}
+
+	tx_ID := stub.GetTxID()
+
+	dk := key
+
+	if minted {
+
+		entropy, err := caller_entropy(stub)
+
+		if err != nil {
+			return err
+		}
+
+		if len(entropy) < MIN_ENTROPY {
+			return invalid(TRANSIENT_ENTROPY, "", fmt.Sprintf("at least %d random bytes must be passed to make a new data key", MIN_ENTROPY))
+		}
+
+		dk = derive([]byte(entropy), "data key", m.ILNSID, tx_ID)
+	}
+
+	for _, field := range plain {
+
+		plaintext, err := json.Marshal(sensitive_value(m, field))
+
+		if err != nil {
+			return internal("Error converting " + field)
+		}
+
+		nonce := derive(dk, "nonce", m.ILNSID, field, tx_ID, string(plaintext))[:12]
+
+		ciphertext, err := seal(dk, nonce, plaintext, m.ILNSID+"/"+field)
+
+		if err != nil {
+			return internal("Unable to encrypt " + field)
+		}
+
+		m.Sealed.set_field(field, base64.StdEncoding.EncodeToString(append(nonce, ciphertext...)))
+
+		redact(m, field)
+	}
+
+	if !current || minted || len(keys) == 0 {
+
+		keys = []Wrapped_Key{}
+
+		for _, k := range org_keys {
+
+			w, err := wrap_key(dk, tx_ID, k)
+
+			if err != nil {
+				return err
+			}
+
+			keys = append(keys, w)
+		}
+
+		return save_envelope(stub, m, keys)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 changed_fields_since_stored - The fields listed whose value differs from the one stored for m, which is read without
+//								   being opened. Every field of a member not yet stored has changed.
+//==============================================================================================================================
+func changed_fields_since_stored(stub shim.ChaincodeStubInterface, m Member, fields []string) ([]string, error) {
+
+	stored, err := read_member(stub, m.ILNSID)
+
+	if err != nil && error_code(err) != NOT_FOUND {
+		return nil, err
+	}
+
+	changed := []string{}
+
+	for _, field := range fields {
+		if !reflect.DeepEqual(member_field(stored, field), member_field(m, field)) {
+			changed = append(changed, field)
+		}
+	}
+
+	return changed, nil
+}
+
+//==============================================================================================================================
+//	 Rotation_Result - Returned by rotate_keys. Next is the bookmark of the following batch and is empty once every member
+//					   has been checked. Unopened lists members that are sealed and whose data key the caller did not
+//					   pass, or whose clinical details the caller's organisation can not read.
+//==============================================================================================================================
+type Rotation_Result struct {
+	Checked  int      `json:"checked"`
+	Rotated  int      `json:"rotated"`
+	Unopened []string `json:"unopened"`
+	Next     string   `json:"next"`
+}
+
+//=================================================================================================================================
+//	 rotate_keys - Re-encrypts members in batches of the ILNSIDs index, starting at the bookmark: each member with a
+//				   sensitive field set is given a new data key, its fields are encrypted again under it and it is wrapped
+//				   for the organisation keys registered now. Members written before encryption was enabled are encrypted
+//				   for the first time. The caller passes the data key of every sealed member in the transient map. Only
+//				   administrators may rotate keys.
+//=================================================================================================================================
+func rotate_keys(stub shim.ChaincodeStubInterface, caller_affiliation string, bookmark string, batch_size string) (*Rotation_Result, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("rotate_keys", ADMIN, caller_affiliation)
+	}
+
+	start := 0
+
+	if bookmark != "" {
+		n, err := strconv.Atoi(bookmark)
+		if err != nil || n < 0 {
+			return nil, invalid("bookmark", bookmark, "must be the next value of a previous batch")
+		}
+		start = n
+	}
+
+	size := DEFAULT_ROTATION_BATCH
+
+	if batch_size != "" {
+		n, err := strconv.Atoi(batch_size)
+		if err != nil || n < 1 || n > MAX_ROTATION_BATCH {
+			return nil, invalid("batch_size", batch_size, fmt.Sprintf("must be between 1 and %d", MAX_ROTATION_BATCH))
+		}
+		size = n
+	}
+
+	ILNSIDs, err := retrieve_ILNS_holder(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := Rotation_Result{Unopened: []string{}}
+
+	pos := start
+
+	for ; pos < len(ILNSIDs.ILNSs) && result.Checked < size; pos++ {
+
+		m, err := retrieve_ILNS(stub, ILNSIDs.ILNSs[pos])
+
+		if error_code(err) == NOT_FOUND {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.Checked++
+
+		before, _ := json.Marshal(m)
+
+		if m.Erased {
+			continue
+		}
+
+		if m.Redacted { // The caller's organisation does not treat the member
+			result.Unopened = append(result.Unopened, m.ILNSID)
+			continue
+		}
+
+		if m.Sealed != nil && len(m.Sealed.Fields) > 0 {
+
+			dk, err := caller_data_key(stub, m.ILNSID)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if dk == nil || !opens(m, dk) {
+				result.Unopened = append(result.Unopened, m.ILNSID)
+				continue
+			}
+		}
+
+		if err = seal_member(stub, &m, true); err != nil {
+			return nil, err
+		}
+
+		if after, _ := json.Marshal(m); bytes.Equal(before, after) {
+			continue
+		}
+
+		if err = save_changes(stub, m); err != nil {
+			return nil, err
+		}
+
+		result.Rotated++
+	}
+
+	if pos < len(ILNSIDs.ILNSs) {
+		result.Next = strconv.Itoa(pos)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,141 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Erasure - A member is erased on a right-to-erasure request by destroying its data key, purging its clinical details
+//			   and replacing the record with a tombstone. Copies of its encrypted fields left in exports or on peers
+//			   stay unreadable, as the data key was derived from entropy only the client held (see seal_member).
+//			   The tombstone keeps the ILNSID and the member's lifecycle (status and dead) for statistics and marks
+//			   every other field ERASED. The member's vitals, immunizations, consents, attached documents and
+//			   credential anchors are deleted, so its credentials no longer verify, and no transaction may change it
+//			   again. The files of its documents are in external storage and must be deleted there.
+//==============================================================================================================================
+const ERASED = "ERASED"
+
+//==============================================================================================================================
+//	 strip - m with everything but its ILNSID and lifecycle removed. Used for the tombstone and for the history of an
+//			 erased member.
+//==============================================================================================================================
+func strip(m Member) Member {
+
+	return Member{
+		Name:           ERASED,
+		DOB:            ERASED,
+		Gender:         ERASED,
+		BloodGrp:       ERASED,
+		Weight:         Weight{Unit: "kg"},
+		Status:         m.Status,
+		Dead:           m.Dead,
+		ILNSID:         m.ILNSID,
+		Parents:        []string{},
+		Erased:         m.Erased,
+		Schema_Version: m.Schema_Version,
+	}
+}
+
+//=================================================================================================================================
+//	 erase_member - Purges the member's data key from DATA_KEY_COLLECTION and its details, vitals and immunizations from
+//					its clinical collections and DETAILS_COLLECTION, so no peer keeps them, deletes its consents,
+//					attached documents and credential anchors and replaces it with its tombstone. Vitals and
+//					immunizations still on the world state, not written since they moved to the collections, are
+//					deleted but stay in the public history. Only administrators may erase members.
+//=================================================================================================================================
+func erase_member(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+
+	if caller_affiliation != ADMIN {
+		return role_required("erase_member", ADMIN, caller_affiliation)
+	}
+
+	if err := stub.PurgePrivateData(DATA_KEY_COLLECTION, data_key_key(m.ILNSID)); err != nil {
+		return internal("Unable to purge the data key of member " + m.ILNSID)
+	}
+
+	collections := clinical_collections(*m)
+
+	if len(m.Treating_Orgs) > 0 {
+		collections = append(collections, DETAILS_COLLECTION) // Held before its treating organisations were recorded
+	}
+
+	for _, collection := range collections {
+		for _, key := range []string{details_key(m.ILNSID), vitals_key(m.ILNSID), immunizations_key(m.ILNSID)} {
+			if err := stub.PurgePrivateData(collection, key); err != nil {
+				return internal("Unable to purge " + key + " from " + collection)
+			}
+		}
+	}
+
+	for _, key := range []string{vitals_key(m.ILNSID), immunizations_key(m.ILNSID)} { // Not yet moved to the collection
+
+		public, err := stub.GetState(key)
+
+		if err != nil {
+			return internal("Error retrieving " + key)
+		}
+
+		if public != nil {
+			if err = stub.DelState(key); err != nil {
+				return internal("Unable to delete " + key)
+			}
+		}
+	}
+
+	consents, err := list_keys(stub, ENTRY_CONSENT, m.ILNSID)
+
+	if err != nil {
+		return err
+	}
+
+	attachments, err := list_keys(stub, ENTRY_ATTACHMENT, m.ILNSID)
+
+	if err != nil {
+		return err
+	}
+
+	credentials, err := list_keys(stub, ENTRY_CREDENTIAL, m.ILNSID)
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range append(append(consents, attachments...), credentials...) {
+		if err = stub.DelState(key); err != nil {
+			return internal("Unable to delete " + key)
+		}
+	}
+
+	var imported Member_History
+
+	found, err := read_document(stub, DOC_HISTORY, history_key(m.ILNSID), &imported)
+
+	if err != nil {
+		return err
+	}
+
+	if found {
+
+		for i := range imported.Revisions {
+			if imported.Revisions[i].Member != nil {
+				stripped := strip(*imported.Revisions[i].Member)
+				imported.Revisions[i].Member = &stripped
+			}
+		}
+
+		if err = save_member_history(stub, imported); err != nil {
+			return err
+		}
+	}
+
+	*m = strip(*m)
+	m.Erased = true
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		return save_failed(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package chaincode_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+)
+
+// ORG_KEY is the private key of Org1MSP in erasure.json.
+const ORG_KEY = "ieDBP6ZS9S2R/JDVaLcAcNbtGlnF2fRS37GyoZmxko4="
+
+// Once member:EraseMember has purged a data key, a copy of the encrypted fields kept from before the erasure can not be
+// opened with a key derived again from anything left: the organisation key, the member and the transaction. The steps
+// are those of erasure.json.
+func TestPurgedDataKeysCanNotBeDerivedAgain(t *testing.T) {
+
+	r := replay_scenario(t, "erasure.json")
+	r.until("the details are sealed under a data key wrapped for the organisation")
+
+	tx_ID := r.h.Stub.GetTxID()
+
+	var envelope chaincode.Data_Key
+
+	if err := json.Unmarshal(r.h.Stub.Private[chaincode.DATA_KEY_COLLECTION]["data_key:AB12345679"], &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	dk, err := chaincode.UnwrapDataKey(envelope, ORG_KEY)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied := chaincode.Member{ILNSID: "AB12345679"}
+
+	if err = json.Unmarshal(r.h.Stub.Private["_implicit_org_Org1MSP"]["details:AB12345679"], &copied); err != nil || copied.Sealed == nil {
+		t.Fatalf("no sealed fields: %v", err)
+	}
+
+	if !chaincode.Opens(copied, dk) {
+		t.Fatal("the unwrapped data key does not open the member")
+	}
+
+	r.until("an administrator erases the member")
+
+	org_key, _ := base64.StdEncoding.DecodeString(ORG_KEY)
+
+	for name, candidate := range map[string][]byte{
+		"without entropy":           chaincode.Derive(nil, "data key", "AB12345679", tx_ID),
+		"from the organisation key": chaincode.Derive(org_key, "data key", "AB12345679", tx_ID),
+	} {
+		if chaincode.Opens(copied, candidate) {
+			t.Errorf("a data key derived %s opens the erased member", name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package chaincode
+
+import (
+	"encoding/json"
+)
+
+//==============================================================================================================================
+//	 Error codes - Every error a transaction returns is a Chaincode_Error rendered as JSON, with one of these codes.
+//				   Clients should act on the code, the message is for people.
+//==============================================================================================================================
+const PERMISSION_DENIED = "PERMISSION_DENIED" // The caller may not do this, or may not do it to the recipient given
+const INVALID_STATE = "INVALID_STATE"         // The member is not in a status, or is dead, so the transaction does not apply
+const NOT_FOUND = "NOT_FOUND"                 // A member or document the transaction needs does not exist
+const VALIDATION_FAILED = "VALIDATION_FAILED" // An argument or a value in one was rejected
+const CONFLICT = "CONFLICT"                   // The transaction would overwrite something that already exists
+const INTERNAL = "INTERNAL"                   // The ledger could not be read or written, or a stored document is corrupt
+
+//==============================================================================================================================
+//	 Chaincode_Error - A failed transaction. Details holds the fields a client needs to react, e.g. expected_status and
+//					   actual_status for INVALID_STATE or required_role for PERMISSION_DENIED.
+//
+//	{"code":"INVALID_STATE","message":"Invalid state. healthy_to_death","details":{"ILNSID":"AB12345679","expected_status":2,"actual_status":3,"dead":false}}
+//==============================================================================================================================
+type Chaincode_Error struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Chaincode_Error) Error() string {
+
+	bytes, err := json.Marshal(e)
+
+	if err != nil {
+		return e.Code + ": " + e.Message
+	}
+
+	return string(bytes)
+}
+
+//==============================================================================================================================
+//	 permission_denied - The caller may not run function. Details usually holds required_role and caller_role.
+//==============================================================================================================================
+func permission_denied(function string, details map[string]interface{}) error {
+	return &Chaincode_Error{Code: PERMISSION_DENIED, Message: "Permission Denied. " + function, Details: details}
+}
+
+//==============================================================================================================================
+//	 role_required - Only callers holding required_role may run function.
+//==============================================================================================================================
+func role_required(function string, required_role string, caller_affiliation string) error {
+	return permission_denied(function, map[string]interface{}{"required_role": required_role, "caller_role": caller_affiliation})
+}
+
+//==============================================================================================================================
+//	 view_denied - The caller may not read the member: they are not its custodian or a parent and hold no consent.
+//==============================================================================================================================
+func view_denied(function string, m Member) error {
+	return permission_denied(function, map[string]interface{}{"ILNSID": m.ILNSID})
+}
+
+//==============================================================================================================================
+//	 details_denied - The caller's organisation does not treat the member and can not read or write its clinical details.
+//==============================================================================================================================
+func details_denied(function string, m Member) error {
+	return permission_denied(function, map[string]interface{}{"ILNSID": m.ILNSID, "collections": clinical_collections(m)})
+}
+
+//==============================================================================================================================
+//	 role_denied - The caller must be the member's custodian and hold required_role.
+//==============================================================================================================================
+func role_denied(function string, m Member, caller string, caller_affiliation string, required_role string) error {
+	return permission_denied(function, map[string]interface{}{
+		"ILNSID":        m.ILNSID,
+		"required_role": required_role,
+		"caller_role":   caller_affiliation,
+		"is_custodian":  m.Name == caller,
+	})
+}
+
+//==============================================================================================================================
+//	 custodian_denied - The caller must be the member's custodian and may not hold the death role.
+//==============================================================================================================================
+func custodian_denied(function string, m Member, caller string, caller_affiliation string) error {
+	return permission_denied(function, map[string]interface{}{
+		"ILNSID":       m.ILNSID,
+		"denied_role":  DEATH,
+		"caller_role":  caller_affiliation,
+		"is_custodian": m.Name == caller,
+	})
+}
+
+//==============================================================================================================================
+//	 invalid_state - function applies to members in the expected status, m is in another or is dead. An expected status
+//					 of -1 means any living member.
+//==============================================================================================================================
+func invalid_state(function string, m Member, expected int) error {
+
+	details := map[string]interface{}{"ILNSID": m.ILNSID, "actual_status": m.Status, "dead": m.Dead}
+
+	if expected >= 0 {
+		details["expected_status"] = expected
+	}
+
+	return &Chaincode_Error{Code: INVALID_STATE, Message: "Invalid state. " + function, Details: details}
+}
+
+//==============================================================================================================================
+//	 erased_state - The member has been erased and can no longer be changed.
+//==============================================================================================================================
+func erased_state(m Member) error {
+	return &Chaincode_Error{Code: INVALID_STATE, Message: "Invalid state. Member " + m.ILNSID + " has been erased", Details: map[string]interface{}{"ILNSID": m.ILNSID, "actual_status": m.Status, "dead": m.Dead, "erased": true}}
+}
+
+//==============================================================================================================================
+//	 not_found - Something the transaction needs does not exist.
+//==============================================================================================================================
+func not_found(message string, details map[string]interface{}) error {
+	return &Chaincode_Error{Code: NOT_FOUND, Message: message, Details: details}
+}
+
+//==============================================================================================================================
+//	 conflict - The transaction would overwrite something that exists.
+//==============================================================================================================================
+func conflict(message string, details map[string]interface{}) error {
+	return &Chaincode_Error{Code: CONFLICT, Message: message, Details: details}
+}
+
+//==============================================================================================================================
+//	 internal - A failure of the ledger or of stored data rather than of the request.
+//==============================================================================================================================
+func internal(message string) error {
+	return &Chaincode_Error{Code: INTERNAL, Message: message}
+}
+
+//==============================================================================================================================
+//	 error_code - The code of an error, INTERNAL for errors that are not structured.
+//==============================================================================================================================
+func error_code(err error) string {
+
+	switch e := err.(type) {
+	case *Chaincode_Error:
+		return e.Code
+	case *Validation_Error:
+		return VALIDATION_FAILED
+	case *Patch_Error:
+		return e.Code()
+	}
+
+	return INTERNAL
+}
+
+//==============================================================================================================================
+//	 error_message - The human readable message of an error, without the JSON around it. Used where an error is reported
+//					 inside another document, e.g. a row of an import report.
+//==============================================================================================================================
+func error_message(err error) string {
+
+	switch e := err.(type) {
+	case *Chaincode_Error:
+		return e.Message
+	case *Validation_Error:
+		return e.Field + ": " + e.Reason
+	}
+
+	return err.Error()
+}
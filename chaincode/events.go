@@ -0,0 +1,161 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Event names - A transaction emits at most one chaincode event, named after what happened to the member so that
+//				   listeners can subscribe to one kind. Updates emit theirs even when no field changes.
+//==============================================================================================================================
+const EVENT_CREATED = "MemberCreated"           // CreateMember
+const EVENT_TRANSITIONED = "MemberTransitioned" // Every lifecycle transfer, including to the death status
+const EVENT_UPDATED = "MemberUpdated"           // UpdateDOB, UpdateGender, UpdateBloodGrp, UpdateWeight, UpdateMember, RecordObservation
+const EVENT_DIED = "MemberDied"                 // DeadMember
+const EVENT_IMPORTED = "MembersImported"        // ImportMembers, one event for every member created
+const EVENT_ERASED = "MemberErased"             // EraseMember
+
+//==============================================================================================================================
+//	 Member_Event - The payload of every member event. From_Status and Old_Custodian are those before the transaction,
+//					-1 and "" for a new member. Changed lists the member fields written, as in Invoke_Result. Org is the
+//					MSP ID of the client that submitted the transaction.
+//
+//	{"event":"MemberTransitioned","ILNSID":"AB12345679","fromStatus":0,"toStatus":1,"oldCustodian":"alice",
+//	 "newCustodian":"bob","changed":["name","status"],"org":"Org1MSP","timestamp":"2024-06-01T09:00:00Z","txID":"3f9c..."}
+//==============================================================================================================================
+type Member_Event struct {
+	Event         string   `json:"event"`
+	ILNSID        string   `json:"ILNSID"`
+	From_Status   int      `json:"fromStatus"`
+	To_Status     int      `json:"toStatus"`
+	Old_Custodian string   `json:"oldCustodian"`
+	New_Custodian string   `json:"newCustodian"`
+	Changed       []string `json:"changed"`
+	Org           string   `json:"org"`
+	Timestamp     string   `json:"timestamp"`
+	Tx_ID         string   `json:"txID"`
+}
+
+//==============================================================================================================================
+//	 Import_Event - The payload of MembersImported. A transaction has a single event so the members are listed together.
+//==============================================================================================================================
+type Import_Event struct {
+	Event     string   `json:"event"`
+	ILNSIDs   []string `json:"ILNSIDs"`
+	Org       string   `json:"org"`
+	Timestamp string   `json:"timestamp"`
+	Tx_ID     string   `json:"txID"`
+}
+
+//==============================================================================================================================
+//	 set_event - Marshals payload and sets it as the event of the transaction.
+//==============================================================================================================================
+func set_event(stub shim.ChaincodeStubInterface, name string, payload interface{}) error {
+
+	bytes, err := json.Marshal(payload)
+
+	if err != nil {
+		return internal("Error creating event " + name)
+	}
+
+	err = stub.SetEvent(name, bytes)
+
+	if err != nil {
+		fmt.Printf("SET_EVENT: Error setting event %s: %s", name, err)
+		return internal("Error setting event " + name)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 emit_member_event - Emits name for a member that was before and is now after. before is nil for a new member.
+//						 The event is recorded in result.
+//==============================================================================================================================
+func emit_member_event(stub shim.ChaincodeStubInterface, org string, name string, before *Member, after Member, result *Invoke_Result) error {
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	event := Member_Event{
+		Event:         name,
+		ILNSID:        after.ILNSID,
+		From_Status:   -1,
+		To_Status:     after.Status,
+		New_Custodian: after.Name,
+		Changed:       result.Changed,
+		Org:           org,
+		Timestamp:     now.Format(time.RFC3339),
+		Tx_ID:         stub.GetTxID(),
+	}
+
+	if before != nil {
+		event.From_Status = before.Status
+		event.Old_Custodian = before.Name
+	}
+
+	if after.Erased { // The event must not name who the member was with
+		event.Old_Custodian = ""
+		event.New_Custodian = ""
+	}
+
+	err = set_event(stub, name, event)
+
+	if err != nil {
+		return err
+	}
+
+	result.Events = append(result.Events, name)
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 emit_import_event - Emits MembersImported for the members an import created.
+//==============================================================================================================================
+func emit_import_event(stub shim.ChaincodeStubInterface, org string, report *Import_Report) error {
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	event := Import_Event{Event: EVENT_IMPORTED, ILNSIDs: []string{}, Org: org, Timestamp: now.Format(time.RFC3339), Tx_ID: stub.GetTxID()}
+
+	for _, row := range report.Rows {
+		if row.Status == "created" {
+			event.ILNSIDs = append(event.ILNSIDs, row.ILNSID)
+		}
+	}
+
+	return set_event(stub, EVENT_IMPORTED, event)
+}
+
+//==============================================================================================================================
+//	 get_caller_org - The MSP ID of the client that submitted the transaction, empty if it can not be read.
+//==============================================================================================================================
+func get_caller_org(ctx contractapi.TransactionContextInterface) string {
+
+	identity := ctx.GetClientIdentity()
+
+	if identity == nil {
+		return ""
+	}
+
+	org, err := identity.GetMSPID()
+
+	if err != nil {
+		return ""
+	}
+
+	return org
+}
@@ -0,0 +1,857 @@
+package chaincode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Export format - An export is a series of JSON lines. Each page starts with a header line describing the format and
+//					 the page, followed by one line per ledger entry. Entries are always listed in the same order (by
+//					 type and then by key) so two exports of the same state are identical.
+//
+//					 A new channel starts the history of every key again, so each member's history is exported as a
+//					 history entry: the revisions get_member_history returns, imported ones included. They are imported
+//					 under history:<ILNSID> and returned ahead of the revisions made on the new channel. Private data
+//					 has no history, so earlier versions of clinical details are lost. Documents are upgraded to their
+//					 current schema version as they are exported, and again as they are imported if the export came
+//					 from an older chaincode.
+//
+//					 Clinical details and the wrapped data keys of sealed members are read from and written back to their
+//					 private data collections, so exports and imports must run on a peer of an organisation in both.
+//					 Data keys are exported wrapped as stored, only the organisations they are wrapped for can open
+//					 them. Files attached to members are exported as their anchored metadata, the files stay in
+//					 external storage.
+//
+//					 Version 2 exports entries under their namespaced keys. Entries of version 1 exports carry the keys
+//					 used before namespaces and are imported under the keys their type and value give them. Version 3
+//					 adds history and data key entries and pages by key: the bookmark is the key of the last entry examined.
+//==============================================================================================================================
+const EXPORT_FORMAT = "medhist-export"
+const EXPORT_VERSION = 3
+
+const DEFAULT_EXPORT_PAGE = 100
+const MAX_EXPORT_PAGE = 1000
+
+const ENTRY_INDEX = "index"
+const ENTRY_PARTICIPANT = "participant"
+const ENTRY_MEMBER = "member"
+const ENTRY_VITALS = "vitals"
+const ENTRY_GROWTH_REFERENCE = "growth_reference"
+const ENTRY_IMPORT_REPORT = "import_report"
+const ENTRY_CONSENT = "consent"
+const ENTRY_ID_SEQUENCE = "id_sequence"
+const ENTRY_ORG_KEY = "org_key"
+const ENTRY_HISTORY = "history"
+
+//==============================================================================================================================
+//	 EXPORT_ORDER - The order entry types are exported in. Entries of one type are exported in key order.
+//==============================================================================================================================
+var EXPORT_ORDER = []string{ENTRY_INDEX, ENTRY_PARTICIPANT, ENTRY_MEMBER, ENTRY_HISTORY, ENTRY_DETAILS, ENTRY_DATA_KEY,
+	ENTRY_VITALS,
+	ENTRY_IMMUNIZATIONS, ENTRY_CONSENT, ENTRY_ATTACHMENT, ENTRY_CREDENTIAL, ENTRY_GROWTH_REFERENCE, ENTRY_SCHEDULE,
+	ENTRY_IMPORT_REPORT, ENTRY_ID_SEQUENCE, ENTRY_ORG_KEY, ENTRY_ISSUER_KEY}
+
+//==============================================================================================================================
+//	 Export_Header - First line of every page. Bookmark is the bookmark the page was requested with, Next is the
+//					 bookmark of the following page, the key of the last entry examined, and is empty on the last page.
+//==============================================================================================================================
+type Export_Header struct {
+	Type     string `json:"type"`
+	Format   string `json:"format"`
+	Version  int    `json:"version"`
+	Exported string `json:"exported"`
+	Bookmark string `json:"bookmark"`
+	Next     string `json:"next"`
+	Count    int    `json:"count"`
+	Total    int    `json:"total"`
+}
+
+//==============================================================================================================================
+//	 Export_Entry - One ledger entry. Value is the stored JSON document, eCerts are not JSON and are exported as a JSON
+//					string.
+//==============================================================================================================================
+type Export_Entry struct {
+	Type  string          `json:"type"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+//==============================================================================================================================
+//	 Import_State_Result - Returned by import_state.
+//==============================================================================================================================
+type Import_State_Result struct {
+	Imported int `json:"imported"`
+	Merged   int `json:"merged"`
+	Skipped  int `json:"skipped"`
+}
+
+type export_key struct {
+	entry_type string
+	key        string
+}
+
+//==============================================================================================================================
+//	 export_keys - Lists every entry to export in export order, see export_before.
+//==============================================================================================================================
+func export_keys(stub shim.ChaincodeStubInterface) ([]export_key, error) {
+
+	keys := []export_key{{ENTRY_INDEX, index_key(INDEX_ILNSIDS)}, {ENTRY_INDEX, index_key(INDEX_PARTICIPANTS)}}
+
+	var participants Participant_Holder
+
+	_, err := read_document(stub, DOC_PARTICIPANT_HOLDER, index_key(INDEX_PARTICIPANTS), &participants)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := append([]string{}, participants.Names...)
+	sort.Strings(names)
+
+	for _, name := range names {
+		keys = append(keys, export_key{ENTRY_PARTICIPANT, participant_key(name)})
+	}
+
+	ILNSIDs, err := retrieve_ILNS_holder(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	members := append([]string{}, ILNSIDs.ILNSs...)
+	sort.Strings(members)
+
+	for _, ILNSID := range members {
+		keys = append(keys, export_key{ENTRY_MEMBER, member_key(ILNSID)})
+	}
+
+	for _, ILNSID := range members {
+		keys = append(keys, export_key{ENTRY_HISTORY, history_key(ILNSID)})
+	}
+
+	for _, ILNSID := range members {
+		keys = append(keys, export_key{ENTRY_DETAILS, details_key(ILNSID)})
+	}
+
+	for _, ILNSID := range members {
+		keys = append(keys, export_key{ENTRY_DATA_KEY, data_key_key(ILNSID)})
+	}
+
+	for _, ILNSID := range members {
+		keys = append(keys, export_key{ENTRY_VITALS, vitals_key(ILNSID)})
+	}
+
+	for _, ILNSID := range members {
+		keys = append(keys, export_key{ENTRY_IMMUNIZATIONS, immunizations_key(ILNSID)})
+	}
+
+	for _, ILNSID := range members {
+
+		consents, err := list_keys(stub, ENTRY_CONSENT, ILNSID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range consents {
+			keys = append(keys, export_key{ENTRY_CONSENT, key})
+		}
+	}
+
+	for _, ILNSID := range members {
+
+		attachments, err := list_keys(stub, ENTRY_ATTACHMENT, ILNSID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range attachments {
+			keys = append(keys, export_key{ENTRY_ATTACHMENT, key})
+		}
+
+		credentials, err := list_keys(stub, ENTRY_CREDENTIAL, ILNSID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range credentials {
+			keys = append(keys, export_key{ENTRY_CREDENTIAL, key})
+		}
+	}
+
+	for _, indicator := range GROWTH_INDICATORS {
+		for _, sex := range []string{"female", "male"} {
+			keys = append(keys, export_key{ENTRY_GROWTH_REFERENCE, growth_reference_key(indicator, sex)})
+		}
+	}
+
+	for _, entry_type := range []string{ENTRY_SCHEDULE, ENTRY_IMPORT_REPORT, ENTRY_ID_SEQUENCE, ENTRY_ORG_KEY, ENTRY_ISSUER_KEY} {
+
+		listed, err := list_keys(stub, entry_type)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range listed {
+			keys = append(keys, export_key{entry_type, key})
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool { return export_before(keys[i], keys[j]) })
+
+	return keys, nil
+}
+
+//==============================================================================================================================
+//	 export_before - Whether a is exported before b: by the position of its type in EXPORT_ORDER and then by key.
+//==============================================================================================================================
+func export_before(a export_key, b export_key) bool {
+
+	rank_a, rank_b := export_rank(a.entry_type), export_rank(b.entry_type)
+
+	if rank_a != rank_b {
+		return rank_a < rank_b
+	}
+
+	return a.key < b.key
+}
+
+func export_rank(entry_type string) int {
+
+	for i, t := range EXPORT_ORDER {
+		if t == entry_type {
+			return i
+		}
+	}
+
+	return len(EXPORT_ORDER)
+}
+
+//=================================================================================================================================
+//	 export_state - Query returning one page of the export as JSON lines. The bookmark is empty for the first page and
+//					the Next value of the previous page's header thereafter. Only administrators may export as the
+//					export includes every member regardless of who may see it.
+//=================================================================================================================================
+func export_state(stub shim.ChaincodeStubInterface, caller_affiliation string, bookmark string, page_size string) ([]byte, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("export_state", ADMIN, caller_affiliation)
+	}
+
+	var after *export_key
+
+	if bookmark != "" {
+		entry_type, _, ok := SplitKey(bookmark)
+		if !ok || export_rank(entry_type) == len(EXPORT_ORDER) {
+			return nil, invalid("bookmark", bookmark, "must be the next value of a previous page")
+		}
+		after = &export_key{entry_type, bookmark}
+	}
+
+	size := DEFAULT_EXPORT_PAGE
+
+	if page_size != "" {
+		n, err := strconv.Atoi(page_size)
+		if err != nil || n < 1 || n > MAX_EXPORT_PAGE {
+			return nil, invalid("page_size", page_size, fmt.Sprintf("must be between 1 and %d", MAX_EXPORT_PAGE))
+		}
+		size = n
+	}
+
+	keys, err := export_keys(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pos := 0
+
+	if after != nil {
+		pos = sort.Search(len(keys), func(i int) bool { return export_before(*after, keys[i]) })
+	}
+
+	var lines []Export_Entry
+	last := ""
+
+	for ; pos < len(keys) && len(lines) < size; pos++ {
+
+		k := keys[pos]
+		last = k.key
+
+		value, err := export_value(stub, k)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if value == nil {
+			continue
+		}
+
+		lines = append(lines, Export_Entry{Type: k.entry_type, Key: k.key, Value: json.RawMessage(value)})
+	}
+
+	header := Export_Header{Type: "header", Format: EXPORT_FORMAT, Version: EXPORT_VERSION, Bookmark: bookmark, Count: len(lines), Total: len(keys)}
+
+	if pos < len(keys) {
+		header.Next = last
+	}
+
+	if now, err := get_tx_time(stub); err == nil {
+		header.Exported = now.Format(time.RFC3339)
+	}
+
+	var out bytes.Buffer
+
+	encoder := json.NewEncoder(&out)
+
+	if err = encoder.Encode(header); err != nil {
+		return nil, internal("Error converting export header")
+	}
+
+	for _, line := range lines {
+		if err = encoder.Encode(line); err != nil {
+			return nil, internal("Error converting export entry " + line.Key)
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+//==============================================================================================================================
+//	 export_value - The value exported for k, nil if there is none. eCerts are exported as JSON strings, documents at
+//					their current schema version and histories as the revisions of the member.
+//==============================================================================================================================
+func export_value(stub shim.ChaincodeStubInterface, k export_key) ([]byte, error) {
+
+	if k.entry_type == ENTRY_HISTORY {
+		return export_history(stub, k.key)
+	}
+
+	value, err := read_entry(stub, k.entry_type, k.key)
+
+	if err != nil {
+		return nil, internal("Unable to read " + k.key)
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	if k.entry_type == ENTRY_PARTICIPANT {
+		return json.Marshal(string(value))
+	}
+
+	value, _, err = upgrade_document(entry_doc_type(k.entry_type, k.key), k.key, value)
+
+	return value, err
+}
+
+//==============================================================================================================================
+//	 export_history - The history entry at key: every revision of the member, without the imported marks, or nil if the
+//					  member has none.
+//==============================================================================================================================
+func export_history(stub shim.ChaincodeStubInterface, key string) ([]byte, error) {
+
+	_, attributes, _ := SplitKey(key)
+	ILNSID := attributes[0]
+
+	var m Member
+
+	if _, err := read_document(stub, DOC_MEMBER, member_key(ILNSID), &m); err != nil {
+		return nil, err
+	}
+
+	revisions, err := member_history(stub, ILNSID, m.Erased)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+
+	for i := range revisions {
+		revisions[i].Imported = false
+	}
+
+	bytes, err := json.Marshal(Member_History{ILNSID: ILNSID, Revisions: revisions, Schema_Version: schema_version(DOC_HISTORY)})
+
+	if err != nil {
+		return nil, internal("Error converting history record " + ILNSID)
+	}
+
+	return bytes, nil
+}
+
+//==============================================================================================================================
+//	 read_entry / write_entry - Entries are kept in the world state except clinical details, vitals and immunizations,
+//								which are kept in the clinical collections of their member, and data keys, kept in
+//								DATA_KEY_COLLECTION. Clinical entries are read from the collection of the caller's
+//								organisation, then from DETAILS_COLLECTION for members with no treating organisations,
+//								and vitals and immunizations written before they moved to the collections from the
+//								world state. They are written to the collections of the member, taken from members
+//								when it was imported with them, see entry_member.
+//==============================================================================================================================
+func read_entry(stub shim.ChaincodeStubInterface, entry_type string, key string) ([]byte, error) {
+
+	switch entry_type {
+	case ENTRY_DETAILS, ENTRY_VITALS, ENTRY_IMMUNIZATIONS:
+
+		collections := []string{DETAILS_COLLECTION}
+
+		if c, ok := stub.(*caller_stub); ok {
+			collections = append([]string{org_collection(c.org)}, collections...)
+		}
+
+		for _, collection := range collections {
+
+			value, err := stub.GetPrivateData(collection, key)
+
+			if err != nil || value != nil {
+				return value, err
+			}
+		}
+
+		if entry_type == ENTRY_DETAILS {
+			return nil, nil
+		}
+	case ENTRY_DATA_KEY:
+		return stub.GetPrivateData(DATA_KEY_COLLECTION, key)
+	}
+
+	return stub.GetState(key)
+}
+
+func write_entry(stub shim.ChaincodeStubInterface, entry_type string, key string, value []byte, members map[string]Member) error {
+
+	switch entry_type {
+	case ENTRY_DETAILS, ENTRY_VITALS, ENTRY_IMMUNIZATIONS:
+
+		m, err := entry_member(stub, key, members)
+
+		if err != nil {
+			return err
+		}
+
+		return put_clinical(stub, m, key, value)
+	case ENTRY_DATA_KEY:
+		return stub.PutPrivateData(DATA_KEY_COLLECTION, key, value)
+	}
+
+	return stub.PutState(key, value)
+}
+
+//==============================================================================================================================
+//	 entry_member - The member a clinical entry belongs to, as imported in members or else as stored.
+//==============================================================================================================================
+func entry_member(stub shim.ChaincodeStubInterface, key string, members map[string]Member) (Member, error) {
+
+	_, attributes, _ := SplitKey(key)
+
+	if len(attributes) == 0 {
+		return Member{}, nil
+	}
+
+	if m, ok := members[attributes[0]]; ok {
+		return m, nil
+	}
+
+	var m Member
+
+	_, err := read_document(stub, DOC_MEMBER, member_key(attributes[0]), &m)
+
+	return m, err
+}
+
+//==============================================================================================================================
+//	 entry_doc_type - The document type stored by an export entry.
+//==============================================================================================================================
+func entry_doc_type(entry_type string, key string) string {
+
+	switch entry_type {
+	case ENTRY_INDEX:
+		if index_name(key) == INDEX_PARTICIPANTS {
+			return DOC_PARTICIPANT_HOLDER
+		}
+		return DOC_ILNS_HOLDER
+	case ENTRY_VITALS:
+		return DOC_VITALS
+	case ENTRY_GROWTH_REFERENCE:
+		return DOC_GROWTH_REFERENCE
+	case ENTRY_IMPORT_REPORT:
+		return DOC_IMPORT_REPORT
+	case ENTRY_CONSENT:
+		return DOC_CONSENT
+	case ENTRY_ID_SEQUENCE:
+		return DOC_ID_SEQUENCE
+	case ENTRY_ORG_KEY:
+		return DOC_ORG_KEY
+	case ENTRY_DETAILS:
+		return DOC_DETAILS
+	case ENTRY_ATTACHMENT:
+		return DOC_ATTACHMENT
+	case ENTRY_CREDENTIAL:
+		return DOC_CREDENTIAL
+	case ENTRY_ISSUER_KEY:
+		return DOC_ISSUER_KEY
+	case ENTRY_IMMUNIZATIONS:
+		return DOC_IMMUNIZATIONS
+	case ENTRY_SCHEDULE:
+		return DOC_SCHEDULE
+	case ENTRY_HISTORY:
+		return DOC_HISTORY
+	case ENTRY_DATA_KEY:
+		return DOC_DATA_KEY
+	}
+
+	return DOC_MEMBER
+}
+
+//==============================================================================================================================
+//	 index_name - The name of the index at key, which is either namespaced or, in version 1 exports, the name itself.
+//==============================================================================================================================
+func index_name(key string) string {
+
+	if entry_type, attributes, ok := SplitKey(key); ok && entry_type == ENTRY_INDEX {
+		return attributes[0]
+	}
+
+	return key
+}
+
+//==============================================================================================================================
+//	 merge_index - Adds the entries of an imported index to the index already on the ledger, keeping existing order.
+//==============================================================================================================================
+func merge_index(existing []string, imported []string) ([]string, bool) {
+
+	seen := map[string]bool{}
+
+	for _, e := range existing {
+		seen[e] = true
+	}
+
+	changed := false
+
+	for _, e := range imported {
+		if !seen[e] {
+			existing = append(existing, e)
+			seen[e] = true
+			changed = true
+		}
+	}
+
+	return existing, changed
+}
+
+//=================================================================================================================================
+//	 import_state - Writes one or more pages of an export back to the ledger. Indexes are merged with the indexes already
+//					present, every other entry is written unless the key already holds the same value. A key holding a
+//					different value is a conflict and fails the import. Only administrators may import.
+//=================================================================================================================================
+func import_state(stub shim.ChaincodeStubInterface, caller_affiliation string, data string) (*Import_State_Result, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("import_state", ADMIN, caller_affiliation)
+	}
+
+	var result Import_State_Result
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line_no := 0
+	version := 0
+	members := map[string]Member{}
+
+	for scanner.Scan() {
+
+		line_no++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		var entry Export_Entry
+
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, invalid("line", strconv.Itoa(line_no), "is not a JSON export entry")
+		}
+
+		if entry.Type == "header" {
+
+			var header Export_Header
+
+			if err := json.Unmarshal([]byte(line), &header); err != nil || header.Format != EXPORT_FORMAT {
+				return nil, invalid("line", strconv.Itoa(line_no), "is not a "+EXPORT_FORMAT+" header")
+			}
+
+			if header.Version > EXPORT_VERSION {
+				return nil, invalid("version", strconv.Itoa(header.Version), fmt.Sprintf("exports newer than version %d cannot be imported", EXPORT_VERSION))
+			}
+
+			version = header.Version
+			continue
+		}
+
+		if version == 0 {
+			return nil, invalid("line", strconv.Itoa(line_no), "entries must follow a header line")
+		}
+
+		value, err := import_entry_value(entry)
+
+		if err == nil {
+			entry.Key, err = import_entry_key(entry, version, value)
+		}
+
+		if err != nil {
+			return nil, invalid("line", strconv.Itoa(line_no), error_message(err))
+		}
+
+		current, err := read_entry(stub, entry.Type, entry.Key)
+
+		if err != nil {
+			return nil, internal("Unable to read " + entry.Key)
+		}
+
+		if entry.Type == ENTRY_INDEX {
+
+			value, changed, err := merge_index_entry(index_name(entry.Key), current, value)
+
+			if err != nil {
+				return nil, invalid("line", strconv.Itoa(line_no), error_message(err))
+			}
+
+			if !changed {
+				result.Skipped++
+				continue
+			}
+
+			if err = stub.PutState(entry.Key, value); err != nil {
+				return nil, internal("Unable to put the state")
+			}
+
+			result.Merged++
+			continue
+		}
+
+		if current != nil {
+
+			if bytes.Equal(current, value) {
+				result.Skipped++
+				continue
+			}
+
+			return nil, conflict("Import conflict. "+entry.Key+" already holds a different value", map[string]interface{}{"key": entry.Key})
+		}
+
+		if err = write_entry(stub, entry.Type, entry.Key, value, members); err != nil {
+			return nil, internal("Unable to put the state")
+		}
+
+		if entry.Type == ENTRY_MEMBER {
+
+			var m Member
+
+			if err = json.Unmarshal(value, &m); err == nil {
+				members[m.ILNSID] = m
+			}
+		}
+
+		result.Imported++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, internal("Unable to read import: " + err.Error())
+	}
+
+	return &result, nil
+}
+
+//==============================================================================================================================
+//	 import_entry_value - Checks an entry has a known type and a value of the right shape, and returns the bytes to store.
+//==============================================================================================================================
+func import_entry_value(entry Export_Entry) ([]byte, error) {
+
+	var err error
+
+	value := []byte(entry.Value)
+
+	if entry.Type != ENTRY_PARTICIPANT && entry.Type != ENTRY_INDEX {
+		if value, _, err = upgrade_document(entry_doc_type(entry.Type, entry.Key), entry.Key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch entry.Type {
+	case ENTRY_PARTICIPANT:
+		var ecert string
+		if err = json.Unmarshal(entry.Value, &ecert); err == nil {
+			return []byte(ecert), nil
+		}
+	case ENTRY_MEMBER:
+		var m Member
+		err = json.Unmarshal(value, &m)
+	case ENTRY_VITALS:
+		var series Vitals_Series
+		err = json.Unmarshal(value, &series)
+	case ENTRY_GROWTH_REFERENCE:
+		var ref Growth_Reference
+		err = json.Unmarshal(value, &ref)
+	case ENTRY_IMPORT_REPORT:
+		var report Import_Report
+		err = json.Unmarshal(value, &report)
+	case ENTRY_CONSENT:
+		var c Consent
+		err = json.Unmarshal(value, &c)
+	case ENTRY_ID_SEQUENCE:
+		var seq ID_Sequence
+		err = json.Unmarshal(value, &seq)
+	case ENTRY_ORG_KEY:
+		var k Org_Key
+		err = json.Unmarshal(value, &k)
+	case ENTRY_DETAILS:
+		var d Member_Details
+		err = json.Unmarshal(value, &d)
+	case ENTRY_ATTACHMENT:
+		var a Attachment
+		err = json.Unmarshal(value, &a)
+	case ENTRY_CREDENTIAL:
+		var r Credential_Record
+		err = json.Unmarshal(value, &r)
+	case ENTRY_ISSUER_KEY:
+		var k Issuer_Key
+		err = json.Unmarshal(value, &k)
+	case ENTRY_IMMUNIZATIONS:
+		var history Immunization_History
+		err = json.Unmarshal(value, &history)
+	case ENTRY_SCHEDULE:
+		var s Vaccine_Schedule
+		err = json.Unmarshal(value, &s)
+	case ENTRY_HISTORY:
+		var h Member_History
+		err = json.Unmarshal(value, &h)
+	case ENTRY_DATA_KEY:
+		var dk Data_Key
+		err = json.Unmarshal(value, &dk)
+	case ENTRY_INDEX:
+		if name := index_name(entry.Key); name != INDEX_ILNSIDS && name != INDEX_PARTICIPANTS {
+			return nil, invalid("key", entry.Key, "unknown index")
+		}
+	default:
+		return nil, invalid("type", entry.Type, "unknown entry type")
+	}
+
+	if err != nil {
+		return nil, invalid("value", entry.Key, "invalid "+entry.Type+" value")
+	}
+
+	return value, nil
+}
+
+//==============================================================================================================================
+//	 import_entry_key - The key an entry is imported under. Documents go under the key their identifying fields give them,
+//						and in a version 2 export that must be the key they were exported under. Indexes and
+//						participants go under the namespaced key of their name.
+//==============================================================================================================================
+func import_entry_key(entry Export_Entry, version int, value []byte) (string, error) {
+
+	if entry.Type == ENTRY_INDEX || entry.Type == ENTRY_PARTICIPANT {
+
+		if version < 2 {
+			return Key(entry.Type, entry.Key), nil
+		}
+
+		if entry_type, _, ok := SplitKey(entry.Key); !ok || entry_type != entry.Type {
+			return "", invalid("key", entry.Key, "is not a "+entry.Type+" key")
+		}
+
+		return entry.Key, nil
+	}
+
+	key, err := document_key(entry.Type, entry.Key, value)
+
+	if err != nil {
+		return "", err
+	}
+
+	if version >= 2 && key != entry.Key {
+		return "", invalid("key", entry.Key, "does not match the "+entry.Type+" value, which belongs under "+key)
+	}
+
+	return key, nil
+}
+
+//==============================================================================================================================
+//	 merge_index_entry - Merges an imported ILNSIDs or Participants index into the current one.
+//==============================================================================================================================
+func merge_index_entry(name string, current []byte, imported []byte) ([]byte, bool, error) {
+
+	if name == INDEX_ILNSIDS {
+
+		var existing, incoming ILNS_Holder
+
+		if current != nil {
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return nil, false, internal("corrupt ILNS_Holder on ledger")
+			}
+		}
+
+		if err := json.Unmarshal(imported, &incoming); err != nil {
+			return nil, false, invalid("value", "ILNSIDs", "invalid ILNSIDs index")
+		}
+
+		merged, changed := merge_index(existing.ILNSs, incoming.ILNSs)
+		existing.ILNSs = merged
+		existing.Schema_Version = schema_version(DOC_ILNS_HOLDER)
+
+		value, err := json.Marshal(existing)
+
+		return value, changed, err
+	}
+
+	var existing, incoming Participant_Holder
+
+	if current != nil {
+		if err := json.Unmarshal(current, &existing); err != nil {
+			return nil, false, internal("corrupt Participant_Holder on ledger")
+		}
+	}
+
+	if err := json.Unmarshal(imported, &incoming); err != nil {
+		return nil, false, invalid("value", "Participants", "invalid Participants index")
+	}
+
+	merged, changed := merge_index(existing.Names, incoming.Names)
+	existing.Names = merged
+	existing.Schema_Version = schema_version(DOC_PARTICIPANT_HOLDER)
+
+	for name, org := range incoming.Orgs { // Organisations already recorded are kept
+		if existing.Orgs[name] == "" {
+			if existing.Orgs == nil {
+				existing.Orgs = map[string]string{}
+			}
+			existing.Orgs[name] = org
+			changed = true
+		}
+	}
+
+	value, err := json.Marshal(existing)
+
+	return value, changed, err
+}
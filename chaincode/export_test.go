@@ -0,0 +1,8 @@
+package chaincode
+
+// Derive and Opens give the external tests the key derivation and decryption of the chaincode.
+var Derive = derive
+
+func Opens(m Member, dk []byte) bool {
+	return opens(m, dk)
+}
@@ -0,0 +1,404 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Argument types - How an argument is passed. Arrays and objects are passed as their JSON text.
+//==============================================================================================================================
+const ARG_STRING = "string"
+const ARG_INTEGER = "integer"
+const ARG_ARRAY = "array"
+const ARG_OBJECT = "object"
+
+//==============================================================================================================================
+//	 Argument - The schema of one argument of a transaction. An argument that is not Optional may not be empty. Pattern
+//				is a regular expression a non empty string argument must match.
+//==============================================================================================================================
+type Argument struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Pattern     string `json:"pattern,omitempty" metadata:",optional"`
+	Optional    bool   `json:"optional,omitempty" metadata:",optional"`
+	Description string `json:"description"`
+}
+
+//==============================================================================================================================
+//	 Function - A transaction and the schema of its arguments, in the order they are passed. Name is the name the
+//				transaction is invoked by, <contract>:<Transaction>. Transient lists the entries the client must pass
+//				in the transient map.
+//==============================================================================================================================
+type Function struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Arguments   []Argument `json:"arguments"`
+	Transient   []string   `json:"transient,omitempty" metadata:",optional"`
+	Evaluate    bool       `json:"evaluate"`
+}
+
+//==============================================================================================================================
+//	 Patterns - The patterns arguments are checked against, and the reason given when a value does not match.
+//==============================================================================================================================
+var TIMESTAMP_PATTERN = `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`
+var MODE_PATTERN = "^(" + ALL_OR_NOTHING + "|" + BEST_EFFORT + ")$"
+var COUNT_PATTERN = `^[0-9]+$`
+
+var PATTERN_REASONS = map[string]string{
+	ILNSID_PATTERN:        ILNSID_REASON,
+	SHA256_PATTERN:        SHA256_REASON,
+	ID_PREFIX_PATTERN:     "must be two capital letters",
+	TIMESTAMP_PATTERN:     "must be an RFC 3339 timestamp",
+	MODE_PATTERN:          "must be " + ALL_OR_NOTHING + " or " + BEST_EFFORT,
+	COUNT_PATTERN:         "must be a whole number",
+	CREDENTIAL_ID_PATTERN: CREDENTIAL_ID_REASON,
+	VACCINE_PATTERN:       VACCINE_REASON,
+}
+
+//==============================================================================================================================
+//	 PATTERN_CHECKS - Further checks for values that match a pattern, e.g. the check digit of an ILNSID.
+//==============================================================================================================================
+var PATTERN_CHECKS = map[string]func(string) error{
+	ILNSID_PATTERN: validate_ILNSID,
+}
+
+//==============================================================================================================================
+//	 argument_formats - The pattern of every argument in FUNCTIONS, compiled once when the chaincode starts.
+//==============================================================================================================================
+var argument_formats = compile_patterns(FUNCTIONS)
+
+func compile_patterns(functions []Function) map[string]*regexp.Regexp {
+
+	formats := map[string]*regexp.Regexp{}
+
+	for _, f := range functions {
+		for _, a := range f.Arguments {
+			if _, ok := formats[a.Pattern]; a.Pattern != "" && !ok {
+				formats[a.Pattern] = regexp.MustCompile(a.Pattern)
+			}
+		}
+	}
+
+	return formats
+}
+
+var ILNSID_ARG = Argument{Name: "ILNSID", Type: ARG_STRING, Pattern: ILNSID_PATTERN, Description: "ILNSID of the member"}
+var RECIPIENT_ARG = Argument{Name: "recipient", Type: ARG_STRING, Description: "Username of the new custodian"}
+
+var SALTED = []string{TRANSIENT_ENTROPY} // Transactions that write clinical details, which salt them, see update_field_tree
+
+//==============================================================================================================================
+//	 FUNCTIONS - The registry of every transaction of every contract. Arguments are checked against it before a
+//				 transaction is dispatched and describe_functions returns it.
+//==============================================================================================================================
+var FUNCTIONS = []Function{
+	{Name: "member:CreateMember", Description: "Creates a member in the carrying state, owned by the caller", Transient: SALTED, Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "parents", Type: ARG_ARRAY, Description: "ILNSIDs of the member's parents"}}},
+	{Name: "member:UpdateDOB", Description: "Sets the date of birth", Transient: SALTED, Arguments: []Argument{ILNSID_ARG, {Name: "value", Type: ARG_STRING, Description: "Date of birth, YYYY-MM-DD"}}},
+	{Name: "member:UpdateGender", Description: "Sets the gender", Transient: SALTED, Arguments: []Argument{ILNSID_ARG, {Name: "value", Type: ARG_STRING, Description: "One of " + strings.Join(GENDERS, ", ")}}},
+	{Name: "member:UpdateBloodGrp", Description: "Sets the blood group", Transient: SALTED, Arguments: []Argument{ILNSID_ARG, {Name: "value", Type: ARG_STRING, Description: "One of " + strings.Join(BLOOD_GROUPS, ", ")}}},
+	{Name: "member:UpdateWeight", Description: "Sets the weight", Transient: SALTED, Arguments: []Argument{ILNSID_ARG, {Name: "value", Type: ARG_STRING, Description: "Weight and unit e.g. 3.45kg"}}},
+	{Name: "member:UpdateMember", Description: "Sets several demographic fields at once", Transient: SALTED, Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "patch", Type: ARG_OBJECT, Description: "Fields to set, any of " + strings.Join(PATCH_FIELDS, ", ")}}},
+	{Name: "member:RecordObservation", Description: "Appends an observation to the member's vitals", Transient: SALTED, Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "observation", Type: ARG_OBJECT, Description: "The observation"}}},
+	{Name: "member:ImportMembers", Description: "Creates members in bulk from JSON or CSV", Transient: SALTED, Arguments: []Argument{
+		{Name: "records", Type: ARG_STRING, Description: "JSON array or CSV of the members"},
+		{Name: "mode", Type: ARG_STRING, Pattern: MODE_PATTERN, Optional: true, Description: "How rejected rows are handled, " + ALL_OR_NOTHING + " by default"}}},
+	{Name: "member:EraseMember", Description: "Erases the member, leaving a tombstone with its lifecycle", Arguments: []Argument{ILNSID_ARG}},
+
+	{Name: "lifecycle:ParentsToBirthday", Description: "Hands a carried member to the birthday custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:BirthdayToHealthy", Description: "Hands a fully defined member to a healthy custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:HealthyToIllness", Description: "Hands a healthy member to an illness custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:IllnessToIllness", Description: "Hands an ill member to another illness custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:IllnessToHealthy", Description: "Hands a recovered member to a healthy custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:HealthyToDeath", Description: "Hands a healthy member to the death custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:IllnessToDeath", Description: "Hands an ill member to the death custodian", Arguments: []Argument{ILNSID_ARG, RECIPIENT_ARG}},
+	{Name: "lifecycle:DeadMember", Description: "Marks a member in the death status as dead", Arguments: []Argument{ILNSID_ARG}},
+
+	{Name: "consent:GrantConsent", Description: "Grants a user read access to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "grantee", Type: ARG_STRING, Description: "Username of the user granted access"},
+		{Name: "expires", Type: ARG_STRING, Pattern: TIMESTAMP_PATTERN, Optional: true, Description: "When the consent expires, never if empty"}}},
+	{Name: "consent:RevokeConsent", Description: "Removes the consent granted to a user", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "grantee", Type: ARG_STRING, Description: "Username of the user granted access"}}},
+	{Name: "consent:ListConsents", Description: "Lists the consents granted on the member", Arguments: []Argument{ILNSID_ARG}},
+
+	{Name: "document:AttachDocument", Description: "Anchors the metadata and hash of a file attached to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "document", Type: ARG_OBJECT, Description: "type (one of " + strings.Join(DOCUMENT_TYPES, ", ") + "), mime, size, sha256, uri and optionally episode"}}},
+	{Name: "document:ListDocuments", Description: "Lists the files attached to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "episode", Type: ARG_STRING, Optional: true, Description: "Only files of this episode, every file if empty"}}},
+	{Name: "document:VerifyDocument", Description: "Checks a file against the documents attached to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "sha256", Type: ARG_STRING, Pattern: SHA256_PATTERN, Description: "Hex SHA-256 of the file"}}},
+
+	{Name: "credential:IssueCredential", Description: "Issues a signed verifiable credential of a fact about the member and anchors its hash", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "type", Type: ARG_STRING, Description: "One of " + strings.Join(CREDENTIAL_TYPES, ", ")},
+		{Name: "expires", Type: ARG_STRING, Pattern: TIMESTAMP_PATTERN, Optional: true, Description: "When the credential expires, never if empty"}}},
+	{Name: "credential:RevokeCredential", Description: "Revokes a credential issued to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "id", Type: ARG_STRING, Pattern: CREDENTIAL_ID_PATTERN, Description: "Id of the credential, " + CREDENTIAL_PREFIX + "<txID>"},
+		{Name: "reason", Type: ARG_STRING, Optional: true, Description: "Why the credential is revoked"}}},
+	{Name: "credential:ListCredentials", Description: "Lists the credentials issued to the member", Arguments: []Argument{ILNSID_ARG}},
+
+	{Name: "immunization:RecordImmunization", Description: "Records a dose of a vaccine given to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "immunization", Type: ARG_OBJECT, Description: "vaccine, dose, lot, practitioner, date (YYYY-MM-DD) and site (one of " + strings.Join(IMMUNIZATION_SITES, ", ") + ")"}}},
+	{Name: "immunization:ListImmunizations", Description: "Lists the doses given to the member", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "vaccine", Type: ARG_STRING, Optional: true, Description: "Only doses of this vaccine, every dose if empty"}}},
+
+	{Name: "registry:AddEcert", Description: "Stores the eCert of a user", Arguments: []Argument{
+		{Name: "name", Type: ARG_STRING, Description: "Username"},
+		{Name: "ecert", Type: ARG_STRING, Description: "PEM encoded eCert"}}},
+	{Name: "registry:GetEcert", Description: "Returns the eCert stored for a user", Arguments: []Argument{{Name: "name", Type: ARG_STRING, Description: "Username"}}},
+	{Name: "registry:SetIDPrefix", Description: "Assigns the prefix an organisation's ILNSIDs are issued under", Arguments: []Argument{
+		{Name: "org", Type: ARG_STRING, Description: "MSP ID of the organisation"},
+		{Name: "prefix", Type: ARG_STRING, Pattern: ID_PREFIX_PATTERN, Description: "Two capital letters"}}},
+	{Name: "registry:AllocateILNSID", Description: "Issues the next ILNSID of the caller's organisation", Arguments: []Argument{}},
+	{Name: "registry:CheckUniqueILNS", Description: "Returns true if the ILNSID has not been used", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "registry:LoadGrowthReference", Description: "Stores a WHO LMS reference table", Arguments: []Argument{{Name: "reference", Type: ARG_OBJECT, Description: "The reference table"}}},
+	{Name: "registry:LoadImmunizationSchedule", Description: "Stores the national schedule of a vaccine", Arguments: []Argument{
+		{Name: "schedule", Type: ARG_OBJECT, Description: "vaccine, name and doses, each with dose, ageDays and graceDays"}}},
+	{Name: "registry:ImportState", Description: "Writes pages of export_state back to the ledger", Arguments: []Argument{{Name: "data", Type: ARG_STRING, Description: "JSON lines of the export"}}},
+	{Name: "registry:MigrateRecords", Description: "Upgrades stored documents to their current schema version", Arguments: []Argument{
+		{Name: "bookmark", Type: ARG_STRING, Optional: true, Description: "Bookmark returned by the previous batch, empty for the first"},
+		{Name: "batch_size", Type: ARG_INTEGER, Pattern: COUNT_PATTERN, Description: "Members per batch, 0 for the default"}}},
+	{Name: "registry:RekeyState", Description: "Moves entries stored under legacy keys to their namespaced keys", Arguments: []Argument{
+		{Name: "bookmark", Type: ARG_STRING, Optional: true, Description: "Bookmark returned by the previous batch, empty for the first"},
+		{Name: "batch_size", Type: ARG_INTEGER, Pattern: COUNT_PATTERN, Description: "Keys per batch, 0 for the default"}}},
+	{Name: "registry:SetOrgKey", Description: "Registers the public key member data keys are wrapped for an organisation", Arguments: []Argument{
+		{Name: "org", Type: ARG_STRING, Description: "MSP ID of the organisation"},
+		{Name: "public_key", Type: ARG_STRING, Description: "Base64 X25519 public key"}}},
+	{Name: "registry:SetIssuerKey", Description: "Registers the public key an organisation signs credentials with", Arguments: []Argument{
+		{Name: "org", Type: ARG_STRING, Description: "MSP ID of the organisation"},
+		{Name: "public_key", Type: ARG_STRING, Description: "Base64 Ed25519 public key"}}},
+	{Name: "registry:RotateKeys", Description: "Re-encrypts members under new data keys for the registered organisation keys", Arguments: []Argument{
+		{Name: "bookmark", Type: ARG_STRING, Optional: true, Description: "Bookmark returned by the previous batch, empty for the first"},
+		{Name: "batch_size", Type: ARG_INTEGER, Pattern: COUNT_PATTERN, Description: "Members per batch, 0 for the default"}}},
+
+	{Name: "query:GetMemberDetails", Description: "Returns the member", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "query:GetMembers", Description: "Returns every member the caller may see", Arguments: []Argument{}},
+	{Name: "query:GetDataKeys", Description: "Returns the wrapped data keys of the members for the caller to unwrap", Arguments: []Argument{
+		{Name: "ILNSIDs", Type: ARG_ARRAY, Description: "ILNSIDs of the members, empty for every member the caller may see"}}},
+	{Name: "query:GetMemberHistory", Description: "Returns every committed version of the member", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "query:VerifyMemberDetails", Description: "Checks the member's clinical details against the hash on the ledger", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "query:GetObservations", Description: "Returns the member's observations", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "obs_type", Type: ARG_STRING, Optional: true, Description: "Only observations of this type, all if empty"}}},
+	{Name: "query:GetGrowthPercentiles", Description: "Returns the member's weights against the growth reference", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "query:GetImmunizationStatus", Description: "Returns the member's doses given, due, overdue and upcoming against the schedule", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "query:GetOverdueMembers", Description: "Lists the members overdue for a dose of a vaccine", Arguments: []Argument{
+		{Name: "vaccine", Type: ARG_STRING, Pattern: VACCINE_PATTERN, Description: "Code of the vaccine"}}},
+	{Name: "query:ProveFields", Description: "Returns a Merkle proof of some of the member's fields", Arguments: []Argument{
+		ILNSID_ARG,
+		{Name: "fields", Type: ARG_ARRAY, Description: "Fields to disclose, among " + strings.Join(DISCLOSED_FIELDS, ", ") + "; ILNSID is always disclosed"}}},
+	{Name: "query:GetFieldsRoot", Description: "Returns the root proofs of the member's fields are checked against", Arguments: []Argument{ILNSID_ARG}},
+	{Name: "query:VerifyCredential", Description: "Checks a credential is anchored, correctly signed, not revoked and not expired", Arguments: []Argument{
+		{Name: "jwt", Type: ARG_STRING, Description: "The credential as a JWT"}}},
+	{Name: "query:CheckImport", Description: "Validates an import without writing it", Arguments: []Argument{
+		{Name: "records", Type: ARG_STRING, Description: "JSON array or CSV of the members"},
+		{Name: "mode", Type: ARG_STRING, Pattern: MODE_PATTERN, Optional: true, Description: "How rejected rows are handled, " + ALL_OR_NOTHING + " by default"}}},
+	{Name: "query:GetImportReport", Description: "Returns the report of an import", Arguments: []Argument{{Name: "tx_ID", Type: ARG_STRING, Description: "Transaction ID of the import"}}},
+	{Name: "query:ExportState", Description: "Returns a page of the ledger as JSON lines", Arguments: []Argument{
+		{Name: "bookmark", Type: ARG_STRING, Optional: true, Description: "Bookmark returned by the previous page, empty for the first"},
+		{Name: "page_size", Type: ARG_INTEGER, Pattern: COUNT_PATTERN, Description: "Entries per page, 0 for the default"}}},
+	{Name: "query:Ping", Description: "Checks the chaincode is running", Arguments: []Argument{}},
+	{Name: "query:DescribeFunctions", Description: "Returns this registry", Arguments: []Argument{}},
+}
+
+//==============================================================================================================================
+//	 find_function - Looks a transaction up in the registry.
+//==============================================================================================================================
+func find_function(name string) (Function, bool) {
+
+	for _, f := range FUNCTIONS {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return Function{}, false
+}
+
+//==============================================================================================================================
+//	 validate_arguments - Checks the arguments passed to a transaction against its schema. Returns a Validation_Error
+//						  naming the first bad argument. An ILNSID that is not in the current format is accepted if
+//						  is_member says a member holds it, as members created before the format keep their IDs.
+//==============================================================================================================================
+func validate_arguments(f Function, args []string, is_member func(ILNSID string) bool) error {
+
+	if len(args) != len(f.Arguments) {
+
+		names := []string{}
+
+		for _, a := range f.Arguments {
+			names = append(names, a.Name)
+		}
+
+		return invalid("arguments", strconv.Itoa(len(args)), fmt.Sprintf("%s takes %d arguments (%s)", f.Name, len(f.Arguments), strings.Join(names, ", ")))
+	}
+
+	for i, a := range f.Arguments {
+
+		value := args[i]
+
+		if value == "" {
+			if a.Optional {
+				continue
+			}
+			return invalid(a.Name, value, "must not be empty")
+		}
+
+		switch a.Type {
+		case ARG_INTEGER:
+			if _, err := strconv.Atoi(value); err != nil {
+				return invalid(a.Name, value, "must be an integer")
+			}
+		case ARG_ARRAY:
+			var v []interface{}
+			if json.Unmarshal([]byte(value), &v) != nil {
+				return invalid(a.Name, value, "must be a JSON array")
+			}
+		case ARG_OBJECT:
+			var v map[string]interface{}
+			if json.Unmarshal([]byte(value), &v) != nil || v == nil {
+				return invalid(a.Name, value, "must be a JSON object")
+			}
+		}
+
+		if err := check_pattern(a, value); err != nil {
+
+			if a.Pattern == ILNSID_PATTERN && is_member(value) {
+				continue
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 check_pattern - Checks a value against the pattern of its argument and any further check the pattern has.
+//==============================================================================================================================
+func check_pattern(a Argument, value string) error {
+
+	if format, ok := argument_formats[a.Pattern]; ok && !format.MatchString(value) {
+
+		reason, ok := PATTERN_REASONS[a.Pattern]
+
+		if !ok {
+			reason = "must match " + a.Pattern
+		}
+
+		return invalid(a.Name, value, reason)
+	}
+
+	if check, ok := PATTERN_CHECKS[a.Pattern]; ok {
+		if err := check(value); err != nil {
+
+			if ve, ok := err.(*Validation_Error); ok {
+				return invalid(a.Name, value, ve.Reason)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 check_arguments - The before transaction handler of a contract. Validates the arguments of every transaction of the
+//					   contract before the contract API converts them and dispatches it. Functions missing from the
+//					   registry are left to the contract API to reject.
+//==============================================================================================================================
+func check_arguments(contract string) func(ctx contractapi.TransactionContextInterface) error {
+
+	return func(ctx contractapi.TransactionContextInterface) error {
+
+		function, args := ctx.GetStub().GetFunctionAndParameters()
+
+		if i := strings.LastIndex(function, ":"); i >= 0 {
+			function = function[i+1:]
+		}
+
+		f, ok := find_function(contract + ":" + function)
+
+		if !ok {
+			return nil
+		}
+
+		return validate_arguments(f, args, func(ILNSID string) bool {
+			record, err := ctx.GetStub().GetState(member_key(ILNSID))
+			return err == nil && record != nil
+		})
+	}
+}
+
+//==============================================================================================================================
+//	 describe_functions - The registry, with Evaluate set from the evaluated transactions of each contract, sorted by
+//						  name.
+//==============================================================================================================================
+func describe_functions() []Function {
+
+	evaluated := map[string]bool{}
+
+	for _, contract := range Contracts() {
+		if ec, ok := contract.(contractapi.EvaluationContractInterface); ok {
+			for _, name := range ec.GetEvaluateTransactions() {
+				evaluated[contract.GetName()+":"+name] = true
+			}
+		}
+	}
+
+	functions := []Function{}
+
+	for _, f := range FUNCTIONS {
+		f.Evaluate = evaluated[f.Name]
+		functions = append(functions, f)
+	}
+
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+
+	return functions
+}
+
+//==============================================================================================================================
+//	 Functions - The registry as query:DescribeFunctions returns it, for clients and documents generated from it.
+//==============================================================================================================================
+func Functions() []Function {
+	return describe_functions()
+}
+
+//==============================================================================================================================
+//	 ValidateArguments - Checks arguments against the registry as the chaincode will, so that clients can reject a call
+//						 before submitting it. Clients can not tell whether an ILNSID in an older format belongs to a
+//						 member, so such IDs are left for the chaincode to check.
+//==============================================================================================================================
+func ValidateArguments(function string, args []string) error {
+
+	f, ok := find_function(function)
+
+	if !ok {
+		return &Chaincode_Error{Code: NOT_FOUND, Message: "No function named " + function, Details: map[string]interface{}{"function": function}}
+	}
+
+	return validate_arguments(f, args, func(string) bool { return true })
+}
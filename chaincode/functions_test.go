@@ -0,0 +1,46 @@
+package chaincode_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+)
+
+// The registry must describe every transaction, with as many arguments as the transaction takes.
+func TestFunctionRegistryMatchesContracts(t *testing.T) {
+
+	h := chaincodetest.New(time.Now())
+
+	registered := map[string]chaincode.Function{}
+
+	for _, f := range chaincode.FUNCTIONS {
+		registered[f.Name] = f
+	}
+
+	for _, name := range h.Functions() {
+
+		f, ok := registered[name]
+
+		if !ok {
+			t.Errorf("%s is not in the function registry", name)
+			continue
+		}
+
+		delete(registered, name)
+
+		i := strings.Index(name, ":")
+		method, _ := reflect.TypeOf(h.Contracts[name[:i]]).MethodByName(name[i+1:])
+
+		if params := method.Type.NumIn() - 2; params != len(f.Arguments) {
+			t.Errorf("%s takes %d arguments, the registry lists %d", name, params, len(f.Arguments))
+		}
+	}
+
+	for name := range registered {
+		t.Errorf("%s is in the function registry but no contract implements it", name)
+	}
+}
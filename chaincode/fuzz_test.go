@@ -0,0 +1,66 @@
+package chaincode_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+)
+
+const FUZZ_DIR = "testdata/fuzz"
+
+var fuzz_runs = flag.Int("lifecycle.runs", 300, "number of random sequences TestLifecycleProperties runs")
+var fuzz_length = flag.Int("lifecycle.length", 100, "number of invokes in each random sequence")
+var fuzz_seed = flag.Int64("lifecycle.seed", 1, "seed of the first random sequence, the others follow on from it")
+
+// Runs random sequences of invokes and checks the lifecycle invariants after every step. A failing sequence is shrunk
+// and saved under testdata/fuzz, where TestLifecycleRegressions replays it from then on.
+func TestLifecycleProperties(t *testing.T) {
+
+	for i := 0; i < *fuzz_runs; i++ {
+
+		seed := *fuzz_seed + int64(i)
+
+		sc, v := chaincodetest.NewFuzzer(seed, *fuzz_length).Run()
+
+		if v == nil {
+			continue
+		}
+
+		sc, v = chaincodetest.Shrink(sc, v)
+		sc.Name = v.Invariant + "-" + sc.Name
+
+		path := filepath.Join(FUZZ_DIR, sc.Name+".json")
+
+		if err := os.MkdirAll(FUZZ_DIR, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := sc.Save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Fatalf("seed %d: %v\nshrunk to %d steps, saved to %s", seed, v, len(sc.Steps), path)
+	}
+}
+
+// Replays the sequences TestLifecycleProperties has saved.
+func TestLifecycleRegressions(t *testing.T) {
+
+	scenarios, err := chaincodetest.LoadScenarios(FUZZ_DIR)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.Name, func(t *testing.T) {
+			if v := chaincodetest.Check(sc); v != nil {
+				t.Fatal(v)
+			}
+		})
+	}
+}
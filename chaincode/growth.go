@@ -0,0 +1,369 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Growth indicators - The WHO Child Growth Standards indicators that percentiles are computed for. The LMS reference
+//						 tables are published by the WHO and are loaded onto the ledger with load_growth_reference, one
+//						 table per indicator and sex.
+//==============================================================================================================================
+const WEIGHT_FOR_AGE = "weight_for_age"
+const LENGTH_FOR_AGE = "length_for_age"
+const HEAD_CIRCUMFERENCE_FOR_AGE = "head_circumference_for_age"
+const BMI_FOR_AGE = "bmi_for_age"
+
+var GROWTH_INDICATORS = []string{WEIGHT_FOR_AGE, LENGTH_FOR_AGE, HEAD_CIRCUMFERENCE_FOR_AGE, BMI_FOR_AGE}
+
+const DAYS_PER_MONTH = 30.4375
+
+//==============================================================================================================================
+//	 LMS_Point - Box-Cox power (L), median (M) and coefficient of variation (S) at a given age.
+//==============================================================================================================================
+type LMS_Point struct {
+	Age float64 `json:"age"`
+	L   float64 `json:"L"`
+	M   float64 `json:"M"`
+	S   float64 `json:"S"`
+}
+
+//==============================================================================================================================
+//	 Growth_Reference - An LMS table for one indicator and sex. Age_Unit is "day" or "month", points must be in
+//						ascending age order.
+//==============================================================================================================================
+type Growth_Reference struct {
+	Indicator      string      `json:"indicator"`
+	Sex            string      `json:"sex"`
+	Age_Unit       string      `json:"ageUnit"`
+	Points         []LMS_Point `json:"points"`
+	Schema_Version int         `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Growth_Point / Growth_Series / Growth_Report - Result of get_growth_percentiles. A series is returned for every
+//													indicator, Reference_Loaded is false when no table is loaded for
+//													it. Observations outside the age range of the table are omitted.
+//==============================================================================================================================
+type Growth_Point struct {
+	Effective  string  `json:"effective"`
+	Age_Days   int     `json:"ageDays"`
+	Value      float64 `json:"value"`
+	Z_Score    float64 `json:"zScore"`
+	Percentile float64 `json:"percentile"`
+}
+
+type Growth_Series struct {
+	Indicator        string         `json:"indicator"`
+	Unit             string         `json:"unit"`
+	Reference_Loaded bool           `json:"referenceLoaded"`
+	Points           []Growth_Point `json:"points"`
+}
+
+type Growth_Report struct {
+	ILNSID string          `json:"ILNSID"`
+	Gender string          `json:"gender"`
+	DOB    string          `json:"DOB"`
+	BMI    float64         `json:"BMI,omitempty" metadata:",optional"`
+	Series []Growth_Series `json:"series"`
+}
+
+//=================================================================================================================================
+//	 load_growth_reference - Stores an LMS reference table. Only administrators may load reference data.
+//=================================================================================================================================
+func load_growth_reference(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, ref Growth_Reference) error {
+
+	if caller_affiliation != ADMIN {
+		return role_required("load_growth_reference", ADMIN, caller_affiliation)
+	}
+
+	known := false
+
+	for _, indicator := range GROWTH_INDICATORS {
+		if ref.Indicator == indicator {
+			known = true
+		}
+	}
+
+	if !known {
+		return invalid("indicator", ref.Indicator, "unknown growth indicator")
+	}
+
+	if ref.Sex != "male" && ref.Sex != "female" {
+		return invalid("sex", ref.Sex, "must be male or female")
+	}
+
+	if ref.Age_Unit != "day" && ref.Age_Unit != "month" {
+		return invalid("ageUnit", ref.Age_Unit, "must be day or month")
+	}
+
+	if len(ref.Points) < 2 {
+		return invalid("points", "", "at least two LMS points are required")
+	}
+
+	for i, p := range ref.Points {
+		if p.M <= 0 || p.S <= 0 || (i > 0 && p.Age <= ref.Points[i-1].Age) {
+			return invalid("points", "", "points must have positive M and S and be in ascending age order")
+		}
+	}
+
+	ref.Schema_Version = schema_version(DOC_GROWTH_REFERENCE)
+
+	bytes, err := json.Marshal(ref)
+
+	if err != nil {
+		return internal("Error converting growth reference")
+	}
+
+	err = stub.PutState(growth_reference_key(ref.Indicator, ref.Sex), bytes)
+
+	if err != nil {
+		return internal("Error storing growth reference")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 retrieve_growth_reference - Gets the reference table for an indicator and sex. Returns nil if none is loaded.
+//==============================================================================================================================
+func retrieve_growth_reference(stub shim.ChaincodeStubInterface, indicator string, sex string) (*Growth_Reference, error) {
+
+	var ref Growth_Reference
+
+	found, err := read_document(stub, DOC_GROWTH_REFERENCE, growth_reference_key(indicator, sex), &ref)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &ref, nil
+}
+
+//==============================================================================================================================
+//	 lms_at - Linearly interpolates the LMS parameters at the age given in days. ok is false outside the table.
+//==============================================================================================================================
+func lms_at(ref *Growth_Reference, age_days float64) (LMS_Point, bool) {
+
+	age := age_days
+
+	if ref.Age_Unit == "month" {
+		age = age_days / DAYS_PER_MONTH
+	}
+
+	points := ref.Points
+
+	if age < points[0].Age || age > points[len(points)-1].Age {
+		return LMS_Point{}, false
+	}
+
+	i := sort.Search(len(points), func(i int) bool { return points[i].Age >= age })
+
+	if points[i].Age == age {
+		return points[i], true
+	}
+
+	lo, hi := points[i-1], points[i]
+	f := (age - lo.Age) / (hi.Age - lo.Age)
+
+	return LMS_Point{
+		Age: age,
+		L:   lo.L + f*(hi.L-lo.L),
+		M:   lo.M + f*(hi.M-lo.M),
+		S:   lo.S + f*(hi.S-lo.S),
+	}, true
+}
+
+//==============================================================================================================================
+//	 lms_value - The measurement that corresponds to z standard deviations for the LMS parameters.
+//==============================================================================================================================
+func lms_value(p LMS_Point, z float64) float64 {
+
+	if p.L == 0 {
+		return p.M * math.Exp(p.S*z)
+	}
+
+	return p.M * math.Pow(1+p.L*p.S*z, 1/p.L)
+}
+
+//==============================================================================================================================
+//	 z_score - The LMS z-score of a measurement. Weight based indicators are skewed so, as in the WHO method, scores
+//			   beyond +/-3 are computed from the distance between the 2SD and 3SD cut-offs rather than the LMS curve.
+//==============================================================================================================================
+func z_score(indicator string, p LMS_Point, y float64) float64 {
+
+	var z float64
+
+	if p.L == 0 {
+		z = math.Log(y/p.M) / p.S
+	} else {
+		z = (math.Pow(y/p.M, p.L) - 1) / (p.L * p.S)
+	}
+
+	if indicator != WEIGHT_FOR_AGE && indicator != BMI_FOR_AGE {
+		return z
+	}
+
+	if z > 3 {
+		sd3 := lms_value(p, 3)
+		z = 3 + (y-sd3)/(sd3-lms_value(p, 2))
+	} else if z < -3 {
+		sd3 := lms_value(p, -3)
+		z = -3 + (y-sd3)/(lms_value(p, -2)-sd3)
+	}
+
+	return z
+}
+
+//==============================================================================================================================
+//	 percentile - Converts a z-score to a percentile of the standard normal distribution.
+//==============================================================================================================================
+func percentile(z float64) float64 {
+	return 50 * math.Erfc(-z/math.Sqrt2)
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+//==============================================================================================================================
+//	 indicator_values - Pairs each relevant observation with its effective time for an indicator. BMI is derived from
+//						each weight and the most recent height taken no more than 31 days earlier.
+//==============================================================================================================================
+type timed_value struct {
+	at    time.Time
+	value float64
+}
+
+func indicator_values(series Vitals_Series, indicator string) []timed_value {
+
+	values := []timed_value{}
+
+	var last_height *timed_value
+
+	for _, o := range series.Observations {
+
+		at, err := effective_time(o.Effective)
+
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case indicator == WEIGHT_FOR_AGE && o.Type == OBS_WEIGHT,
+			indicator == LENGTH_FOR_AGE && o.Type == OBS_HEIGHT,
+			indicator == HEAD_CIRCUMFERENCE_FOR_AGE && o.Type == OBS_HEAD_CIRCUMFERENCE:
+
+			values = append(values, timed_value{at, normalised_value(o)})
+
+		case indicator == BMI_FOR_AGE && o.Type == OBS_HEIGHT:
+
+			last_height = &timed_value{at, normalised_value(o)}
+
+		case indicator == BMI_FOR_AGE && o.Type == OBS_WEIGHT && last_height != nil:
+
+			if at.Sub(last_height.at) <= 31*24*time.Hour {
+				metres := last_height.value / 100
+				values = append(values, timed_value{at, normalised_value(o) / (metres * metres)})
+			}
+		}
+	}
+
+	return values
+}
+
+//=================================================================================================================================
+//	 get_growth_percentiles - Computes WHO growth standard z-scores and percentiles from the member's vitals series using
+//							  the member's DOB and gender, together with their most recent BMI.
+//=================================================================================================================================
+func get_growth_percentiles(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) (*Growth_Report, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("get_growth_percentiles", m)
+	}
+
+	if m.Redacted {
+		return nil, details_denied("get_growth_percentiles", m)
+	}
+
+	if m.DOB == ENCRYPTED {
+		return nil, invalid("DOB", m.DOB, "the DOB is encrypted, pass its data key in "+TRANSIENT_DATA_KEYS)
+	}
+
+	dob, err := parse_DOB(m.DOB)
+
+	if err != nil {
+		return nil, invalid("DOB", m.DOB, "a DOB is required to compute growth percentiles")
+	}
+
+	if m.Gender != "male" && m.Gender != "female" {
+		return nil, invalid("gender", m.Gender, "growth standards are defined for male and female only")
+	}
+
+	series, err := retrieve_vitals(stub, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := Growth_Report{ILNSID: m.ILNSID, Gender: m.Gender, DOB: m.DOB, Series: []Growth_Series{}}
+
+	bmi := indicator_values(series, BMI_FOR_AGE)
+
+	if len(bmi) > 0 {
+		report.BMI = round(bmi[len(bmi)-1].value, 1)
+	}
+
+	units := map[string]string{WEIGHT_FOR_AGE: "kg", LENGTH_FOR_AGE: "cm", HEAD_CIRCUMFERENCE_FOR_AGE: "cm", BMI_FOR_AGE: "kg/m2"}
+
+	for _, indicator := range GROWTH_INDICATORS {
+
+		result := Growth_Series{Indicator: indicator, Unit: units[indicator], Points: []Growth_Point{}}
+
+		ref, err := retrieve_growth_reference(stub, indicator, m.Gender)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ref != nil {
+
+			result.Reference_Loaded = true
+
+			for _, v := range indicator_values(series, indicator) {
+
+				age_days := v.at.Sub(dob).Hours() / 24
+
+				p, ok := lms_at(ref, age_days)
+
+				if !ok {
+					continue
+				}
+
+				z := z_score(indicator, p, v.value)
+
+				result.Points = append(result.Points, Growth_Point{
+					Effective:  v.at.Format(time.RFC3339),
+					Age_Days:   int(age_days),
+					Value:      round(v.value, 2),
+					Z_Score:    round(z, 2),
+					Percentile: round(percentile(z), 1),
+				})
+			}
+		}
+
+		report.Series = append(report.Series, result)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,277 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 ILNSID format - Two capital letters naming the issuing organisation, a seven digit sequence and a Luhn check digit,
+//					 e.g. AB12345674. The check digit is computed over the letters as two digit numbers (A=10 .. Z=35)
+//					 followed by the sequence, as IBANs do, so that a mistyped letter is caught as well as a digit.
+//==============================================================================================================================
+const ILNSID_PATTERN = `^[A-Z]{2}[0-9]{8}$`
+const ILNSID_REASON = "must be two capital letters, seven digits and a check digit"
+const ID_PREFIX_PATTERN = `^[A-Z]{2}$`
+
+const MAX_ID_SEQUENCE = 9999999
+
+var ILNSID_format = regexp.MustCompile(ILNSID_PATTERN)
+var ID_prefix_format = regexp.MustCompile(ID_PREFIX_PATTERN)
+
+//==============================================================================================================================
+//	 ID_Sequence - The ILNSIDs issued to an organisation. Next is the sequence number the next allocation tries.
+//==============================================================================================================================
+type ID_Sequence struct {
+	Org            string `json:"org"`
+	Prefix         string `json:"prefix"`
+	Next           int    `json:"next"`
+	Schema_Version int    `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 luhn_digits - The digits the check digit is computed over.
+//==============================================================================================================================
+func luhn_digits(payload string) string {
+
+	digits := ""
+
+	for _, c := range payload {
+		if c >= 'A' && c <= 'Z' {
+			digits += strconv.Itoa(int(c-'A') + 10)
+		} else {
+			digits += string(c)
+		}
+	}
+
+	return digits
+}
+
+//==============================================================================================================================
+//	 check_digit - The Luhn check digit of a prefix and sequence.
+//==============================================================================================================================
+func check_digit(payload string) int {
+
+	digits := luhn_digits(payload)
+	sum := 0
+
+	for i := len(digits) - 1; i >= 0; i-- {
+
+		d := int(digits[i] - '0')
+
+		if (len(digits)-1-i)%2 == 0 { // Doubling starts with the rightmost digit as the check digit is appended after it
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+	}
+
+	return (10 - sum%10) % 10
+}
+
+//==============================================================================================================================
+//	 format_ILNSID - The ILNSID with the prefix and sequence number given.
+//==============================================================================================================================
+func format_ILNSID(prefix string, sequence int) string {
+
+	payload := fmt.Sprintf("%s%07d", prefix, sequence)
+
+	return payload + strconv.Itoa(check_digit(payload))
+}
+
+//==============================================================================================================================
+//	 validate_ILNSID - Checks the ILNSID is well formed and its check digit matches.
+//==============================================================================================================================
+func validate_ILNSID(ILNSID string) error {
+
+	if !ILNSID_format.MatchString(ILNSID) {
+		return invalid("ILNSID", ILNSID, ILNSID_REASON)
+	}
+
+	if strconv.Itoa(check_digit(ILNSID[:9])) != ILNSID[9:] {
+		return invalid("ILNSID", ILNSID, "check digit does not match")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 retrieve_id_sequence - The sequence of an organisation, nil if no prefix has been set for it.
+//==============================================================================================================================
+func retrieve_id_sequence(stub shim.ChaincodeStubInterface, org string) (*ID_Sequence, error) {
+
+	var seq ID_Sequence
+
+	found, err := read_document(stub, DOC_ID_SEQUENCE, id_sequence_key(org), &seq)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &seq, nil
+}
+
+func save_id_sequence(stub shim.ChaincodeStubInterface, seq ID_Sequence) error {
+
+	seq.Schema_Version = schema_version(DOC_ID_SEQUENCE)
+
+	bytes, err := json.Marshal(seq)
+
+	if err != nil {
+		return internal("Error converting ID sequence")
+	}
+
+	if err = stub.PutState(id_sequence_key(seq.Org), bytes); err != nil {
+		return internal("Error storing ID sequence")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 prefix_owner - The organisation the prefix has been given to, empty if it has not been given to any.
+//==============================================================================================================================
+func prefix_owner(stub shim.ChaincodeStubInterface, prefix string) (string, error) {
+
+	keys, err := list_keys(stub, ENTRY_ID_SEQUENCE)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range keys {
+
+		var seq ID_Sequence
+
+		if _, err := read_document(stub, DOC_ID_SEQUENCE, key, &seq); err != nil {
+			return "", err
+		}
+
+		if seq.Prefix == prefix {
+			return seq.Org, nil
+		}
+	}
+
+	return "", nil
+}
+
+//==============================================================================================================================
+//	 check_ILNSID_prefix - A new member's ILNSID must be issued under the prefix of the caller's organisation. IDs under
+//						   a prefix given to another organisation are refused, as are IDs under any other prefix once
+//						   the caller's organisation has one. Before prefixes are set any well formed ID is accepted.
+//==============================================================================================================================
+func check_ILNSID_prefix(stub shim.ChaincodeStubInterface, ILNSID string, org string) error {
+
+	prefix := ILNSID[:2]
+
+	owner, err := prefix_owner(stub, prefix)
+
+	if err != nil {
+		return err
+	}
+
+	if owner != "" && owner != org {
+		return permission_denied("check_ILNSID_prefix", map[string]interface{}{"ILNSID": ILNSID, "prefix": prefix, "prefix_org": owner, "caller_org": org})
+	}
+
+	seq, err := retrieve_id_sequence(stub, org)
+
+	if err != nil {
+		return err
+	}
+
+	if seq != nil && seq.Prefix != prefix {
+		return invalid("ILNSID", ILNSID, "must be issued under "+seq.Prefix+", the prefix of "+org)
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 set_id_prefix - Assigns the two letter prefix an organisation's ILNSIDs are issued under. A prefix belongs to one
+//					 organisation only. Changing an organisation's prefix starts a new sequence. Only administrators may
+//					 set prefixes.
+//=================================================================================================================================
+func set_id_prefix(stub shim.ChaincodeStubInterface, caller_affiliation string, org string, prefix string) (*ID_Sequence, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("set_id_prefix", ADMIN, caller_affiliation)
+	}
+
+	if !ID_prefix_format.MatchString(prefix) {
+		return nil, invalid("prefix", prefix, "must be two capital letters")
+	}
+
+	owner, err := prefix_owner(stub, prefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if owner != "" && owner != org {
+		return nil, conflict("Prefix "+prefix+" is used by "+owner, map[string]interface{}{"prefix": prefix, "org": owner})
+	}
+
+	seq, err := retrieve_id_sequence(stub, org)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if seq != nil && seq.Prefix == prefix {
+		return seq, nil
+	}
+
+	seq = &ID_Sequence{Org: org, Prefix: prefix, Next: 1}
+
+	return seq, save_id_sequence(stub, *seq)
+}
+
+//=================================================================================================================================
+//	 allocate_ILNSID - Issues the next ILNSID of the caller's organisation. IDs already taken, e.g. by members created
+//					   with IDs chosen by hand, are skipped. Allocations by one organisation are serialised by the sequence
+//					   document, concurrent ones fail validation and must be retried.
+//=================================================================================================================================
+func allocate_ILNSID(stub shim.ChaincodeStubInterface, caller_affiliation string, org string) (string, error) {
+
+	if caller_affiliation != PARENTS && caller_affiliation != ADMIN {
+		return "", role_required("allocate_ilnsid", PARENTS, caller_affiliation)
+	}
+
+	seq, err := retrieve_id_sequence(stub, org)
+
+	if err != nil {
+		return "", err
+	}
+
+	if seq == nil {
+		return "", not_found("No ID prefix set for "+org, map[string]interface{}{"org": org})
+	}
+
+	for ; seq.Next <= MAX_ID_SEQUENCE; seq.Next++ {
+
+		ILNSID := format_ILNSID(seq.Prefix, seq.Next)
+
+		existing, err := stub.GetState(member_key(ILNSID))
+
+		if err != nil {
+			return "", internal("Error retrieving " + ILNSID)
+		}
+
+		if existing == nil {
+
+			seq.Next++
+
+			return ILNSID, save_id_sequence(stub, *seq)
+		}
+	}
+
+	return "", &Chaincode_Error{Code: INVALID_STATE, Message: "ID sequence of " + org + " is exhausted", Details: map[string]interface{}{"org": org, "prefix": seq.Prefix}}
+}
@@ -0,0 +1,639 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Immunizations - The vaccinations given to a member are kept in one series per member under immunizations:<ILNSID>,
+//					 in the order they were given, in the member's clinical collections with the series' hash on the
+//					 member's stub. The national schedule is loaded by administrators one vaccine at a time under
+//					 schedule:<vaccine>, each dose due at an age in days from the DOB and overdue once its grace period
+//					 has passed. Vaccines without a schedule may be recorded but are not assessed.
+//==============================================================================================================================
+const ENTRY_IMMUNIZATIONS = "immunizations"
+const ENTRY_SCHEDULE = "schedule"
+
+var IMMUNIZATION_SITES = []string{"left_arm", "right_arm", "left_thigh", "right_thigh", "oral", "intranasal", "other"}
+
+const VACCINE_PATTERN = `^[A-Za-z0-9-]{1,32}$`
+const VACCINE_REASON = "must be a vaccine code of up to 32 letters, digits and hyphens"
+
+var vaccine_format = regexp.MustCompile(VACCINE_PATTERN)
+
+const MAX_DOSES = 20
+const MAX_SCHEDULE_AGE = 100 * 365
+
+//==============================================================================================================================
+//	 Dose statuses - Where each scheduled dose of a member stands at the time of the query.
+//==============================================================================================================================
+const DOSE_GIVEN = "given"
+const DOSE_OVERDUE = "overdue"
+const DOSE_DUE = "due"
+const DOSE_UPCOMING = "upcoming"
+
+//==============================================================================================================================
+//	 Immunization - One dose of a vaccine given to a member. Date is the ISO-8601 date it was given, Recorded_By and Tx_ID
+//					record who added it.
+//==============================================================================================================================
+type Immunization struct {
+	Vaccine      string `json:"vaccine"`
+	Dose         int    `json:"dose"`
+	Lot          string `json:"lot"`
+	Practitioner string `json:"practitioner"`
+	Date         string `json:"date"`
+	Site         string `json:"site"`
+	Recorded_By  string `json:"recordedBy" metadata:",optional"`
+	Tx_ID        string `json:"txID" metadata:",optional"`
+}
+
+//==============================================================================================================================
+//	 Immunization_History - Every dose given to a member, ordered by date. Redacted is set, and no doses are given, when
+//							the caller may not read the member's details.
+//==============================================================================================================================
+type Immunization_History struct {
+	ILNSID         string         `json:"ILNSID"`
+	Immunizations  []Immunization `json:"immunizations"`
+	Redacted       bool           `json:"redacted,omitempty" metadata:",optional"`
+	Schema_Version int            `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Vaccine_Schedule - The doses of a vaccine in the national schedule, in dose order. Each dose is due Age_Days after
+//						the DOB and overdue Grace_Days after that.
+//==============================================================================================================================
+type Scheduled_Dose struct {
+	Dose       int `json:"dose"`
+	Age_Days   int `json:"ageDays"`
+	Grace_Days int `json:"graceDays"`
+}
+
+type Vaccine_Schedule struct {
+	Vaccine        string           `json:"vaccine"`
+	Name           string           `json:"name"`
+	Doses          []Scheduled_Dose `json:"doses"`
+	Schema_Version int              `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Dose_Status / Vaccine_Status / Immunization_Status - Result of get_immunization_status. Given holds the dose
+//														 recorded for a dose that was given.
+//==============================================================================================================================
+type Dose_Status struct {
+	Dose   int           `json:"dose"`
+	Due    string        `json:"due"`
+	Status string        `json:"status"`
+	Given  *Immunization `json:"given,omitempty" metadata:",optional"`
+}
+
+type Vaccine_Status struct {
+	Vaccine string        `json:"vaccine"`
+	Name    string        `json:"name"`
+	Doses   []Dose_Status `json:"doses"`
+}
+
+type Immunization_Status struct {
+	ILNSID     string           `json:"ILNSID"`
+	DOB        string           `json:"DOB"`
+	As_Of      string           `json:"asOf"`
+	Up_To_Date bool             `json:"upToDate"`
+	Overdue    int              `json:"overdue"`
+	Upcoming   int              `json:"upcoming"`
+	Vaccines   []Vaccine_Status `json:"vaccines"`
+}
+
+//==============================================================================================================================
+//	 Overdue_Member / Overdue_Report - Result of get_overdue_members. Unassessed counts the members the caller may see
+//									   whose DOB they can not read, so whether they are overdue is unknown.
+//==============================================================================================================================
+type Overdue_Member struct {
+	ILNSID       string `json:"ILNSID"`
+	DOB          string `json:"DOB"`
+	Dose         int    `json:"dose"`
+	Due          string `json:"due"`
+	Days_Overdue int    `json:"daysOverdue"`
+}
+
+type Overdue_Report struct {
+	Vaccine    string           `json:"vaccine"`
+	As_Of      string           `json:"asOf"`
+	Members    []Overdue_Member `json:"members"`
+	Unassessed int              `json:"unassessed"`
+}
+
+//==============================================================================================================================
+//	 ImmunizationContract - Transactions that record and list the vaccinations given to members.
+//==============================================================================================================================
+type ImmunizationContract struct {
+	contractapi.Contract
+}
+
+//=================================================================================================================================
+//	 load_immunization_schedule - Stores the schedule of a vaccine, replacing any loaded before. Only administrators may
+//								  load reference data.
+//=================================================================================================================================
+func load_immunization_schedule(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, s Vaccine_Schedule) error {
+
+	if caller_affiliation != ADMIN {
+		return role_required("load_immunization_schedule", ADMIN, caller_affiliation)
+	}
+
+	if !vaccine_format.MatchString(s.Vaccine) {
+		return invalid("vaccine", s.Vaccine, VACCINE_REASON)
+	}
+
+	if len(s.Doses) == 0 || len(s.Doses) > MAX_DOSES {
+		return invalid("doses", "", "a schedule has between 1 and "+strconv.Itoa(MAX_DOSES)+" doses")
+	}
+
+	for i, d := range s.Doses {
+		if d.Dose != i+1 || d.Age_Days < 0 || d.Age_Days > MAX_SCHEDULE_AGE || d.Grace_Days < 0 || (i > 0 && d.Age_Days <= s.Doses[i-1].Age_Days) {
+			return invalid("doses", "", "doses must be numbered from 1 and due at ascending ages, with no negative grace period")
+		}
+	}
+
+	s.Schema_Version = schema_version(DOC_SCHEDULE)
+
+	bytes, err := json.Marshal(s)
+
+	if err != nil {
+		return internal("Error converting immunization schedule")
+	}
+
+	err = stub.PutState(schedule_key(s.Vaccine), bytes)
+
+	if err != nil {
+		return internal("Error storing immunization schedule")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 retrieve_schedule - Gets the schedule of a vaccine. Returns nil if none is loaded.
+//==============================================================================================================================
+func retrieve_schedule(stub shim.ChaincodeStubInterface, vaccine string) (*Vaccine_Schedule, error) {
+
+	var s Vaccine_Schedule
+
+	found, err := read_document(stub, DOC_SCHEDULE, schedule_key(vaccine), &s)
+
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+//==============================================================================================================================
+//	 retrieve_schedules - Every schedule loaded, in vaccine order.
+//==============================================================================================================================
+func retrieve_schedules(stub shim.ChaincodeStubInterface) ([]Vaccine_Schedule, error) {
+
+	keys, err := list_keys(stub, ENTRY_SCHEDULE)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []Vaccine_Schedule{}
+
+	for _, key := range keys {
+
+		var s Vaccine_Schedule
+
+		if _, err := read_document(stub, DOC_SCHEDULE, key, &s); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, nil
+}
+
+//==============================================================================================================================
+//	 retrieve_immunizations - Gets the immunizations of a member. Members without any get an empty history, callers who
+//							  may not read the member's details a redacted one, see read_clinical.
+//==============================================================================================================================
+func retrieve_immunizations(stub shim.ChaincodeStubInterface, m Member) (Immunization_History, error) {
+
+	history := Immunization_History{ILNSID: m.ILNSID, Immunizations: []Immunization{}}
+
+	readable, err := read_clinical(stub, m, DOC_IMMUNIZATIONS, immunizations_key(m.ILNSID), m.Immunizations_Hash, &history)
+
+	if err != nil {
+		return history, err
+	}
+
+	if !readable {
+		history.Immunizations = []Immunization{}
+		history.Redacted = true
+	}
+
+	return history, nil
+}
+
+//==============================================================================================================================
+//	 save_immunizations - Saves the immunizations of a member to its clinical collections and sets their hash on the member,
+//						  which the caller then saves. A redacted history may not be written.
+//==============================================================================================================================
+func save_immunizations(stub shim.ChaincodeStubInterface, m *Member, history Immunization_History) error {
+
+	if history.Redacted {
+		return details_denied("save_immunizations", *m)
+	}
+
+	history.Schema_Version = schema_version(DOC_IMMUNIZATIONS)
+
+	hash, err := save_clinical(stub, *m, DOC_IMMUNIZATIONS, immunizations_key(m.ILNSID), m.Immunizations_Hash, history)
+
+	if err != nil {
+		return err
+	}
+
+	m.Immunizations_Hash = hash
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 validate_immunization - Checks the fields of a dose and that it was given between the DOB of the member, when it can
+//							 be read, and now.
+//==============================================================================================================================
+func validate_immunization(m Member, i Immunization, now time.Time) error {
+
+	if !vaccine_format.MatchString(i.Vaccine) {
+		return invalid("vaccine", i.Vaccine, VACCINE_REASON)
+	}
+
+	if i.Dose < 1 || i.Dose > MAX_DOSES {
+		return invalid("dose", strconv.Itoa(i.Dose), "must be between 1 and "+strconv.Itoa(MAX_DOSES))
+	}
+
+	if strings.TrimSpace(i.Lot) == "" || len(i.Lot) > 64 {
+		return invalid("lot", i.Lot, "the lot number is required, at most 64 characters")
+	}
+
+	if strings.TrimSpace(i.Practitioner) == "" || len(i.Practitioner) > 128 {
+		return invalid("practitioner", i.Practitioner, "the administering practitioner is required, at most 128 characters")
+	}
+
+	site_ok := false
+
+	for _, site := range IMMUNIZATION_SITES {
+		site_ok = site_ok || i.Site == site
+	}
+
+	if !site_ok {
+		return invalid("site", i.Site, "must be one of "+strings.Join(IMMUNIZATION_SITES, ", "))
+	}
+
+	date, err := time.Parse(DOB_LAYOUT, i.Date)
+
+	if err != nil {
+		return invalid("date", i.Date, "must be an ISO-8601 date (YYYY-MM-DD)")
+	}
+
+	if date.After(now) {
+		return invalid("date", i.Date, "must not be in the future")
+	}
+
+	if dob, err := parse_DOB(m.DOB); err == nil && date.Before(dob) {
+		return invalid("date", i.Date, "must not be before the DOB of the member")
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 record_immunization - Adds a dose given to the member to its history in date order. The same people who may record
+//						   observations may record immunizations, and a dose of a vaccine is recorded once. The member
+//						   is saved with the new hash of its immunizations.
+//=================================================================================================================================
+func record_immunization(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, i Immunization) (*Immunization, error) {
+
+	if m.Erased {
+		return nil, erased_state(m)
+	}
+
+	if m.Dead {
+		return nil, invalid_state("record_immunization", m, -1)
+	}
+
+	if m.Name != caller || caller_affiliation == DEATH {
+		return nil, custodian_denied("record_immunization", m, caller, caller_affiliation)
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validate_immunization(m, i, now); err != nil {
+		return nil, err
+	}
+
+	history, err := retrieve_immunizations(stub, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if history.Redacted {
+		return nil, details_denied("record_immunization", m)
+	}
+
+	for _, given := range history.Immunizations {
+		if given.Vaccine == i.Vaccine && given.Dose == i.Dose {
+			return nil, conflict(fmt.Sprintf("Dose %d of %s is already recorded for %s", i.Dose, i.Vaccine, m.ILNSID), map[string]interface{}{"ILNSID": m.ILNSID, "vaccine": i.Vaccine, "dose": i.Dose})
+		}
+	}
+
+	i.Recorded_By = caller
+	i.Tx_ID = stub.GetTxID()
+
+	pos := sort.Search(len(history.Immunizations), func(n int) bool { return history.Immunizations[n].Date > i.Date })
+
+	history.Immunizations = append(history.Immunizations, Immunization{})
+	copy(history.Immunizations[pos+1:], history.Immunizations[pos:])
+	history.Immunizations[pos] = i
+
+	if err = save_immunizations(stub, &m, history); err != nil {
+		return nil, err
+	}
+
+	if err = save_changes(stub, m); err != nil {
+		fmt.Printf("RECORD_IMMUNIZATION: Error saving changes: %s", err)
+		return nil, save_failed(err)
+	}
+
+	return &i, nil
+}
+
+//=================================================================================================================================
+//	 list_immunizations - Returns every dose given to the member, filtered to vaccine unless it is empty. Visible to the
+//						  same callers as get_member_details.
+//=================================================================================================================================
+func list_immunizations(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, vaccine string) (*Immunization_History, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("list_immunizations", m)
+	}
+
+	history, err := retrieve_immunizations(stub, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if vaccine != "" {
+
+		filtered := []Immunization{}
+
+		for _, i := range history.Immunizations {
+			if i.Vaccine == vaccine {
+				filtered = append(filtered, i)
+			}
+		}
+
+		history.Immunizations = filtered
+	}
+
+	return &history, nil
+}
+
+//==============================================================================================================================
+//	 vaccine_status - Where each dose of the schedule stands for a member born on dob with the doses given, at now.
+//==============================================================================================================================
+func vaccine_status(s Vaccine_Schedule, dob time.Time, given []Immunization, now time.Time) Vaccine_Status {
+
+	status := Vaccine_Status{Vaccine: s.Vaccine, Name: s.Name, Doses: []Dose_Status{}}
+
+	for _, d := range s.Doses {
+
+		due := dob.AddDate(0, 0, d.Age_Days)
+		dose := Dose_Status{Dose: d.Dose, Due: due.Format(DOB_LAYOUT), Status: DOSE_UPCOMING}
+
+		for n := range given {
+			if given[n].Vaccine == s.Vaccine && given[n].Dose == d.Dose {
+				dose.Given = &given[n]
+			}
+		}
+
+		switch {
+		case dose.Given != nil:
+			dose.Status = DOSE_GIVEN
+		case !now.Before(due.AddDate(0, 0, d.Grace_Days+1)): // The whole of the last day of grace has passed
+			dose.Status = DOSE_OVERDUE
+		case !now.Before(due):
+			dose.Status = DOSE_DUE
+		}
+
+		status.Doses = append(status.Doses, dose)
+	}
+
+	return status
+}
+
+//==============================================================================================================================
+//	 immunization_dob - The DOB of m, for assessing it against the schedule.
+//==============================================================================================================================
+func immunization_dob(m Member) (time.Time, error) {
+
+	if m.DOB == ENCRYPTED {
+		return time.Time{}, invalid("DOB", m.DOB, "the DOB is encrypted, pass its data key in "+TRANSIENT_DATA_KEYS)
+	}
+
+	dob, err := parse_DOB(m.DOB)
+
+	if err != nil {
+		return dob, invalid("DOB", m.DOB, "a DOB is required to assess immunizations against the schedule")
+	}
+
+	return dob, nil
+}
+
+//=================================================================================================================================
+//	 get_immunization_status - Assesses the member's immunizations against every schedule loaded, reporting the doses
+//							   given, due, overdue and upcoming at the time of the query. Visible to the same callers as
+//							   get_member_details.
+//=================================================================================================================================
+func get_immunization_status(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) (*Immunization_Status, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("get_immunization_status", m)
+	}
+
+	if m.Erased {
+		return nil, erased_state(m)
+	}
+
+	if m.Redacted {
+		return nil, details_denied("get_immunization_status", m)
+	}
+
+	dob, err := immunization_dob(m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := retrieve_schedules(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := retrieve_immunizations(stub, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := Immunization_Status{ILNSID: m.ILNSID, DOB: m.DOB, As_Of: now.Format(time.RFC3339), Vaccines: []Vaccine_Status{}}
+
+	for _, s := range schedules {
+
+		status := vaccine_status(s, dob, history.Immunizations, now)
+
+		for _, d := range status.Doses {
+			switch d.Status {
+			case DOSE_OVERDUE:
+				report.Overdue++
+			case DOSE_DUE, DOSE_UPCOMING:
+				report.Upcoming++
+			}
+		}
+
+		report.Vaccines = append(report.Vaccines, status)
+	}
+
+	report.Up_To_Date = report.Overdue == 0
+
+	return &report, nil
+}
+
+//=================================================================================================================================
+//	 get_overdue_members - Lists the living members the caller may see that are overdue for a dose of the vaccine, with
+//						   the first dose they are missing. Members whose DOB the caller can not read are counted as
+//						   unassessed.
+//=================================================================================================================================
+func get_overdue_members(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, vaccine string) (*Overdue_Report, error) {
+
+	s, err := retrieve_schedule(stub, vaccine)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		return nil, not_found("No schedule is loaded for "+vaccine, map[string]interface{}{"vaccine": vaccine})
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := get_members(stub, caller, caller_affiliation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := Overdue_Report{Vaccine: vaccine, As_Of: now.Format(time.RFC3339), Members: []Overdue_Member{}}
+
+	for _, m := range members {
+
+		if m.Dead || m.Erased {
+			continue
+		}
+
+		dob, err := immunization_dob(m)
+
+		if m.Redacted || err != nil {
+			report.Unassessed++
+			continue
+		}
+
+		history, err := retrieve_immunizations(stub, m)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range vaccine_status(*s, dob, history.Immunizations, now).Doses {
+
+			if d.Status != DOSE_OVERDUE {
+				continue
+			}
+
+			due, _ := time.Parse(DOB_LAYOUT, d.Due)
+
+			report.Members = append(report.Members, Overdue_Member{ILNSID: m.ILNSID, DOB: m.DOB, Dose: d.Dose, Due: d.Due, Days_Overdue: int(now.Sub(due).Hours() / 24)})
+
+			break
+		}
+	}
+
+	return &report, nil
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 RecordImmunization - Adds a dose of a vaccine given to the member.
+//=================================================================================================================================
+func (c *ImmunizationContract) RecordImmunization(ctx contractapi.TransactionContextInterface, ILNSID string, immunization Immunization) (*Immunization, error) {
+
+	var recorded *Immunization
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		recorded, err = record_immunization(stub, m, caller, caller_affiliation, immunization)
+		return err
+	})
+
+	return recorded, err
+}
+
+//=================================================================================================================================
+//	 ListImmunizations - Returns the doses given to the member, filtered to vaccine unless it is empty.
+//=================================================================================================================================
+func (c *ImmunizationContract) ListImmunizations(ctx contractapi.TransactionContextInterface, ILNSID string, vaccine string) (*Immunization_History, error) {
+
+	var history *Immunization_History
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		history, err = list_immunizations(stub, m, caller, caller_affiliation, vaccine)
+		return err
+	})
+
+	return history, err
+}
+
+//=================================================================================================================================
+//	 GetEvaluateTransactions - ListImmunizations is read only and is evaluated rather than submitted.
+//=================================================================================================================================
+func (c *ImmunizationContract) GetEvaluateTransactions() []string {
+	return []string{"ListImmunizations"}
+}
@@ -0,0 +1,364 @@
+package chaincode
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Import modes - ALL_OR_NOTHING writes nothing if any row is rejected, BEST_EFFORT creates every valid row and reports
+//					the rest.
+//==============================================================================================================================
+const ALL_OR_NOTHING = "all_or_nothing"
+const BEST_EFFORT = "best_effort"
+
+const MAX_IMPORT_ROWS = 500
+
+//==============================================================================================================================
+//	 Import_Record - One member to be created. Optional fields may be left empty and are then UNDEFINED as for
+//					 create_member. In CSV the header names the columns and parents are separated by ';'.
+//==============================================================================================================================
+type Import_Record struct {
+	ILNSID   string   `json:"ILNSID"`
+	DOB      string   `json:"DOB"`
+	Gender   string   `json:"gender"`
+	BloodGrp string   `json:"BloodGrp"`
+	Weight   string   `json:"Weight"`
+	Parents  []string `json:"parents"`
+}
+
+//==============================================================================================================================
+//	 Import_Row / Import_Report - Per-row result of an import. Row numbers start at 1 and exclude any CSV header.
+//==============================================================================================================================
+type Import_Row struct {
+	Row    int    `json:"row"`
+	ILNSID string `json:"ILNSID"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty" metadata:",optional"`
+}
+
+type Import_Report struct {
+	Tx_ID          string       `json:"txID"`
+	Mode           string       `json:"mode"`
+	Created        int          `json:"created"`
+	Rejected       int          `json:"rejected"`
+	Rows           []Import_Row `json:"rows"`
+	Schema_Version int          `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 parse_import_records - Reads the records from either a JSON array or CSV with a header row.
+//==============================================================================================================================
+func parse_import_records(data string) ([]Import_Record, error) {
+
+	var records []Import_Record
+
+	if strings.HasPrefix(strings.TrimSpace(data), "[") {
+
+		err := json.Unmarshal([]byte(data), &records)
+
+		if err != nil {
+			return nil, invalid("records", "", "invalid JSON array of records: "+err.Error())
+		}
+
+		return records, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		return nil, invalid("records", "", "records must be a JSON array or CSV with a header row")
+	}
+
+	columns := map[string]int{}
+
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := columns["ILNSID"]; !ok {
+		return nil, invalid("records", "", "CSV header must include an ILNSID column")
+	}
+
+	for {
+		row, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, invalid("records", "", "invalid CSV: "+err.Error())
+		}
+
+		column := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		r := Import_Record{ILNSID: column("ILNSID"), DOB: column("DOB"), Gender: column("gender"), BloodGrp: column("BloodGrp"), Weight: column("Weight")}
+
+		if parents := column("parents"); parents != "" {
+			r.Parents = strings.Split(parents, ";")
+		}
+
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+//==============================================================================================================================
+//	 build_import_member - Validates a record and builds the member it describes, with the series of its first weight.
+//						   Each field is applied as update_member applies it, so the importing parent may set the
+//						   gender and Weight but a DOB or BloodGrp is rejected as it would be for them interactively.
+//						   batch holds the members built from earlier rows so that they can be used as parents and so
+//						   duplicates are caught. The ILNSID must be under the prefix of caller_org, as in create_member.
+//==============================================================================================================================
+func build_import_member(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, caller_org string, r Import_Record, batch map[string]Member) (Member, Vitals_Series, error) {
+
+	m := Member{Name: caller, ILNSID: r.ILNSID, DOB: UNDEFINED, Gender: UNDEFINED, BloodGrp: UNDEFINED, Weight: Weight{Unit: "kg"}, Status: STATE_CARRYING, Parents: r.Parents, Guardians: []string{caller}}
+	series := Vitals_Series{ILNSID: r.ILNSID, Observations: []Observation{}}
+
+	err := validate_ILNSID(r.ILNSID)
+
+	if err != nil {
+		return m, series, err
+	}
+
+	if err = check_ILNSID_prefix(stub, r.ILNSID, caller_org); err != nil {
+		return m, series, err
+	}
+
+	if _, ok := batch[r.ILNSID]; ok {
+		return m, series, invalid("ILNSID", r.ILNSID, "duplicated within the import")
+	}
+
+	record, err := stub.GetState(member_key(r.ILNSID))
+
+	if err != nil {
+		return m, series, internal("Error checking ILNSID " + r.ILNSID)
+	}
+
+	if record != nil {
+		return m, series, invalid("ILNSID", r.ILNSID, "member already exists")
+	}
+
+	lookup := func(ILNSID string) (Member, error) {
+		if parent, ok := batch[ILNSID]; ok {
+			return parent, nil
+		}
+		return retrieve_ILNS(stub, ILNSID)
+	}
+
+	for _, parent_ID := range r.Parents {
+		if _, err := lookup(parent_ID); err != nil {
+			return m, series, invalid("parents", parent_ID, "parent member does not exist")
+		}
+	}
+
+	if r.DOB != "" {
+		if err := apply_DOB_with(stub, &m, caller, caller_affiliation, r.DOB, lookup); err != nil {
+			return m, series, err
+		}
+	}
+
+	if r.Gender != "" {
+		if err := apply_gender(stub, &m, caller, caller_affiliation, r.Gender); err != nil {
+			return m, series, err
+		}
+	}
+
+	if r.BloodGrp != "" {
+		if err := apply_BloodGrp(stub, &m, caller, caller_affiliation, r.BloodGrp); err != nil {
+			return m, series, err
+		}
+	}
+
+	if r.Weight != "" {
+		if err := apply_Weight(stub, &m, &series, caller, caller_affiliation, r.Weight); err != nil {
+			return m, series, err
+		}
+	}
+
+	return m, series, nil
+}
+
+//==============================================================================================================================
+//	 run_import - Validates every record and, unless dry_run is set, creates the members allowed by the mode. The
+//				  ILNSIDs index is read and written once for the whole batch.
+//==============================================================================================================================
+func run_import(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, caller_org string, records_data string, mode string, dry_run bool) (Import_Report, error) {
+
+	report := Import_Report{Tx_ID: stub.GetTxID(), Mode: mode, Rows: []Import_Row{}, Schema_Version: schema_version(DOC_IMPORT_REPORT)}
+
+	if caller_affiliation != PARENTS {
+		return report, role_required("import_members", PARENTS, caller_affiliation)
+	}
+
+	if mode != ALL_OR_NOTHING && mode != BEST_EFFORT {
+		return report, invalid("mode", mode, "must be "+ALL_OR_NOTHING+" or "+BEST_EFFORT)
+	}
+
+	records, err := parse_import_records(records_data)
+
+	if err != nil {
+		return report, err
+	}
+
+	if len(records) == 0 || len(records) > MAX_IMPORT_ROWS {
+		return report, invalid("records", fmt.Sprint(len(records)), fmt.Sprintf("an import must contain between 1 and %d records", MAX_IMPORT_ROWS))
+	}
+
+	batch := map[string]Member{}
+	created := []Member{}
+	vitals := map[string]Vitals_Series{}
+
+	for i, r := range records {
+
+		row := Import_Row{Row: i + 1, ILNSID: r.ILNSID, Status: "created"}
+
+		m, series, err := build_import_member(stub, caller, caller_affiliation, caller_org, r, batch)
+
+		if ve, ok := err.(*Validation_Error); ok {
+			row.Status, row.Error = "rejected", ve.Field+": "+ve.Reason
+		} else if err != nil {
+			row.Status, row.Error = "rejected", error_message(err)
+		}
+
+		if err == nil {
+			batch[m.ILNSID] = m
+			vitals[m.ILNSID] = series
+			created = append(created, m)
+			report.Created++
+		} else {
+			report.Rejected++
+		}
+
+		report.Rows = append(report.Rows, row)
+	}
+
+	if report.Rejected > 0 && mode == ALL_OR_NOTHING {
+
+		for i := range report.Rows {
+			if report.Rows[i].Status == "created" {
+				report.Rows[i].Status = "not_created"
+			}
+		}
+
+		report.Created = 0
+
+		return report, nil
+	}
+
+	if dry_run || len(created) == 0 {
+		return report, nil
+	}
+
+	ILNSIDs, err := retrieve_ILNS_holder(stub)
+
+	if err != nil {
+		return report, err
+	}
+
+	for _, m := range created {
+
+		m.Custodian_Org = caller_org
+		m.Treating_Orgs = add_treating_org(m.Treating_Orgs, caller_org)
+
+		if series := vitals[m.ILNSID]; len(series.Observations) > 0 {
+			if err = save_vitals(stub, &m, series); err != nil {
+				return report, err
+			}
+		}
+
+		err = save_changes(stub, m)
+
+		if err != nil {
+			fmt.Printf("IMPORT_MEMBERS: Error saving changes: %s", err)
+			return report, save_failed(err)
+		}
+
+		ILNSIDs.ILNSs = append(ILNSIDs.ILNSs, m.ILNSID)
+	}
+
+	err = save_ILNS_holder(stub, ILNSIDs)
+
+	return report, err
+}
+
+//=================================================================================================================================
+//	 import_members - Creates members in bulk from a chunk of up to MAX_IMPORT_ROWS JSON or CSV records. Only the parents
+//					  may import. The per-row report is returned and also stored under IMPORT_<txID> so that it can be
+//					  read back with get_import_report. In all_or_nothing mode a rejected row fails the whole import.
+//=================================================================================================================================
+func import_members(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, caller_org string, records_data string, mode string) (*Import_Report, error) {
+
+	report, err := run_import(stub, caller, caller_affiliation, caller_org, records_data, mode, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := json.Marshal(report)
+
+	if err != nil {
+		return nil, internal("Error converting import report")
+	}
+
+	if report.Rejected > 0 && mode == ALL_OR_NOTHING {
+		return nil, &Chaincode_Error{Code: VALIDATION_FAILED, Message: "Import rejected", Details: map[string]interface{}{"report": report}}
+	}
+
+	err = stub.PutState(import_report_key(report.Tx_ID), bytes)
+
+	if err != nil {
+		return nil, internal("Error storing import report")
+	}
+
+	return &report, nil
+}
+
+//=================================================================================================================================
+//	 check_import - Query that validates records exactly as import_members would without creating anything.
+//=================================================================================================================================
+func check_import(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, records_data string, mode string) (*Import_Report, error) {
+
+	report, err := run_import(stub, caller, caller_affiliation, "", records_data, mode, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+//=================================================================================================================================
+//	 get_import_report - Returns the report stored by the import_members transaction with the ID given.
+//=================================================================================================================================
+func get_import_report(stub shim.ChaincodeStubInterface, caller_affiliation string, tx_ID string) (*Import_Report, error) {
+
+	if caller_affiliation != PARENTS {
+		return nil, role_required("get_import_report", PARENTS, caller_affiliation)
+	}
+
+	var report Import_Report
+
+	found, err := read_document(stub, DOC_IMPORT_REPORT, import_report_key(tx_ID), &report)
+
+	if err != nil || !found {
+		return nil, not_found("No import report for transaction "+tx_ID, map[string]interface{}{"tx_ID": tx_ID})
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,420 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Keys - Every entry is stored under a key naming its type, which is its export entry type, followed by the attributes
+//			that identify it, separated by colons: member:AB12345679, participant:bob, consent:AB12345679:gina. Each type
+//			has a namespace of its own so a user name can not collide with a member or an index, and as only the last
+//			attribute of a key may contain a colon (the others are ILNSIDs, indicators or index names) keys of one type
+//			do not collide with each other either. Keys are only ever built with the functions below.
+//==============================================================================================================================
+const KEY_SEPARATOR = ":"
+
+const INDEX_ILNSIDS = "ILNSIDs"
+const INDEX_PARTICIPANTS = "Participants"
+
+var KEY_ATTRIBUTES = map[string]int{
+	ENTRY_INDEX:            1,
+	ENTRY_PARTICIPANT:      1,
+	ENTRY_MEMBER:           1,
+	ENTRY_VITALS:           1,
+	ENTRY_CONSENT:          2,
+	ENTRY_GROWTH_REFERENCE: 2,
+	ENTRY_IMPORT_REPORT:    1,
+	ENTRY_ID_SEQUENCE:      1,
+	ENTRY_ORG_KEY:          1,
+	ENTRY_DATA_KEY:         1,
+	ENTRY_DETAILS:          1,
+	ENTRY_ATTACHMENT:       2,
+	ENTRY_CREDENTIAL:       2,
+	ENTRY_ISSUER_KEY:       1,
+	ENTRY_IMMUNIZATIONS:    1,
+	ENTRY_SCHEDULE:         1,
+	ENTRY_HISTORY:          1,
+}
+
+//==============================================================================================================================
+//	 Key - The key of the entry of entry_type identified by attributes.
+//==============================================================================================================================
+func Key(entry_type string, attributes ...string) string {
+	return strings.Join(append([]string{entry_type}, attributes...), KEY_SEPARATOR)
+}
+
+//==============================================================================================================================
+//	 SplitKey - The entry type and attributes of a key. ok is false if the key is not in a known namespace.
+//==============================================================================================================================
+func SplitKey(key string) (string, []string, bool) {
+
+	entry_type, rest, found := strings.Cut(key, KEY_SEPARATOR)
+	n, known := KEY_ATTRIBUTES[entry_type]
+
+	if !found || !known {
+		return "", nil, false
+	}
+
+	attributes := strings.SplitN(rest, KEY_SEPARATOR, n)
+
+	if len(attributes) != n {
+		return "", nil, false
+	}
+
+	return entry_type, attributes, true
+}
+
+func index_key(name string) string {
+	return Key(ENTRY_INDEX, name)
+}
+
+func participant_key(name string) string {
+	return Key(ENTRY_PARTICIPANT, name)
+}
+
+func member_key(ILNSID string) string {
+	return Key(ENTRY_MEMBER, ILNSID)
+}
+
+func vitals_key(ILNSID string) string {
+	return Key(ENTRY_VITALS, ILNSID)
+}
+
+func consent_key(ILNSID string, grantee string) string {
+	return Key(ENTRY_CONSENT, ILNSID, grantee)
+}
+
+func growth_reference_key(indicator string, sex string) string {
+	return Key(ENTRY_GROWTH_REFERENCE, indicator, sex)
+}
+
+func import_report_key(tx_ID string) string {
+	return Key(ENTRY_IMPORT_REPORT, tx_ID)
+}
+
+func id_sequence_key(org string) string {
+	return Key(ENTRY_ID_SEQUENCE, org)
+}
+
+func org_key_key(org string) string {
+	return Key(ENTRY_ORG_KEY, org)
+}
+
+func data_key_key(ILNSID string) string {
+	return Key(ENTRY_DATA_KEY, ILNSID)
+}
+
+func details_key(ILNSID string) string {
+	return Key(ENTRY_DETAILS, ILNSID)
+}
+
+func attachment_key(ILNSID string, hash string) string {
+	return Key(ENTRY_ATTACHMENT, ILNSID, hash)
+}
+
+func credential_key(ILNSID string, tx_ID string) string {
+	return Key(ENTRY_CREDENTIAL, ILNSID, tx_ID)
+}
+
+func issuer_key_key(org string) string {
+	return Key(ENTRY_ISSUER_KEY, org)
+}
+
+func immunizations_key(ILNSID string) string {
+	return Key(ENTRY_IMMUNIZATIONS, ILNSID)
+}
+
+func schedule_key(vaccine string) string {
+	return Key(ENTRY_SCHEDULE, vaccine)
+}
+
+func history_key(ILNSID string) string {
+	return Key(ENTRY_HISTORY, ILNSID)
+}
+
+//==============================================================================================================================
+//	 list_keys - Lists in key order the keys of entry_type whose leading attributes are those given, e.g. every consent
+//				 on a member with list_keys(stub, ENTRY_CONSENT, ILNSID).
+//==============================================================================================================================
+func list_keys(stub shim.ChaincodeStubInterface, entry_type string, attributes ...string) ([]string, error) {
+
+	prefix := Key(entry_type, attributes...) + KEY_SEPARATOR
+
+	iter, err := stub.GetStateByRange(prefix, prefix+string(utf8.MaxRune))
+
+	if err != nil {
+		return nil, internal("Unable to list " + entry_type + " entries")
+	}
+
+	defer iter.Close()
+
+	keys := []string{}
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			return nil, internal("Unable to list " + entry_type + " entries")
+		}
+
+		keys = append(keys, kv.Key)
+	}
+
+	return keys, nil
+}
+
+//==============================================================================================================================
+//	 document_key - The key a document of entry_type belongs under, built from the fields of the document that identify
+//					it. key is where the document was found and is only used to upgrade it. Indexes and participants
+//					carry no identifying fields and have no document key.
+//==============================================================================================================================
+func document_key(entry_type string, key string, value []byte) (string, error) {
+
+	value, _, err := upgrade_document(entry_doc_type(entry_type, key), key, value)
+
+	if err != nil {
+		return "", err
+	}
+
+	var doc struct {
+		ILNSID    string `json:"ILNSID"`
+		Grantee   string `json:"grantee"`
+		Indicator string `json:"indicator"`
+		Sex       string `json:"sex"`
+		Tx_ID     string `json:"txID"`
+		Org       string `json:"org"`
+		SHA256    string `json:"sha256"`
+		Vaccine   string `json:"vaccine"`
+	}
+
+	if err = json.Unmarshal(value, &doc); err != nil {
+		return "", internal("Corrupt " + entry_type + " record " + key)
+	}
+
+	var attributes []string
+
+	switch entry_type {
+	case ENTRY_MEMBER, ENTRY_VITALS, ENTRY_DETAILS, ENTRY_DATA_KEY, ENTRY_IMMUNIZATIONS, ENTRY_HISTORY:
+		attributes = []string{doc.ILNSID}
+	case ENTRY_CONSENT:
+		attributes = []string{doc.ILNSID, doc.Grantee}
+	case ENTRY_ATTACHMENT:
+		attributes = []string{doc.ILNSID, doc.SHA256}
+	case ENTRY_CREDENTIAL:
+		attributes = []string{doc.ILNSID, doc.Tx_ID}
+	case ENTRY_GROWTH_REFERENCE:
+		attributes = []string{doc.Indicator, doc.Sex}
+	case ENTRY_SCHEDULE:
+		attributes = []string{doc.Vaccine}
+	case ENTRY_IMPORT_REPORT:
+		attributes = []string{doc.Tx_ID}
+	case ENTRY_ID_SEQUENCE, ENTRY_ORG_KEY, ENTRY_ISSUER_KEY:
+		attributes = []string{doc.Org}
+	default:
+		return "", internal(entry_type + " entries have no document key")
+	}
+
+	for _, attribute := range attributes {
+		if attribute == "" {
+			return "", invalid("key", key, "the "+entry_type+" record does not say which it is")
+		}
+	}
+
+	return Key(entry_type, attributes...), nil
+}
+
+//==============================================================================================================================
+//	 Legacy keys - Before namespaces members were stored under their ILNSID, eCerts under the user's name, the indexes
+//				   under ILNSIDs and Participants and everything else under one of these prefixes. They are moved to
+//				   their namespaced keys by rekey_state.
+//==============================================================================================================================
+var LEGACY_PREFIXES = map[string]string{
+	"CONSENT_":     ENTRY_CONSENT,
+	"GROWTH_REF_":  ENTRY_GROWTH_REFERENCE,
+	"ID_SEQUENCE_": ENTRY_ID_SEQUENCE,
+	"IMPORT_":      ENTRY_IMPORT_REPORT,
+	"VITALS_":      ENTRY_VITALS,
+}
+
+const DEFAULT_REKEY_BATCH = 100
+const MAX_REKEY_BATCH = 1000
+
+//==============================================================================================================================
+//	 legacy_names - The names listed by a legacy index. The index is read from its legacy key, or from its new key if an
+//					earlier batch has already moved it.
+//==============================================================================================================================
+func legacy_names(stub shim.ChaincodeStubInterface, name string) (map[string]bool, error) {
+
+	value, err := stub.GetState(name)
+
+	if err == nil && value == nil {
+		value, err = stub.GetState(index_key(name))
+	}
+
+	if err != nil {
+		return nil, internal("Unable to read the " + name + " index")
+	}
+
+	var index struct {
+		ILNSs []string `json:"ILNSs"`
+		Names []string `json:"names"`
+	}
+
+	names := map[string]bool{}
+
+	if value != nil && json.Unmarshal(value, &index) != nil {
+		return nil, internal("Corrupt " + name + " index")
+	}
+
+	for _, n := range append(index.ILNSs, index.Names...) {
+		names[n] = true
+	}
+
+	return names, nil
+}
+
+//==============================================================================================================================
+//	 legacy_key - The namespaced key of an entry stored under a legacy key. ok is false if the legacy key is not one the
+//				  chaincode wrote.
+//==============================================================================================================================
+func legacy_key(key string, value []byte, members map[string]bool, participants map[string]bool) (string, bool, error) {
+
+	if key == INDEX_ILNSIDS || key == INDEX_PARTICIPANTS {
+		return index_key(key), true, nil
+	}
+
+	for prefix, entry_type := range LEGACY_PREFIXES {
+		if strings.HasPrefix(key, prefix) {
+			new_key, err := document_key(entry_type, key, value)
+			return new_key, err == nil, nil
+		}
+	}
+
+	if participants[key] {
+		return participant_key(key), true, nil
+	}
+
+	if members[key] {
+		return member_key(key), true, nil
+	}
+
+	return "", false, nil
+}
+
+//==============================================================================================================================
+//	 Rekey_Result - Returned by rekey_state. Next is the bookmark to pass for the following batch and is empty once every
+//					key has been checked. Unknown lists legacy keys the chaincode did not write, Conflicts those whose
+//					namespaced key already holds a different value. Both are left where they are.
+//==============================================================================================================================
+type Rekey_Result struct {
+	Checked   int      `json:"checked"`
+	Moved     int      `json:"moved"`
+	Unknown   []string `json:"unknown"`
+	Conflicts []string `json:"conflicts"`
+	Next      string   `json:"next"`
+}
+
+//=================================================================================================================================
+//	 rekey_state - Moves entries stored under legacy keys to their namespaced keys in batches of keys in key order,
+//				   starting at the bookmark. Keys already in a namespace are counted but left alone, so the migration may
+//				   be run again safely. Only administrators may re-key the ledger.
+//=================================================================================================================================
+func rekey_state(stub shim.ChaincodeStubInterface, caller_affiliation string, bookmark string, batch_size string) (*Rekey_Result, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("rekey_state", ADMIN, caller_affiliation)
+	}
+
+	size := DEFAULT_REKEY_BATCH
+
+	if batch_size != "" {
+		n, err := strconv.Atoi(batch_size)
+		if err != nil || n < 1 || n > MAX_REKEY_BATCH {
+			return nil, invalid("batch_size", batch_size, fmt.Sprintf("must be between 1 and %d", MAX_REKEY_BATCH))
+		}
+		size = n
+	}
+
+	members, err := legacy_names(stub, INDEX_ILNSIDS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := legacy_names(stub, INDEX_PARTICIPANTS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := stub.GetStateByRange(bookmark, "")
+
+	if err != nil {
+		return nil, internal("Unable to list the ledger")
+	}
+
+	defer iter.Close()
+
+	result := Rekey_Result{Unknown: []string{}, Conflicts: []string{}}
+
+	for iter.HasNext() {
+
+		kv, err := iter.Next()
+
+		if err != nil {
+			return nil, internal("Unable to list the ledger")
+		}
+
+		if result.Checked == size {
+			result.Next = kv.Key
+			break
+		}
+
+		result.Checked++
+
+		if _, _, ok := SplitKey(kv.Key); ok {
+			continue
+		}
+
+		new_key, ok, err := legacy_key(kv.Key, kv.Value, members, participants)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			result.Unknown = append(result.Unknown, kv.Key)
+			continue
+		}
+
+		current, err := stub.GetState(new_key)
+
+		if err != nil {
+			return nil, internal("Unable to read " + new_key)
+		}
+
+		if current != nil && string(current) != string(kv.Value) {
+			result.Conflicts = append(result.Conflicts, kv.Key)
+			continue
+		}
+
+		if err = stub.PutState(new_key, kv.Value); err != nil {
+			return nil, internal("Unable to put the state")
+		}
+
+		if err = stub.DelState(kv.Key); err != nil {
+			return nil, internal("Unable to delete " + kv.Key)
+		}
+
+		result.Moved++
+	}
+
+	return &result, nil
+}
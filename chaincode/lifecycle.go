@@ -0,0 +1,337 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 LifecycleContract - Transactions that pass a member from one custodian to the next as it moves through its statuses.
+//==============================================================================================================================
+type LifecycleContract struct {
+	contractapi.Contract
+}
+
+//=================================================================================================================================
+//	 check_transfer - A transfer applies to a living member in status, held by the caller who must hold role, and passes
+//					  it to a recipient holding recipient_role. The state is checked first so that a transfer out of the
+//					  wrong status is reported as such whoever asks.
+//=================================================================================================================================
+func check_transfer(function string, m Member, caller string, caller_affiliation string, recipient_affiliation string, status int, role string, recipient_role string) error {
+
+	if m.Dead || m.Status != status {
+		return invalid_state(function, m, status)
+	}
+
+	if m.Name != caller || caller_affiliation != role {
+		return role_denied(function, m, caller, caller_affiliation, role)
+	}
+
+	if recipient_affiliation != recipient_role {
+		return permission_denied(function, map[string]interface{}{
+			"ILNSID":                  m.ILNSID,
+			"required_recipient_role": recipient_role,
+			"recipient_role":          recipient_affiliation,
+		})
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 undefined_fields - The demographic fields of m that have not been set yet.
+//=================================================================================================================================
+func undefined_fields(m Member) []string {
+
+	undefined := []string{}
+
+	if m.DOB == "UNDEFINED" {
+		undefined = append(undefined, "DOB")
+	}
+
+	if m.Gender == "UNDEFINED" {
+		undefined = append(undefined, "Gender")
+	}
+
+	if m.BloodGrp == "UNDEFINED" {
+		undefined = append(undefined, "BloodGrp")
+	}
+
+	if m.Weight.Value == 0 {
+		undefined = append(undefined, "Weight")
+	}
+
+	return undefined
+}
+
+//=================================================================================================================================
+//	 Transfer Functions
+//=================================================================================================================================
+//	 parents_to_birthday
+//=================================================================================================================================
+func parents_to_birthday(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("parents_to_birthday", *m, caller, caller_affiliation, recipient_affiliation, STATE_CARRYING, PARENTS, BIRTHDAY)
+
+	if err != nil {
+		fmt.Printf("PARENTS_TO_BIRTHDAY: %s", err)
+		return err
+	}
+
+	m.Name = recipient_name // then make the owner the new owner
+	m.Status = STATE_BIRTH  // and mark it as born
+
+	err = save_changes(stub, *m) // Write new state
+
+	if err != nil {
+		fmt.Printf("PARENTS_TO_BIRTHDAY: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil // We are Done
+
+}
+
+//=================================================================================================================================
+//	 birthday_to_healthy
+//=================================================================================================================================
+func birthday_to_healthy(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("birthday_to_healthy", *m, caller, caller_affiliation, recipient_affiliation, STATE_BIRTH, BIRTHDAY, HEALTHY)
+
+	if err != nil {
+		fmt.Printf("BIRTHDAY_TO_HEALTHY: %s", err)
+		return err
+	}
+
+	undefined := undefined_fields(*m)
+
+	if len(undefined) > 0 { //If any detail of the member is undefined it has not been fully updated so cannot be sent
+		fmt.Printf("BIRTHDAY_TO_HEALTHY: Member not fully defined")
+		return &Chaincode_Error{Code: INVALID_STATE, Message: "Member not fully defined", Details: map[string]interface{}{"ILNSID": m.ILNSID, "undefined_fields": undefined}}
+	}
+
+	m.Name = recipient_name
+	m.Status = STATE_HEALTHY
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("BIRTHDAY_TO_HEALTHY: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 healthy_to_illness
+//=================================================================================================================================
+func healthy_to_illness(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("healthy_to_illness", *m, caller, caller_affiliation, recipient_affiliation, STATE_HEALTHY, HEALTHY, ILLNESS)
+
+	if err != nil {
+		fmt.Printf("HEALTHY_TO_ILLNESS: %s", err)
+		return err
+	}
+
+	m.Name = recipient_name
+	m.Status = STATE_ILLNESS
+
+	err = save_changes(stub, *m)
+	if err != nil {
+		fmt.Printf("HEALTHY_TO_ILLNESS: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 illness_to_illness
+//=================================================================================================================================
+func illness_to_illness(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("illness_to_illness", *m, caller, caller_affiliation, recipient_affiliation, STATE_ILLNESS, ILLNESS, ILLNESS)
+
+	if err != nil {
+		fmt.Printf("ILLNESS_TO_ILLNESS: %s", err)
+		return err
+	}
+
+	m.Name = recipient_name
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("ILLNESS_TO_ILLNESS: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 illness_to_healthy
+//=================================================================================================================================
+func illness_to_healthy(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("illness_to_healthy", *m, caller, caller_affiliation, recipient_affiliation, STATE_ILLNESS, ILLNESS, HEALTHY)
+
+	if err != nil {
+		fmt.Printf("ILLNESS_TO_HEALTHY: %s", err)
+		return err
+	}
+
+	m.Name = recipient_name
+	m.Status = STATE_HEALTHY
+
+	err = save_changes(stub, *m)
+	if err != nil {
+		fmt.Printf("ILLNESS_TO_HEALTHY: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 healthy_to_death
+//=================================================================================================================================
+func healthy_to_death(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("healthy_to_death", *m, caller, caller_affiliation, recipient_affiliation, STATE_HEALTHY, HEALTHY, DEATH)
+
+	if err != nil {
+		fmt.Printf("HEALTHY_TO_DEATH: %s", err)
+		return err
+	}
+
+	m.Name = recipient_name
+	m.Status = STATE_DEATH
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("HEALTHY_TO_DEATH: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 illness_to_death
+//=================================================================================================================================
+func illness_to_death(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, recipient_name string, recipient_affiliation string) error {
+
+	err := check_transfer("illness_to_death", *m, caller, caller_affiliation, recipient_affiliation, STATE_ILLNESS, ILLNESS, DEATH)
+
+	if err != nil {
+		fmt.Printf("ILLNESS_TO_DEATH: %s", err)
+		return err
+	}
+
+	m.Name = recipient_name
+	m.Status = STATE_DEATH
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("ILLNESS_TO_DEATH: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 dead_member
+//=================================================================================================================================
+func dead_member(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+
+	if m.Status != STATE_DEATH || m.Dead {
+		return invalid_state("dead_member", *m, STATE_DEATH)
+	}
+
+	if m.Name != caller || caller_affiliation != DEATH {
+		return role_denied("dead_member", *m, caller, caller_affiliation, DEATH)
+	}
+
+	m.Dead = true
+
+	err := save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("DEAD_MEMBER: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 Each transfer passes the member to recipient, who must hold the role the transfer is named after.
+//=================================================================================================================================
+func (c *LifecycleContract) ParentsToBirthday(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return parents_to_birthday(stub, m, caller, caller_affiliation, recipient, BIRTHDAY)
+	})
+}
+
+func (c *LifecycleContract) BirthdayToHealthy(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return birthday_to_healthy(stub, m, caller, caller_affiliation, recipient, HEALTHY)
+	})
+}
+
+func (c *LifecycleContract) HealthyToIllness(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return healthy_to_illness(stub, m, caller, caller_affiliation, recipient, ILLNESS)
+	})
+}
+
+func (c *LifecycleContract) IllnessToIllness(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return illness_to_illness(stub, m, caller, caller_affiliation, recipient, ILLNESS)
+	})
+}
+
+func (c *LifecycleContract) IllnessToHealthy(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return illness_to_healthy(stub, m, caller, caller_affiliation, recipient, HEALTHY)
+	})
+}
+
+func (c *LifecycleContract) HealthyToDeath(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return healthy_to_death(stub, m, caller, caller_affiliation, recipient, DEATH)
+	})
+}
+
+func (c *LifecycleContract) IllnessToDeath(ctx contractapi.TransactionContextInterface, ILNSID string, recipient string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_TRANSITIONED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return illness_to_death(stub, m, caller, caller_affiliation, recipient, DEATH)
+	})
+}
+
+//=================================================================================================================================
+//	 DeadMember - Marks a member in the death status as dead. No further changes may be made to it.
+//=================================================================================================================================
+func (c *LifecycleContract) DeadMember(ctx contractapi.TransactionContextInterface, ILNSID string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_DIED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return dead_member(stub, m, caller, caller_affiliation)
+	})
+}
@@ -0,0 +1,920 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 member - Defines the structure for a member object. JSON on right tells it what JSON fields to map to
+//			  that element when reading a JSON object into the struct e.g. JSON name -> Struct Name.
+//==============================================================================================================================
+type Member struct {
+	Name               string       `json:"name"`
+	DOB                string       `json:"DOB"`
+	Gender             string       `json:"gender"`
+	BloodGrp           string       `json:"BloodGrp"`
+	Weight             Weight       `json:"Weight"`
+	Status             int          `json:"status"`
+	Dead               bool         `json:"dead"`
+	ILNSID             string       `json:"ILNSID"`
+	Custodian_Org      string       `json:"custodianOrg,omitempty" metadata:",optional"`
+	Treating_Orgs      []string     `json:"treatingOrgs,omitempty" metadata:",optional"`
+	Guardians          []string     `json:"guardians,omitempty" metadata:",optional"`
+	Parents            []string     `json:"parents" metadata:",optional"`
+	Diagnoses          []string     `json:"diagnoses,omitempty" metadata:",optional"`
+	Notes              string       `json:"notes,omitempty" metadata:",optional"`
+	Sealed             *Sealed      `json:"sealed,omitempty" metadata:",optional"`
+	Erased             bool         `json:"erased,omitempty" metadata:",optional"`
+	Details_Hash       string       `json:"detailsHash,omitempty" metadata:",optional"`
+	Vitals_Hash        string       `json:"vitalsHash,omitempty" metadata:",optional"`
+	Immunizations_Hash string       `json:"immunizationsHash,omitempty" metadata:",optional"`
+	Redacted           bool         `json:"redacted,omitempty" metadata:",optional"`
+	Fields_Root        string       `json:"fieldsRoot,omitempty" metadata:",optional"`
+	Field_Leaves       []Field_Leaf `json:"fieldLeaves,omitempty" metadata:",optional"`
+	Schema_Version     int          `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 Ilns Holder - Defines the structure that holds all the Illness for Entity that have been created.
+//				Used as an index when querying all reports.
+//==============================================================================================================================
+type ILNS_Holder struct {
+	ILNSs          []string `json:"ILNSs"`
+	Schema_Version int      `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 MemberContract - Transactions that create members and change their demographic fields and vitals.
+//==============================================================================================================================
+type MemberContract struct {
+	contractapi.Contract
+}
+
+//==============================================================================================================================
+//	 retrieve_ILNS - Gets the state of the Member at ILNSID in the ledger then converts it from the stored
+//					JSON into the Member struct for use in the contract. Records stored with an older schema
+//					are migrated as they are read, the clinical details are read from their private collection, see
+//					read_details, and encrypted fields are decrypted if the caller passed a key that opens them.
+//					Returns the Member struct.
+//					Returns empty m if it errors.
+//==============================================================================================================================
+func retrieve_ILNS(stub shim.ChaincodeStubInterface, ILNSID string) (Member, error) {
+
+	m, err := read_member(stub, ILNSID)
+
+	if err != nil {
+		return m, err
+	}
+
+	return m, open_member(stub, &m)
+}
+
+//==============================================================================================================================
+//	 read_member - The member at ILNSID with its clinical details, without opening its encrypted fields.
+//==============================================================================================================================
+func read_member(stub shim.ChaincodeStubInterface, ILNSID string) (Member, error) {
+
+	var m Member
+
+	found, err := read_document(stub, DOC_MEMBER, member_key(ILNSID), &m)
+
+	if err != nil {
+		fmt.Printf("RETRIEVE_ILNS: Failed to read member: %s", err)
+		return m, internal("RETRIEVE_ILNS: " + error_message(err))
+	}
+
+	if !found {
+		return m, not_found("Error retrieving ILNS. No member with ILNSID = "+ILNSID, map[string]interface{}{"ILNSID": ILNSID})
+	}
+
+	return m, read_details(stub, &m)
+}
+
+//==============================================================================================================================
+//	 save_changes - Writes to the ledger the member struct passed in a JSON format. Uses the shim file's method
+//					'PutState'. The fields root is computed first, see update_field_tree, the sensitive fields are
+//					encrypted, see seal_member, and the clinical details are stored in their private collection, see
+//					save_details, leaving the public stub to be written. A member its custodian changes is held by the
+//					custodian's organisation, one its custodian hands over by the recipient's, see handover_org. Every
+//					organisation that has held the member is kept in its treating organisations, whose clients may
+//					read its details, see may_read_details, and whose peers hold them, see share_clinical.
+//==============================================================================================================================
+func save_changes(stub shim.ChaincodeStubInterface, m Member) error {
+
+	previous := m
+
+	m.Schema_Version = schema_version(DOC_MEMBER) // Records read at an older version are written back at the current one
+
+	if c, ok := stub.(*caller_stub); ok {
+
+		org := ""
+
+		if m.Name == c.caller {
+			org = c.org
+		} else if c.holder == c.caller {
+
+			var err error
+
+			if org, err = handover_org(c, m.Name); err != nil {
+				return err
+			}
+		}
+
+		if org != "" {
+			m.Custodian_Org = org
+			m.Treating_Orgs = add_treating_org(m.Treating_Orgs, org)
+		}
+	}
+
+	err := update_field_tree(stub, &m)
+
+	if err != nil {
+		return err
+	}
+
+	if err = seal_member(stub, &m, false); err != nil {
+		return err
+	}
+
+	if !m.Erased {
+
+		if err = save_details(stub, &m); err != nil {
+			return err
+		}
+
+		if err = share_clinical(stub, previous, m); err != nil {
+			return err
+		}
+	}
+
+	bytes, err := json.Marshal(m)
+
+	if err != nil {
+		fmt.Printf("SAVE_CHANGES: Error converting member record: %s", err)
+		return internal("Error converting member record")
+	}
+
+	err = stub.PutState(member_key(m.ILNSID), bytes)
+
+	if err != nil {
+		fmt.Printf("SAVE_CHANGES: Error storing member record: %s", err)
+		return internal("Error storing member record")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 handover_org - The organisation of the recipient of a member the caller hands over: the one they were registered by,
+//					see add_ecert, or the caller's own for recipients who were not registered.
+//==============================================================================================================================
+func handover_org(c *caller_stub, recipient string) (string, error) {
+
+	org, err := participant_org(c, recipient)
+
+	if err != nil || org != "" {
+		return org, err
+	}
+
+	return c.org, nil
+}
+
+//==============================================================================================================================
+//	 add_treating_org - The treating organisations with org added if it is not already one of them.
+//==============================================================================================================================
+func add_treating_org(orgs []string, org string) []string {
+
+	if org == "" {
+		return orgs
+	}
+
+	for _, treating := range orgs {
+		if treating == org {
+			return orgs
+		}
+	}
+
+	return append(orgs, org)
+}
+
+//==============================================================================================================================
+//	 save_failed - The error returned when save_changes fails. A caller that may not write the encrypted fields of the
+//				   member, or passed too little entropy or a malformed data key, is told why, other failures are
+//				   internal.
+//==============================================================================================================================
+func save_failed(err error) error {
+
+	if code := error_code(err); code == PERMISSION_DENIED || code == VALIDATION_FAILED {
+		return err
+	}
+
+	return internal("Error saving changes")
+}
+
+//==============================================================================================================================
+//	 retrieve_ILNS_holder - Gets the index of all ILNSIDs that have been created. The index is created with the first
+//							member so an empty index is returned if none has been written yet.
+//==============================================================================================================================
+func retrieve_ILNS_holder(stub shim.ChaincodeStubInterface) (ILNS_Holder, error) {
+
+	ILNSIDs := ILNS_Holder{ILNSs: []string{}}
+
+	_, err := read_document(stub, DOC_ILNS_HOLDER, index_key(INDEX_ILNSIDS), &ILNSIDs)
+
+	if err != nil {
+		return ILNSIDs, internal("Unable to get ILNSIDs")
+	}
+
+	return ILNSIDs, nil
+}
+
+//==============================================================================================================================
+//	 save_ILNS_holder - Writes the index of all ILNSIDs to the ledger.
+//==============================================================================================================================
+func save_ILNS_holder(stub shim.ChaincodeStubInterface, ILNSIDs ILNS_Holder) error {
+
+	ILNSIDs.Schema_Version = schema_version(DOC_ILNS_HOLDER)
+
+	bytes, err := json.Marshal(ILNSIDs)
+
+	if err != nil {
+		return internal("Error creating ILNS_Holder record")
+	}
+
+	err = stub.PutState(index_key(INDEX_ILNSIDS), bytes)
+
+	if err != nil {
+		return internal("Unable to put the state")
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 Create Function
+//=================================================================================================================================
+//	 Create member - Creates the initial JSON for the member and then saves it to the ledger. Any further arguments are the
+//					 ILNSIDs of the member's parents, these must already exist. caller_org is the caller's organisation,
+//					 whose prefix the ILNSID must be issued under, see check_ILNSID_prefix.
+//=================================================================================================================================
+func create_member(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string, caller_org string, ILNSID string, parents []string) (Member, error) {
+
+	var m Member
+
+	if caller_affiliation != PARENTS { // Only the parents can create a new ILNS, checked first so that others can not probe which ILNSIDs exist
+		return m, role_required("create_member", PARENTS, caller_affiliation)
+	}
+
+	err := validate_ILNSID(ILNSID)
+
+	if err != nil {
+		fmt.Printf("CREATE_MEMBER: Invalid ILNSID provided")
+		return m, err
+	}
+
+	if err = check_ILNSID_prefix(stub, ILNSID, caller_org); err != nil {
+		return m, err
+	}
+
+	m = Member{
+		ILNSID:        ILNSID,
+		Name:          caller,
+		DOB:           UNDEFINED,
+		Gender:        UNDEFINED,
+		BloodGrp:      UNDEFINED,
+		Weight:        Weight{Value: 0, Unit: "kg"},
+		Status:        STATE_CARRYING,
+		Dead:          false,
+		Custodian_Org: caller_org,
+		Treating_Orgs: add_treating_org(nil, caller_org),
+		Guardians:     []string{caller},
+	}
+
+	for _, parent_ID := range parents {
+
+		_, err = retrieve_ILNS(stub, parent_ID)
+
+		if err != nil {
+			return m, invalid("parents", parent_ID, "parent member does not exist")
+		}
+	}
+
+	m.Parents = parents
+
+	record, err := stub.GetState(member_key(m.ILNSID)) // If not an error then a record exists so cant create a new member with this ILNSID as it must be unique
+
+	if err != nil {
+		return m, internal("Error checking ILNSID " + m.ILNSID)
+	}
+
+	if record != nil {
+		return m, conflict("member already exists", map[string]interface{}{"ILNSID": m.ILNSID})
+	}
+
+	err = save_changes(stub, m)
+
+	if err != nil {
+		fmt.Printf("CREATE_MEMBER: Error saving changes: %s", err)
+		return m, save_failed(err)
+	}
+
+	ILNSIDs, err := retrieve_ILNS_holder(stub)
+
+	if err != nil {
+		return m, err
+	}
+
+	ILNSIDs.ILNSs = append(ILNSIDs.ILNSs, ILNSID)
+
+	return m, save_ILNS_holder(stub, ILNSIDs)
+}
+
+//=================================================================================================================================
+//	 Update Functions
+//=================================================================================================================================
+//	 Each field has an apply_ function that validates the new value, checks the caller may change the field and then sets
+//	it on the member passed. The update_ functions apply a single field and save, update_member applies several.
+//=================================================================================================================================
+//	 apply_DOB
+//=================================================================================================================================
+func apply_DOB(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	return apply_DOB_with(stub, m, caller, caller_affiliation, new_value, func(ILNSID string) (Member, error) { return retrieve_ILNS(stub, ILNSID) })
+}
+
+//=================================================================================================================================
+//	 apply_DOB_with - As apply_DOB, parents are looked up with the function passed, see validate_DOB_with.
+//=================================================================================================================================
+func apply_DOB_with(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string, lookup func(string) (Member, error)) error {
+
+	err := validate_DOB_with(stub, *m, new_value, lookup)
+
+	if err != nil {
+		return err
+	}
+
+	if m.Dead {
+		return invalid_state("update_DOB", *m, -1)
+	}
+
+	if m.Name == caller &&
+		caller_affiliation == BIRTHDAY {
+
+		m.DOB = new_value
+	} else {
+
+		return role_denied("update_DOB", *m, caller, caller_affiliation, BIRTHDAY)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 apply_BloodGrp
+//=================================================================================================================================
+func apply_BloodGrp(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	err := validate_BloodGrp(new_value)
+
+	if err != nil {
+		return err
+	}
+
+	if m.Dead {
+		return invalid_state("update_BloodGrp", *m, -1)
+	}
+
+	if m.Name == caller &&
+		caller_affiliation == BIRTHDAY {
+
+		m.BloodGrp = new_value
+
+	} else {
+		return role_denied("update_BloodGrp", *m, caller, caller_affiliation, BIRTHDAY)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 apply_gender
+//=================================================================================================================================
+func apply_gender(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	err := validate_gender(new_value)
+
+	if err != nil {
+		return err
+	}
+
+	if m.Dead {
+		return invalid_state("update_gender", *m, -1)
+	}
+
+	if m.Name == caller &&
+		caller_affiliation != DEATH {
+
+		m.Gender = new_value
+
+	} else {
+		return custodian_denied("update_gender", *m, caller, caller_affiliation)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 apply_Weight - Also adds the weight to the member's vitals series so that the series stays in step with the current
+//					weight. The series is changed in place and must be saved by the caller.
+//=================================================================================================================================
+func apply_Weight(stub shim.ChaincodeStubInterface, m *Member, series *Vitals_Series, caller string, caller_affiliation string, new_value string) error {
+
+	new_Weight, err := validate_Weight(stub, *m, new_value) // will return an error if the value is not a decimal weight with a unit or is implausible
+
+	if err != nil {
+		return err
+	}
+
+	if m.Dead {
+		return invalid_state("update_Weight", *m, -1)
+	}
+
+	if m.Name == caller &&
+		caller_affiliation != DEATH {
+
+		m.Weight = new_Weight // Update to the new value
+	} else {
+
+		return custodian_denied("update_Weight", *m, caller, caller_affiliation)
+
+	}
+
+	return add_observation(stub, m, series, caller, Observation{Type: OBS_WEIGHT, Value: new_Weight.Value, Unit: new_Weight.Unit})
+
+}
+
+//=================================================================================================================================
+//	 apply_diagnoses - Replaces the member's diagnoses with the ICD-10 codes listed, an empty list clears them.
+//=================================================================================================================================
+func apply_diagnoses(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	diagnoses, err := parse_diagnoses(new_value)
+
+	if err != nil {
+		return err
+	}
+
+	if m.Dead {
+		return invalid_state("update_diagnoses", *m, -1)
+	}
+
+	if m.Name == caller &&
+		caller_affiliation != DEATH {
+
+		m.Diagnoses = diagnoses
+
+	} else {
+		return custodian_denied("update_diagnoses", *m, caller, caller_affiliation)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 apply_notes
+//=================================================================================================================================
+func apply_notes(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	err := validate_notes(new_value)
+
+	if err != nil {
+		return err
+	}
+
+	if m.Dead {
+		return invalid_state("update_notes", *m, -1)
+	}
+
+	if m.Name == caller &&
+		caller_affiliation != DEATH {
+
+		m.Notes = new_value
+
+	} else {
+		return custodian_denied("update_notes", *m, caller, caller_affiliation)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 update_DOB
+//=================================================================================================================================
+func update_DOB(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	err := apply_DOB(stub, m, caller, caller_affiliation, new_value)
+
+	if err != nil {
+		return err
+	}
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("UPDATE_DOB: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 update_BloodGrp
+//=================================================================================================================================
+func update_BloodGrp(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	err := apply_BloodGrp(stub, m, caller, caller_affiliation, new_value)
+
+	if err != nil {
+		return err
+	}
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("UPDATE_BloodGrp: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 update_gender
+//=================================================================================================================================
+func update_gender(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	err := apply_gender(stub, m, caller, caller_affiliation, new_value)
+
+	if err != nil {
+		return err
+	}
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("UPDATE_GENDER: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 update_Weight
+//=================================================================================================================================
+func update_Weight(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, new_value string) error {
+
+	series, err := retrieve_vitals(stub, *m)
+
+	if err != nil {
+		return err
+	}
+
+	err = apply_Weight(stub, m, &series, caller, caller_affiliation, new_value)
+
+	if err != nil {
+		return err
+	}
+
+	err = save_vitals(stub, m, series)
+
+	if err != nil {
+		fmt.Printf("UPDATE_WEIGHT: Error saving vitals: %s", err)
+		return save_failed(err)
+	}
+
+	err = save_changes(stub, *m) // Save the changes in the blockchain
+
+	if err != nil {
+		fmt.Printf("UPDATE_WEIGHT: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 update_member - Applies a JSON patch of several fields e.g. {"DOB":"2016-05-01","gender":"female","Weight":"3.2kg"} in
+//					 one transaction. Fields are applied in the order DOB, gender, BloodGrp, Weight, diagnoses, notes so
+//					 that the weight is checked against the new DOB. diagnoses is a comma separated list of ICD-10
+//					 codes. Every field is validated and permission checked, if any is rejected nothing is written and
+//					 the error lists each rejected field with its error code. The error has the code shared by every
+//					 rejected field, or VALIDATION_FAILED if they differ.
+//=================================================================================================================================
+var PATCH_FIELDS = []string{"DOB", "gender", "BloodGrp", "Weight", "diagnoses", "notes"}
+
+type Rejected_Field struct {
+	Field string `json:"field"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type Patch_Error struct {
+	Rejected []Rejected_Field `json:"rejected"`
+}
+
+func (e *Patch_Error) Code() string {
+
+	code := VALIDATION_FAILED
+
+	for i, r := range e.Rejected {
+		if i == 0 {
+			code = r.Code
+		} else if r.Code != code {
+			return VALIDATION_FAILED
+		}
+	}
+
+	return code
+}
+
+func (e *Patch_Error) Error() string {
+
+	ce := Chaincode_Error{Code: e.Code(), Message: "Patch rejected", Details: map[string]interface{}{"rejected": e.Rejected}}
+
+	return ce.Error()
+}
+
+func update_member(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, patch_json string) error {
+
+	var patch map[string]string
+
+	err := json.Unmarshal([]byte(patch_json), &patch)
+
+	if err != nil || len(patch) == 0 {
+		return invalid("patch", patch_json, "must be a non-empty JSON object of field names to string values")
+	}
+
+	var rejected Patch_Error
+
+	fields := []string{}
+
+	for field := range patch {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields) // Map order is random, keep the error the same on every peer
+
+	for _, field := range fields {
+
+		known := false
+
+		for _, f := range PATCH_FIELDS {
+			if field == f {
+				known = true
+			}
+		}
+
+		if !known {
+			rejected.Rejected = append(rejected.Rejected, Rejected_Field{field, VALIDATION_FAILED, "Unknown or read-only field"})
+		}
+	}
+
+	series, err := retrieve_vitals(stub, *m)
+
+	if err != nil {
+		return err
+	}
+
+	for _, field := range PATCH_FIELDS {
+
+		value, ok := patch[field]
+
+		if !ok {
+			continue
+		}
+
+		switch field {
+		case "DOB":
+			err = apply_DOB(stub, m, caller, caller_affiliation, value)
+		case "gender":
+			err = apply_gender(stub, m, caller, caller_affiliation, value)
+		case "BloodGrp":
+			err = apply_BloodGrp(stub, m, caller, caller_affiliation, value)
+		case "Weight":
+			err = apply_Weight(stub, m, &series, caller, caller_affiliation, value)
+		case "diagnoses":
+			err = apply_diagnoses(stub, m, caller, caller_affiliation, value)
+		case "notes":
+			err = apply_notes(stub, m, caller, caller_affiliation, value)
+		}
+
+		if ve, ok := err.(*Validation_Error); ok {
+			rejected.Rejected = append(rejected.Rejected, Rejected_Field{field, VALIDATION_FAILED, ve.Reason})
+		} else if err != nil {
+			rejected.Rejected = append(rejected.Rejected, Rejected_Field{field, error_code(err), error_message(err)})
+		}
+	}
+
+	if len(rejected.Rejected) > 0 {
+		return &rejected
+	}
+
+	if _, ok := patch["Weight"]; ok {
+
+		err = save_vitals(stub, m, series)
+
+		if err != nil {
+			fmt.Printf("UPDATE_MEMBER: Error saving vitals: %s", err)
+			return save_failed(err)
+		}
+	}
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("UPDATE_MEMBER: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 CreateMember - Creates a member in the carrying state, owned by the caller. parents are the ILNSIDs of the member's
+//					parents and may be empty.
+//=================================================================================================================================
+func (c *MemberContract) CreateMember(ctx contractapi.TransactionContextInterface, ILNSID string, parents []string) (*Invoke_Result, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := create_member(new_caller_stub(ctx, caller), caller, caller_affiliation, get_caller_org(ctx), ILNSID, parents)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := new_result(ctx.GetStub(), m, MEMBER_FIELDS)
+
+	err = emit_member_event(ctx.GetStub(), get_caller_org(ctx), EVENT_CREATED, nil, m, result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+//==============================================================================================================================
+//	 caller_stub - The stub of a transaction run against a member, with the caller, their organisation and the custodian
+//				   the member was read with, so that save_changes can tell which organisation holds the member, and the
+//				   clinical records saved for it, which save_changes writes, see save_clinical.
+//==============================================================================================================================
+type caller_stub struct {
+	shim.ChaincodeStubInterface
+	caller   string
+	org      string
+	holder   string
+	clinical map[string][]byte
+}
+
+//==============================================================================================================================
+//	 new_caller_stub - The stub of the transaction for the caller, who has not read a member yet.
+//==============================================================================================================================
+func new_caller_stub(ctx contractapi.TransactionContextInterface, caller string) *caller_stub {
+	return &caller_stub{ChaincodeStubInterface: ctx.GetStub(), caller: caller, org: get_caller_org(ctx)}
+}
+
+//=================================================================================================================================
+//	 with_member - Resolves the caller and retrieves the member before running a transaction against it.
+//=================================================================================================================================
+func with_member(ctx contractapi.TransactionContextInterface, ILNSID string, fn func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error) error {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	stub := new_caller_stub(ctx, caller)
+
+	m, err := retrieve_ILNS(stub, ILNSID)
+
+	if err != nil {
+		fmt.Printf("INVOKE: Error retrieving ILNS: %s", err)
+		return err
+	}
+
+	stub.holder = m.Name
+
+	return fn(stub, m, caller, caller_affiliation)
+}
+
+//=================================================================================================================================
+//	 UpdateDOB / UpdateGender / UpdateBloodGrp / UpdateWeight - Change a single demographic field of the member.
+//=================================================================================================================================
+func (c *MemberContract) UpdateDOB(ctx contractapi.TransactionContextInterface, ILNSID string, value string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_UPDATED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return update_DOB(stub, m, caller, caller_affiliation, value)
+	})
+}
+
+func (c *MemberContract) UpdateGender(ctx contractapi.TransactionContextInterface, ILNSID string, value string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_UPDATED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return update_gender(stub, m, caller, caller_affiliation, value)
+	})
+}
+
+func (c *MemberContract) UpdateBloodGrp(ctx contractapi.TransactionContextInterface, ILNSID string, value string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_UPDATED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return update_BloodGrp(stub, m, caller, caller_affiliation, value)
+	})
+}
+
+func (c *MemberContract) UpdateWeight(ctx contractapi.TransactionContextInterface, ILNSID string, value string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_UPDATED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return update_Weight(stub, m, caller, caller_affiliation, value)
+	})
+}
+
+//=================================================================================================================================
+//	 UpdateMember - Applies a JSON patch of several demographic fields in one transaction, see update_member.
+//=================================================================================================================================
+func (c *MemberContract) UpdateMember(ctx contractapi.TransactionContextInterface, ILNSID string, patch string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_UPDATED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return update_member(stub, m, caller, caller_affiliation, patch)
+	})
+}
+
+//=================================================================================================================================
+//	 RecordObservation - Adds a timestamped vital sign to the member's series.
+//=================================================================================================================================
+func (c *MemberContract) RecordObservation(ctx contractapi.TransactionContextInterface, ILNSID string, observation Observation) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_UPDATED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return record_observation(stub, m, caller, caller_affiliation, observation)
+	})
+}
+
+//=================================================================================================================================
+//	 EraseMember - Erases the member on a right-to-erasure request, leaving a tombstone with its lifecycle.
+//=================================================================================================================================
+func (c *MemberContract) EraseMember(ctx contractapi.TransactionContextInterface, ILNSID string) (*Invoke_Result, error) {
+	return change_member(ctx, ILNSID, EVENT_ERASED, func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error {
+		return erase_member(stub, m, caller, caller_affiliation)
+	})
+}
+
+//=================================================================================================================================
+//	 ImportMembers - Creates members in bulk from JSON or CSV records. mode is all_or_nothing or best_effort.
+//=================================================================================================================================
+func (c *MemberContract) ImportMembers(ctx contractapi.TransactionContextInterface, records string, mode string) (*Import_Report, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == "" {
+		mode = ALL_OR_NOTHING
+	}
+
+	report, err := import_members(new_caller_stub(ctx, caller), caller, caller_affiliation, get_caller_org(ctx), records, mode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Created > 0 {
+
+		err = emit_import_event(ctx.GetStub(), get_caller_org(ctx), report)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,401 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Document types - Every document stored on the ledger carries a schemaVersion. Documents written before versioning
+//					  was introduced have no schemaVersion and are treated as version 0.
+//==============================================================================================================================
+const DOC_MEMBER = "member"
+const DOC_ILNS_HOLDER = "ILNS_Holder"
+const DOC_PARTICIPANT_HOLDER = "Participant_Holder"
+const DOC_VITALS = "vitals"
+const DOC_GROWTH_REFERENCE = "growth_reference"
+const DOC_IMPORT_REPORT = "import_report"
+const DOC_CONSENT = "consent"
+const DOC_ID_SEQUENCE = "id_sequence"
+const DOC_ORG_KEY = "org_key"
+const DOC_DATA_KEY = "data_key"
+const DOC_DETAILS = "details"
+const DOC_ATTACHMENT = "attachment"
+const DOC_CREDENTIAL = "credential"
+const DOC_ISSUER_KEY = "issuer_key"
+const DOC_IMMUNIZATIONS = "immunizations"
+const DOC_SCHEDULE = "schedule"
+const DOC_HISTORY = "history"
+
+const DEFAULT_MIGRATION_BATCH = 50
+const MAX_MIGRATION_BATCH = 500
+
+//==============================================================================================================================
+//	 Migration - Upgrades a decoded document by one version in place. key is the key the document was read from.
+//==============================================================================================================================
+type Migration func(doc map[string]interface{}, key string) error
+
+//==============================================================================================================================
+//	 MIGRATIONS - The upgrade functions registered for each document type. Entry i upgrades a document from version i to
+//				  version i+1, so the current version of a type is the number of migrations registered for it. To change
+//				  a stored document add a migration to the end of its list.
+//==============================================================================================================================
+var MIGRATIONS = map[string][]Migration{
+	DOC_MEMBER:             {member_v0_to_v1},
+	DOC_ILNS_HOLDER:        {stamp_version},
+	DOC_PARTICIPANT_HOLDER: {stamp_version},
+	DOC_VITALS:             {stamp_version},
+	DOC_GROWTH_REFERENCE:   {stamp_version},
+	DOC_IMPORT_REPORT:      {stamp_version},
+	DOC_CONSENT:            {stamp_version},
+	DOC_ID_SEQUENCE:        {stamp_version},
+	DOC_ORG_KEY:            {stamp_version},
+	DOC_DATA_KEY:           {stamp_version},
+	DOC_DETAILS:            {stamp_version},
+	DOC_ATTACHMENT:         {stamp_version},
+	DOC_CREDENTIAL:         {stamp_version},
+	DOC_ISSUER_KEY:         {stamp_version},
+	DOC_IMMUNIZATIONS:      {stamp_version},
+	DOC_SCHEDULE:           {stamp_version},
+	DOC_HISTORY:            {stamp_version},
+}
+
+//==============================================================================================================================
+//	 schema_version - The current schema version of a document type.
+//==============================================================================================================================
+func schema_version(doc_type string) int {
+	return len(MIGRATIONS[doc_type])
+}
+
+//==============================================================================================================================
+//	 stamp_version - Migration for documents whose only change is gaining a schemaVersion.
+//==============================================================================================================================
+func stamp_version(doc map[string]interface{}, key string) error {
+	return nil
+}
+
+//==============================================================================================================================
+//	 member_v0_to_v1 - The ILNSID was stored under "IllnessID" and records created by create_member stored it empty, it
+//					   is now stored under "ILNSID" and taken from the key when missing. Weight was a bare integer and
+//					   is now a value with a unit, old weights are taken to be kilograms.
+//==============================================================================================================================
+func member_v0_to_v1(doc map[string]interface{}, key string) error {
+
+	ILNSID, _ := doc["IllnessID"].(string)
+
+	if ILNSID == "" {
+		ILNSID = key
+		if _, attributes, ok := SplitKey(key); ok {
+			ILNSID = attributes[0]
+		}
+	}
+
+	delete(doc, "IllnessID")
+	doc["ILNSID"] = ILNSID
+
+	switch weight := doc["Weight"].(type) {
+	case float64:
+		doc["Weight"] = map[string]interface{}{"value": weight, "unit": "kg"}
+	case nil:
+		doc["Weight"] = map[string]interface{}{"value": 0, "unit": "kg"}
+	case map[string]interface{}:
+	default:
+		return internal("unexpected Weight in member " + key)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 upgrade_document - Applies the registered migrations to a stored document until it is at the current version.
+//						Returns the upgraded JSON and whether anything was changed. Documents from a newer version of
+//						the chaincode are rejected rather than read with missing fields.
+//==============================================================================================================================
+func upgrade_document(doc_type string, key string, bytes []byte) ([]byte, bool, error) {
+
+	var doc map[string]interface{}
+
+	err := json.Unmarshal(bytes, &doc)
+
+	if err != nil || doc == nil {
+		return nil, false, internal("Corrupt " + doc_type + " record " + key)
+	}
+
+	version := 0
+
+	if v, ok := doc["schemaVersion"].(float64); ok {
+		version = int(v)
+	}
+
+	current := schema_version(doc_type)
+
+	if version == current {
+		return bytes, false, nil
+	}
+
+	if version > current {
+		return nil, false, internal(fmt.Sprintf("%s record %s has schema version %d, this chaincode supports up to %d", doc_type, key, version, current))
+	}
+
+	for ; version < current; version++ {
+		if err = MIGRATIONS[doc_type][version](doc, key); err != nil {
+			return nil, false, err
+		}
+	}
+
+	doc["schemaVersion"] = current
+
+	upgraded, err := json.Marshal(doc)
+
+	if err != nil {
+		return nil, false, internal("Error converting " + doc_type + " record " + key)
+	}
+
+	return upgraded, true, nil
+}
+
+//==============================================================================================================================
+//	 read_document - Gets the document at key, upgrades it to the current version and decodes it into v. found is false
+//					 if there is no document at key. Upgrades are not written back, the document is stored at the current
+//					 version the next time it is saved.
+//==============================================================================================================================
+func read_document(stub shim.ChaincodeStubInterface, doc_type string, key string, v interface{}) (bool, error) {
+
+	bytes, err := stub.GetState(key)
+
+	if err != nil {
+		return false, internal("Error retrieving " + doc_type + " record " + key)
+	}
+
+	if bytes == nil {
+		return false, nil
+	}
+
+	bytes, _, err = upgrade_document(doc_type, key, bytes)
+
+	if err != nil {
+		return true, err
+	}
+
+	err = json.Unmarshal(bytes, v)
+
+	if err != nil {
+		return true, internal("Corrupt " + doc_type + " record " + key)
+	}
+
+	return true, nil
+}
+
+//==============================================================================================================================
+//	 migrate_key - Upgrades the document at key and writes it back if it changed. Returns whether it was rewritten.
+//==============================================================================================================================
+func migrate_key(stub shim.ChaincodeStubInterface, doc_type string, key string) (bool, error) {
+
+	bytes, err := stub.GetState(key)
+
+	if err != nil {
+		return false, internal("Error retrieving " + doc_type + " record " + key)
+	}
+
+	if bytes == nil {
+		return false, nil
+	}
+
+	upgraded, changed, err := upgrade_document(doc_type, key, bytes)
+
+	if err != nil || !changed {
+		return false, err
+	}
+
+	err = stub.PutState(key, upgraded)
+
+	if err != nil {
+		return false, internal("Error storing " + doc_type + " record " + key)
+	}
+
+	return true, nil
+}
+
+//==============================================================================================================================
+//	 Migration_Result - Returned by migrate_records. Next is the bookmark to pass for the following batch and is empty
+//						once every member has been checked. Missing lists ILNSIDs in the index with no stored record.
+//==============================================================================================================================
+type Migration_Result struct {
+	Checked  int      `json:"checked"`
+	Migrated int      `json:"migrated"`
+	Missing  []string `json:"missing"`
+	Next     string   `json:"next"`
+}
+
+//=================================================================================================================================
+//	 migrate_records - Eagerly upgrades stored documents in batches of members (with their vitals, immunizations,
+//					   consents, attached documents and credentials), starting at the bookmark. The first batch also
+//					   upgrades the indexes, growth references, import reports and immunization schedules. Only
+//					   administrators may migrate.
+//=================================================================================================================================
+func migrate_records(stub shim.ChaincodeStubInterface, caller_affiliation string, bookmark string, batch_size string) (*Migration_Result, error) {
+
+	if caller_affiliation != ADMIN {
+		return nil, role_required("migrate_records", ADMIN, caller_affiliation)
+	}
+
+	start := 0
+
+	if bookmark != "" {
+		n, err := strconv.Atoi(bookmark)
+		if err != nil || n < 0 {
+			return nil, invalid("bookmark", bookmark, "must be the next value of a previous batch")
+		}
+		start = n
+	}
+
+	size := DEFAULT_MIGRATION_BATCH
+
+	if batch_size != "" {
+		n, err := strconv.Atoi(batch_size)
+		if err != nil || n < 1 || n > MAX_MIGRATION_BATCH {
+			return nil, invalid("batch_size", batch_size, fmt.Sprintf("must be between 1 and %d", MAX_MIGRATION_BATCH))
+		}
+		size = n
+	}
+
+	result := Migration_Result{Missing: []string{}}
+
+	migrate := func(doc_type string, key string) error {
+		changed, err := migrate_key(stub, doc_type, key)
+		if changed {
+			result.Migrated++
+		}
+		return err
+	}
+
+	if start == 0 {
+
+		if err := migrate(DOC_ILNS_HOLDER, index_key(INDEX_ILNSIDS)); err != nil {
+			return nil, err
+		}
+
+		if err := migrate(DOC_PARTICIPANT_HOLDER, index_key(INDEX_PARTICIPANTS)); err != nil {
+			return nil, err
+		}
+
+		for _, indicator := range GROWTH_INDICATORS {
+			for _, sex := range []string{"female", "male"} {
+				if err := migrate(DOC_GROWTH_REFERENCE, growth_reference_key(indicator, sex)); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		reports, err := list_keys(stub, ENTRY_IMPORT_REPORT)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range reports {
+			if err := migrate(DOC_IMPORT_REPORT, key); err != nil {
+				return nil, err
+			}
+		}
+
+		schedules, err := list_keys(stub, ENTRY_SCHEDULE)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range schedules {
+			if err := migrate(DOC_SCHEDULE, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ILNSIDs, err := retrieve_ILNS_holder(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pos := start
+
+	for ; pos < len(ILNSIDs.ILNSs) && result.Checked < size; pos++ {
+
+		ILNSID := ILNSIDs.ILNSs[pos]
+
+		record, err := stub.GetState(member_key(ILNSID))
+
+		if err != nil {
+			return nil, internal("Error retrieving member record " + ILNSID)
+		}
+
+		result.Checked++
+
+		if record == nil {
+			result.Missing = append(result.Missing, ILNSID)
+			continue
+		}
+
+		if err = migrate(DOC_MEMBER, member_key(ILNSID)); err != nil {
+			return nil, err
+		}
+
+		if err = migrate(DOC_VITALS, vitals_key(ILNSID)); err != nil {
+			return nil, err
+		}
+
+		if err = migrate(DOC_IMMUNIZATIONS, immunizations_key(ILNSID)); err != nil {
+			return nil, err
+		}
+
+		if err = migrate(DOC_HISTORY, history_key(ILNSID)); err != nil {
+			return nil, err
+		}
+
+		consents, err := list_keys(stub, ENTRY_CONSENT, ILNSID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range consents {
+			if err = migrate(DOC_CONSENT, key); err != nil {
+				return nil, err
+			}
+		}
+
+		attachments, err := list_keys(stub, ENTRY_ATTACHMENT, ILNSID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range attachments {
+			if err = migrate(DOC_ATTACHMENT, key); err != nil {
+				return nil, err
+			}
+		}
+
+		credentials, err := list_keys(stub, ENTRY_CREDENTIAL, ILNSID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range credentials {
+			if err = migrate(DOC_CREDENTIAL, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if pos < len(ILNSIDs.ILNSs) {
+		result.Next = strconv.Itoa(pos)
+	}
+
+	return &result, nil
+}
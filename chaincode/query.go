@@ -0,0 +1,472 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/ravivarmakv/SampleChainCode/disclosure"
+)
+
+//==============================================================================================================================
+//	 QueryContract - Read only queries. Every transaction of the contract is evaluated rather than submitted.
+//==============================================================================================================================
+type QueryContract struct {
+	contractapi.Contract
+}
+
+//=================================================================================================================================
+//	 Read Functions
+//=================================================================================================================================
+//	 get_member_details
+//=================================================================================================================================
+func get_member_details(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) (*Member, error) {
+
+	if can_view(stub, m, caller, caller_affiliation) {
+
+		return &m, nil
+	} else {
+		return nil, view_denied("get_member_details", m)
+	}
+
+}
+
+//=================================================================================================================================
+//	 get_members - Returns every member the caller may see.
+//=================================================================================================================================
+func get_members(stub shim.ChaincodeStubInterface, caller string, caller_affiliation string) ([]Member, error) {
+
+	ILNSIDs, err := retrieve_ILNS_holder(stub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Member{}
+
+	for _, ILNS := range ILNSIDs.ILNSs {
+
+		m, err := retrieve_ILNS(stub, ILNS)
+
+		if err != nil {
+			return nil, internal("Failed to retrieve ILNS")
+		}
+
+		if details, err := get_member_details(stub, m, caller, caller_affiliation); err == nil {
+			result = append(result, *details)
+		}
+	}
+
+	return result, nil
+}
+
+//=================================================================================================================================
+//	 get_member_history - Returns every committed version of the member, oldest first, if the caller may see it now.
+//						  Versions written before the member was re-keyed are read from its legacy key, without the
+//						  deletion rekey_state made there, and versions imported from another channel come first. Only
+//						  the lifecycle of each version of an erased member is returned. Private data keeps no history,
+//						  so versions written since the clinical details moved to their collection hold the public stub
+//						  alone.
+//=================================================================================================================================
+type Member_Revision struct {
+	Tx_ID     string  `json:"txID"`
+	Timestamp string  `json:"timestamp"`
+	Member    *Member `json:"member,omitempty" metadata:",optional"`
+	Deleted   bool    `json:"deleted"`
+	Imported  bool    `json:"imported,omitempty" metadata:",optional"`
+}
+
+func get_member_history(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) ([]Member_Revision, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("get_member_history", m)
+	}
+
+	return member_history(stub, m.ILNSID, m.Erased)
+}
+
+//==============================================================================================================================
+//	 Member_History - The revisions of a member exported from another channel, kept under history:<ILNSID>.
+//==============================================================================================================================
+type Member_History struct {
+	ILNSID         string            `json:"ILNSID"`
+	Revisions      []Member_Revision `json:"revisions"`
+	Schema_Version int               `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 save_member_history - Writes the imported revisions of a member to the ledger.
+//==============================================================================================================================
+func save_member_history(stub shim.ChaincodeStubInterface, history Member_History) error {
+
+	history.Schema_Version = schema_version(DOC_HISTORY)
+
+	bytes, err := json.Marshal(history)
+
+	if err != nil {
+		return internal("Error converting history record")
+	}
+
+	err = stub.PutState(history_key(history.ILNSID), bytes)
+
+	if err != nil {
+		return internal("Error storing history record")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 member_history - The imported revisions of a member, marked imported, followed by those of its legacy and current
+//					  keys. The revisions of an erased member are stripped.
+//==============================================================================================================================
+func member_history(stub shim.ChaincodeStubInterface, ILNSID string, erased bool) ([]Member_Revision, error) {
+
+	var imported Member_History
+
+	if _, err := read_document(stub, DOC_HISTORY, history_key(ILNSID), &imported); err != nil {
+		return nil, err
+	}
+
+	history := []Member_Revision{}
+
+	for _, revision := range imported.Revisions {
+		revision.Imported = true
+		history = append(history, revision)
+	}
+
+	for _, key := range []string{ILNSID, member_key(ILNSID)} {
+
+		revisions, err := key_history(stub, ILNSID, key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, revisions...)
+	}
+
+	for i := range history {
+		if erased && history[i].Member != nil {
+			stripped := strip(*history[i].Member)
+			history[i].Member = &stripped
+		}
+	}
+
+	return history, nil
+}
+
+func key_history(stub shim.ChaincodeStubInterface, ILNSID string, key string) ([]Member_Revision, error) {
+
+	it, err := stub.GetHistoryForKey(key)
+
+	if err != nil {
+		return nil, internal("Error retrieving history of " + ILNSID)
+	}
+
+	defer it.Close()
+
+	history := []Member_Revision{}
+
+	for it.HasNext() {
+
+		km, err := it.Next()
+
+		if err != nil {
+			return nil, internal("Error retrieving history of " + ILNSID)
+		}
+
+		if km.IsDelete && key == ILNSID {
+			continue
+		}
+
+		revision := Member_Revision{Tx_ID: km.TxId, Deleted: km.IsDelete}
+
+		if km.Timestamp != nil {
+			revision.Timestamp = km.Timestamp.AsTime().UTC().Format(time.RFC3339)
+		}
+
+		if !km.IsDelete {
+
+			bytes, _, err := upgrade_document(DOC_MEMBER, key, km.Value) // Older versions may predate the current schema
+
+			if err != nil {
+				return nil, err
+			}
+
+			var version Member
+
+			if json.Unmarshal(bytes, &version) != nil {
+				return nil, internal("Corrupt member record " + ILNSID + " in " + km.TxId)
+			}
+
+			revision.Member = &version
+		}
+
+		history = append(history, revision)
+	}
+
+	return history, nil
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 GetMemberDetails - Returns the member if the caller is its custodian, a parent or holds consent.
+//=================================================================================================================================
+func (c *QueryContract) GetMemberDetails(ctx contractapi.TransactionContextInterface, ILNSID string) (*Member, error) {
+
+	var details *Member
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		details, err = get_member_details(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return details, err
+}
+
+//=================================================================================================================================
+//	 GetMembers - Returns every member the caller may see.
+//=================================================================================================================================
+func (c *QueryContract) GetMembers(ctx contractapi.TransactionContextInterface) ([]Member, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return get_members(new_caller_stub(ctx, caller), caller, caller_affiliation)
+}
+
+//=================================================================================================================================
+//	 GetDataKeys - Returns the wrapped data keys of the members, and of their parents, for the caller to unwrap.
+//=================================================================================================================================
+func (c *QueryContract) GetDataKeys(ctx contractapi.TransactionContextInterface, ILNSIDs []string) ([]Data_Key, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return get_data_keys(new_caller_stub(ctx, caller), caller, caller_affiliation, ILNSIDs)
+}
+
+//=================================================================================================================================
+//	 GetMemberHistory - Returns every committed version of the member with the transaction that wrote it.
+//=================================================================================================================================
+func (c *QueryContract) GetMemberHistory(ctx contractapi.TransactionContextInterface, ILNSID string) ([]Member_Revision, error) {
+
+	var history []Member_Revision
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		history, err = get_member_history(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return history, err
+}
+
+//=================================================================================================================================
+//	 VerifyMemberDetails - Checks the member's clinical details against the hash on its public stub.
+//=================================================================================================================================
+func (c *QueryContract) VerifyMemberDetails(ctx contractapi.TransactionContextInterface, ILNSID string) (*Details_Verification, error) {
+
+	var verification *Details_Verification
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		verification, err = verify_member_details(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return verification, err
+}
+
+//=================================================================================================================================
+//	 GetObservations - Returns the member's vitals series, filtered to obs_type unless it is empty.
+//=================================================================================================================================
+func (c *QueryContract) GetObservations(ctx contractapi.TransactionContextInterface, ILNSID string, obs_type string) (*Vitals_Series, error) {
+
+	var series *Vitals_Series
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		series, err = get_observations(stub, m, caller, caller_affiliation, obs_type)
+		return err
+	})
+
+	return series, err
+}
+
+//=================================================================================================================================
+//	 GetGrowthPercentiles - Returns WHO growth standard z-scores and percentiles for the member.
+//=================================================================================================================================
+func (c *QueryContract) GetGrowthPercentiles(ctx contractapi.TransactionContextInterface, ILNSID string) (*Growth_Report, error) {
+
+	var report *Growth_Report
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		report, err = get_growth_percentiles(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return report, err
+}
+
+//=================================================================================================================================
+//	 GetImmunizationStatus - Returns the member's doses given, due, overdue and upcoming against the schedules loaded.
+//=================================================================================================================================
+func (c *QueryContract) GetImmunizationStatus(ctx contractapi.TransactionContextInterface, ILNSID string) (*Immunization_Status, error) {
+
+	var status *Immunization_Status
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		status, err = get_immunization_status(stub, m, caller, caller_affiliation)
+		return err
+	})
+
+	return status, err
+}
+
+//=================================================================================================================================
+//	 GetOverdueMembers - Returns the members the caller may see that are overdue for a dose of the vaccine.
+//=================================================================================================================================
+func (c *QueryContract) GetOverdueMembers(ctx contractapi.TransactionContextInterface, vaccine string) (*Overdue_Report, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return get_overdue_members(new_caller_stub(ctx, caller), caller, caller_affiliation, vaccine)
+}
+
+//=================================================================================================================================
+//	 ProveFields - Returns a Merkle proof of the named fields of the member against the fields root on its stub.
+//=================================================================================================================================
+func (c *QueryContract) ProveFields(ctx contractapi.TransactionContextInterface, ILNSID string, fields []string) (*disclosure.Proof, error) {
+
+	var proof *disclosure.Proof
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		proof, err = prove_fields(stub, m, caller, caller_affiliation, fields)
+		return err
+	})
+
+	return proof, err
+}
+
+//=================================================================================================================================
+//	 GetFieldsRoot - Returns the fields root of the member, that proofs of its fields are checked against.
+//=================================================================================================================================
+func (c *QueryContract) GetFieldsRoot(ctx contractapi.TransactionContextInterface, ILNSID string) (*Member_Root, error) {
+
+	var root *Member_Root
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+		var err error
+		root, err = get_fields_root(m)
+		return err
+	})
+
+	return root, err
+}
+
+//=================================================================================================================================
+//	 VerifyCredential - Checks a credential against its anchor on the ledger. Open to every caller.
+//=================================================================================================================================
+func (c *QueryContract) VerifyCredential(ctx contractapi.TransactionContextInterface, jwt string) (*Credential_Verification, error) {
+
+	if _, _, err := get_caller_data(ctx); err != nil {
+		return nil, err
+	}
+
+	return verify_credential(ctx.GetStub(), jwt)
+}
+
+//=================================================================================================================================
+//	 CheckImport - Validates records exactly as member:ImportMembers would without creating anything.
+//=================================================================================================================================
+func (c *QueryContract) CheckImport(ctx contractapi.TransactionContextInterface, records string, mode string) (*Import_Report, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == "" {
+		mode = ALL_OR_NOTHING
+	}
+
+	return check_import(new_caller_stub(ctx, caller), caller, caller_affiliation, records, mode)
+}
+
+//=================================================================================================================================
+//	 GetImportReport - Returns the report stored by the import with the transaction ID given.
+//=================================================================================================================================
+func (c *QueryContract) GetImportReport(ctx contractapi.TransactionContextInterface, tx_ID string) (*Import_Report, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return get_import_report(ctx.GetStub(), caller_affiliation, tx_ID)
+}
+
+//=================================================================================================================================
+//	 ExportState - Returns one page of the export as JSON lines. bookmark is empty for the first page and page_size is 0
+//				   for the default size.
+//=================================================================================================================================
+func (c *QueryContract) ExportState(ctx contractapi.TransactionContextInterface, bookmark string, page_size int) (string, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	size := ""
+
+	if page_size != 0 {
+		size = strconv.Itoa(page_size)
+	}
+
+	page, err := export_state(new_caller_stub(ctx, caller), caller_affiliation, bookmark, size)
+
+	return string(page), err
+}
+
+//=================================================================================================================================
+//	 Ping - Checks the chaincode is running.
+//=================================================================================================================================
+func (c *QueryContract) Ping(ctx contractapi.TransactionContextInterface) string {
+	return "Hello, world!"
+}
+
+//=================================================================================================================================
+//	 DescribeFunctions - Returns every transaction with the schema of its arguments.
+//=================================================================================================================================
+func (c *QueryContract) DescribeFunctions(ctx contractapi.TransactionContextInterface) []Function {
+	return describe_functions()
+}
+
+//=================================================================================================================================
+//	 GetEvaluateTransactions - Every query is evaluated.
+//=================================================================================================================================
+func (c *QueryContract) GetEvaluateTransactions() []string {
+	return []string{"GetMemberDetails", "GetMembers", "GetDataKeys", "GetMemberHistory", "VerifyMemberDetails", "GetObservations", "GetGrowthPercentiles", "GetImmunizationStatus", "GetOverdueMembers", "ProveFields", "GetFieldsRoot", "VerifyCredential", "CheckImport", "GetImportReport", "ExportState", "Ping", "DescribeFunctions"}
+}
@@ -0,0 +1,374 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Participant Holder - Index of the names of all users that have had an eCert stored. Used when exporting participants.
+//						  Orgs maps each name to the organisation of the administrator who registered it, the user's
+//						  organisation, which a member handed over to the user is then held by.
+//==============================================================================================================================
+type Participant_Holder struct {
+	Names          []string          `json:"names"`
+	Orgs           map[string]string `json:"orgs,omitempty" metadata:",optional"`
+	Schema_Version int               `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 RegistryContract - Transactions that manage participants, reference data and the ledger itself.
+//==============================================================================================================================
+type RegistryContract struct {
+	contractapi.Contract
+}
+
+//==============================================================================================================================
+//	 get_ecert - Returns the ecert stored for the user.
+//==============================================================================================================================
+func get_ecert(stub shim.ChaincodeStubInterface, name string) ([]byte, error) {
+
+	ecert, err := stub.GetState(participant_key(name))
+
+	if err != nil {
+		return nil, internal("Couldn't retrieve ecert for user " + name)
+	}
+
+	return ecert, nil
+}
+
+//==============================================================================================================================
+//	 validate_participant_name - eCerts are stored in a namespace of their own, so any name but the empty one may be used.
+//==============================================================================================================================
+func validate_participant_name(name string) error {
+
+	if name == "" {
+		return invalid("name", name, "must not be empty")
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 read_participants - The index of participants, empty before the first is registered.
+//==============================================================================================================================
+func read_participants(stub shim.ChaincodeStubInterface) (Participant_Holder, error) {
+
+	var participants Participant_Holder
+
+	_, err := read_document(stub, DOC_PARTICIPANT_HOLDER, index_key(INDEX_PARTICIPANTS), &participants)
+
+	return participants, err
+}
+
+//==============================================================================================================================
+//	 participant_org - The organisation the user was registered by, empty for users who were not registered.
+//==============================================================================================================================
+func participant_org(stub shim.ChaincodeStubInterface, name string) (string, error) {
+
+	participants, err := read_participants(stub)
+
+	if err != nil {
+		return "", err
+	}
+
+	return participants.Orgs[name], nil
+}
+
+//==============================================================================================================================
+//	 add_ecert - Adds a new ecert and user pair to the table of ecerts, recording the user as a member of org, the
+//				 registering administrator's organisation. A user registered by one organisation may not be registered
+//				 again by another.
+//==============================================================================================================================
+func add_ecert(stub shim.ChaincodeStubInterface, name string, ecert string, org string) error {
+
+	if err := validate_participant_name(name); err != nil {
+		return err
+	}
+
+	participants, err := read_participants(stub)
+
+	if err != nil {
+		return err
+	}
+
+	if registered := participants.Orgs[name]; registered != "" && registered != org {
+		return permission_denied("add_ecert", map[string]interface{}{"name": name, "org": registered, "caller_org": org})
+	}
+
+	err = stub.PutState(participant_key(name), []byte(ecert))
+
+	if err != nil {
+		return internal("Error storing eCert for user " + name + " identity: " + ecert)
+	}
+
+	known := false
+
+	for _, existing := range participants.Names {
+		if existing == name {
+			known = true
+		}
+	}
+
+	if known && participants.Orgs[name] == org {
+		return nil
+	}
+
+	if !known {
+		participants.Names = append(participants.Names, name)
+	}
+
+	if org != "" {
+
+		if participants.Orgs == nil {
+			participants.Orgs = map[string]string{}
+		}
+
+		participants.Orgs[name] = org
+	}
+
+	participants.Schema_Version = schema_version(DOC_PARTICIPANT_HOLDER)
+
+	bytes, err := json.Marshal(participants)
+
+	if err != nil {
+		return internal("Error creating Participant_Holder record")
+	}
+
+	err = stub.PutState(index_key(INDEX_PARTICIPANTS), bytes)
+
+	if err != nil {
+		return internal("Unable to put the state")
+	}
+
+	return nil
+
+}
+
+//=================================================================================================================================
+//	 check_unique_ILNS - Returns true if no member has been created with the ILNSID. Only the member namespace is looked
+//						 at, other entries can not take an ILNSID.
+//=================================================================================================================================
+func check_unique_ILNS(stub shim.ChaincodeStubInterface, ILNS string) (bool, error) {
+
+	_, err := retrieve_ILNS(stub, ILNS)
+
+	if err == nil {
+		return false, conflict("ILNS is not unique", map[string]interface{}{"ILNSID": ILNS})
+	}
+
+	if error_code(err) != NOT_FOUND {
+		return false, err
+	}
+
+	return true, nil
+}
+
+//=================================================================================================================================
+//	 Transactions
+//=================================================================================================================================
+//	 AddEcert - Stores the ecert of a user. Replaces the name/ecert pairs that were passed to Init. Only administrators may
+//				register participants, who are registered as members of the administrator's organisation.
+//=================================================================================================================================
+func (c *RegistryContract) AddEcert(ctx contractapi.TransactionContextInterface, name string, ecert string) error {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if caller_affiliation != ADMIN {
+		return role_required("add_ecert", ADMIN, caller_affiliation)
+	}
+
+	return add_ecert(ctx.GetStub(), name, ecert, get_caller_org(ctx))
+}
+
+//=================================================================================================================================
+//	 GetEcert - Returns the ecert stored for the user.
+//=================================================================================================================================
+func (c *RegistryContract) GetEcert(ctx contractapi.TransactionContextInterface, name string) (string, error) {
+
+	ecert, err := get_ecert(ctx.GetStub(), name)
+
+	return string(ecert), err
+}
+
+//=================================================================================================================================
+//	 CheckUniqueILNS - Returns true if the ILNSID has not been used.
+//=================================================================================================================================
+func (c *RegistryContract) CheckUniqueILNS(ctx contractapi.TransactionContextInterface, ILNSID string) (bool, error) {
+	return check_unique_ILNS(ctx.GetStub(), ILNSID)
+}
+
+//=================================================================================================================================
+//	 SetIDPrefix - Assigns the two letter prefix the ILNSIDs of an organisation are issued under.
+//=================================================================================================================================
+func (c *RegistryContract) SetIDPrefix(ctx contractapi.TransactionContextInterface, org string, prefix string) (*ID_Sequence, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return set_id_prefix(ctx.GetStub(), caller_affiliation, org, prefix)
+}
+
+//=================================================================================================================================
+//	 AllocateILNSID - Issues the next ILNSID of the caller's organisation, to be passed to member:CreateMember.
+//=================================================================================================================================
+func (c *RegistryContract) AllocateILNSID(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return allocate_ILNSID(ctx.GetStub(), caller_affiliation, get_caller_org(ctx))
+}
+
+//=================================================================================================================================
+//	 LoadGrowthReference - Stores a WHO LMS reference table. Only administrators may load reference data.
+//=================================================================================================================================
+func (c *RegistryContract) LoadGrowthReference(ctx contractapi.TransactionContextInterface, reference Growth_Reference) error {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return load_growth_reference(ctx.GetStub(), caller, caller_affiliation, reference)
+}
+
+//=================================================================================================================================
+//	 LoadImmunizationSchedule - Stores the national schedule of a vaccine. Only administrators may load reference data.
+//=================================================================================================================================
+func (c *RegistryContract) LoadImmunizationSchedule(ctx contractapi.TransactionContextInterface, schedule Vaccine_Schedule) error {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return load_immunization_schedule(ctx.GetStub(), caller, caller_affiliation, schedule)
+}
+
+//=================================================================================================================================
+//	 ImportState - Writes the JSON lines of one or more pages of export_state back to the ledger.
+//=================================================================================================================================
+func (c *RegistryContract) ImportState(ctx contractapi.TransactionContextInterface, data string) (*Import_State_Result, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return import_state(new_caller_stub(ctx, caller), caller_affiliation, data)
+}
+
+//=================================================================================================================================
+//	 MigrateRecords - Upgrades stored documents to their current schema version in batches. bookmark is empty for the
+//					  first batch and batch_size is 0 for the default size.
+//=================================================================================================================================
+func (c *RegistryContract) MigrateRecords(ctx contractapi.TransactionContextInterface, bookmark string, batch_size int) (*Migration_Result, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size := ""
+
+	if batch_size != 0 {
+		size = strconv.Itoa(batch_size)
+	}
+
+	return migrate_records(ctx.GetStub(), caller_affiliation, bookmark, size)
+}
+
+//=================================================================================================================================
+//	 RekeyState - Moves entries stored under the keys used before namespaces to their namespaced keys in batches.
+//				  bookmark is empty for the first batch and batch_size is 0 for the default size.
+//=================================================================================================================================
+func (c *RegistryContract) RekeyState(ctx contractapi.TransactionContextInterface, bookmark string, batch_size int) (*Rekey_Result, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size := ""
+
+	if batch_size != 0 {
+		size = strconv.Itoa(batch_size)
+	}
+
+	return rekey_state(ctx.GetStub(), caller_affiliation, bookmark, size)
+}
+
+//=================================================================================================================================
+//	 SetOrgKey - Registers the X25519 public key the data keys of members are wrapped for an organisation.
+//=================================================================================================================================
+func (c *RegistryContract) SetOrgKey(ctx contractapi.TransactionContextInterface, org string, public_key string) (*Org_Key, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return set_org_key(ctx.GetStub(), caller_affiliation, get_caller_org(ctx), org, public_key)
+}
+
+//=================================================================================================================================
+//	 SetIssuerKey - Registers the Ed25519 public key an organisation signs verifiable credentials with.
+//=================================================================================================================================
+func (c *RegistryContract) SetIssuerKey(ctx contractapi.TransactionContextInterface, org string, public_key string) (*Issuer_Key, error) {
+
+	_, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return set_issuer_key(ctx.GetStub(), caller_affiliation, org, public_key)
+}
+
+//=================================================================================================================================
+//	 RotateKeys - Re-encrypts members under new data keys in batches, with their current data keys passed in the transient
+//				  map. bookmark is empty for the first batch and batch_size is 0 for the default size.
+//=================================================================================================================================
+func (c *RegistryContract) RotateKeys(ctx contractapi.TransactionContextInterface, bookmark string, batch_size int) (*Rotation_Result, error) {
+
+	caller, caller_affiliation, err := get_caller_data(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size := ""
+
+	if batch_size != 0 {
+		size = strconv.Itoa(batch_size)
+	}
+
+	return rotate_keys(new_caller_stub(ctx, caller), caller_affiliation, bookmark, size)
+}
+
+//=================================================================================================================================
+//	 GetEvaluateTransactions - The read only transactions of the registry.
+//=================================================================================================================================
+func (c *RegistryContract) GetEvaluateTransactions() []string {
+	return []string{"GetEcert", "CheckUniqueILNS"}
+}
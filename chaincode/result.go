@@ -0,0 +1,132 @@
+package chaincode
+
+import (
+	"reflect"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+//==============================================================================================================================
+//	 Invoke_Result - Returned by every invoke that creates or changes a member so that clients see the record as it now
+//					 stands without a second query. Changed lists the member fields the invoke wrote, by their JSON
+//					 names, and Events the names of the chaincode events it emitted.
+//
+//	{"ILNSID":"AB12345679","status":1,"custodian":"bob","txID":"tx3","changed":["name","status"],"events":[]}
+//==============================================================================================================================
+type Invoke_Result struct {
+	ILNSID    string   `json:"ILNSID"`
+	Status    int      `json:"status"`
+	Custodian string   `json:"custodian"`
+	Dead      bool     `json:"dead"`
+	Tx_ID     string   `json:"txID"`
+	Changed   []string `json:"changed"`
+	Events    []string `json:"events"`
+}
+
+//==============================================================================================================================
+//	 MEMBER_FIELDS - The fields of a member an invoke may change, by JSON name, in the order they are reported.
+//==============================================================================================================================
+var MEMBER_FIELDS = []string{"name", "DOB", "gender", "BloodGrp", "Weight", "status", "dead", "parents", "diagnoses", "notes", "erased"}
+
+//==============================================================================================================================
+//	 member_field - The value of one of the MEMBER_FIELDS of m.
+//==============================================================================================================================
+func member_field(m Member, field string) interface{} {
+
+	switch field {
+	case "name":
+		return m.Name
+	case "DOB":
+		return m.DOB
+	case "gender":
+		return m.Gender
+	case "BloodGrp":
+		return m.BloodGrp
+	case "Weight":
+		return m.Weight
+	case "status":
+		return m.Status
+	case "dead":
+		return m.Dead
+	case "parents":
+		return m.Parents
+	case "diagnoses":
+		return m.Diagnoses
+	case "notes":
+		return m.Notes
+	case "erased":
+		return m.Erased
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 changed_fields - The MEMBER_FIELDS that differ between before and after.
+//==============================================================================================================================
+func changed_fields(before Member, after Member) []string {
+
+	changed := []string{}
+
+	for _, field := range MEMBER_FIELDS {
+		if !reflect.DeepEqual(member_field(before, field), member_field(after, field)) {
+			changed = append(changed, field)
+		}
+	}
+
+	return changed
+}
+
+//==============================================================================================================================
+//	 new_result - The result of an invoke that left m as given, having changed the fields listed.
+//==============================================================================================================================
+func new_result(stub shim.ChaincodeStubInterface, m Member, changed []string) *Invoke_Result {
+
+	return &Invoke_Result{
+		ILNSID:    m.ILNSID,
+		Status:    m.Status,
+		Custodian: m.Name,
+		Dead:      m.Dead,
+		Tx_ID:     stub.GetTxID(),
+		Changed:   changed,
+		Events:    []string{},
+	}
+}
+
+//==============================================================================================================================
+//	 change_member - Runs a transaction that changes the member, as with_member, and returns the member as fn left it.
+//					 The ledger does not return writes made earlier in the transaction so the result is built from the
+//					 member fn changed rather than read back. The event is emitted whether or not a field of the
+//					 member changed, with the fields that did; an observation of height, say, changes none. Erased
+//					 members can not be changed.
+//==============================================================================================================================
+func change_member(ctx contractapi.TransactionContextInterface, ILNSID string, event string, fn func(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string) error) (*Invoke_Result, error) {
+
+	var result *Invoke_Result
+
+	err := with_member(ctx, ILNSID, func(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string) error {
+
+		if m.Erased {
+			return erased_state(m)
+		}
+
+		before := m
+
+		err := fn(stub, &m, caller, caller_affiliation)
+
+		if err != nil {
+			return err
+		}
+
+		result = new_result(stub, m, changed_fields(before, m))
+
+		return emit_member_event(stub, get_caller_org(ctx), event, &before, m, result)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package chaincode_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+)
+
+const SCENARIO_DIR = "testdata/scenarios"
+
+func TestScenarios(t *testing.T) {
+
+	scenarios, err := chaincodetest.LoadScenarios(SCENARIO_DIR)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scenarios) == 0 {
+		t.Fatal("no scenarios found in " + SCENARIO_DIR)
+	}
+
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := sc.Run(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Every transaction that changes a member must have at least one scenario step where it is refused.
+func TestScenariosCoverPermissionDenied(t *testing.T) {
+
+	scenarios, err := chaincodetest.LoadScenarios(SCENARIO_DIR)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	denied := map[string]bool{}
+
+	for _, sc := range scenarios {
+		for _, step := range sc.Steps {
+			if strings.Contains(strings.ToLower(step.Expect.Error), "permission denied") {
+				denied[step.Invoke+step.Query] = true
+			}
+		}
+	}
+
+	h, _ := (&chaincodetest.Scenario{}).Harness()
+
+	for _, function := range h.Functions() {
+
+		contract := function[:strings.Index(function, ":")]
+
+		if contract == "query" || strings.HasPrefix(function, "registry:Get") || strings.HasPrefix(function, "registry:Check") {
+			continue
+		}
+
+		if !denied[function] {
+			t.Errorf("no scenario covers %s being refused", function)
+		}
+	}
+}
+
+// replay runs the steps of a scenario a few at a time, for tests that check what a step can not express.
+type replay struct {
+	t     *testing.T
+	h     *chaincodetest.Harness
+	steps []chaincodetest.Step
+}
+
+// replay_scenario starts the scenario in file, under SCENARIO_DIR, on a fresh ledger.
+func replay_scenario(t *testing.T, file string) *replay {
+
+	sc, err := chaincodetest.LoadScenario(filepath.Join(SCENARIO_DIR, file))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := sc.Harness()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &replay{t, h, sc.Steps}
+}
+
+// until runs the steps left up to and including the one named name.
+func (r *replay) until(name string) {
+
+	for len(r.steps) > 0 {
+
+		step := r.steps[0]
+		r.steps = r.steps[1:]
+
+		if err := r.h.RunStep(step); err != nil {
+			r.t.Fatalf("%s: %v", step.Label(), err)
+		}
+
+		if step.Name == name {
+			return
+		}
+	}
+
+	r.t.Fatalf("no step named %q", name)
+}
+
+// identity is the caller named username in the scenario.
+func (r *replay) identity(username string) *chaincodetest.Identity {
+
+	id, err := r.h.Identity(username)
+
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	return id
+}
@@ -0,0 +1,356 @@
+package chaincode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Field formats - DOB is an ISO-8601 calendar date, blood group is ABO + Rh and gender follows the administrative-gender
+//					 value set (male | female | other | unknown).
+//==============================================================================================================================
+const DOB_LAYOUT = "2006-01-02"
+
+const UNDEFINED = "UNDEFINED"
+
+var BLOOD_GROUPS = []string{"A+", "A-", "B+", "B-", "AB+", "AB-", "O+", "O-"}
+
+var GENDERS = []string{"male", "female", "other", "unknown"}
+
+var WEIGHT_UNITS = map[string]float64{"kg": 1, "g": 0.001}
+
+//==============================================================================================================================
+//	 Clinical fields - Diagnoses are ICD-10 codes e.g. E11.9, notes are free text of up to MAX_NOTES_LENGTH characters.
+//==============================================================================================================================
+var icd10_format = regexp.MustCompile(`^[A-Z][0-9][0-9A-Z](\.[0-9A-Z]{1,4})?$`)
+
+const MAX_NOTES_LENGTH = 2000
+
+//==============================================================================================================================
+//	 Weight bounds - Plausible weight range in kg for the age of the member. The first entry whose maximum age (in days)
+//					 is not exceeded applies. Members without a DOB are checked against the widest range.
+//==============================================================================================================================
+type Weight_Bound struct {
+	Max_Age_Days int
+	Min_Kg       float64
+	Max_Kg       float64
+}
+
+var WEIGHT_BOUNDS = []Weight_Bound{
+	{28, 0.3, 7},       // neonate
+	{365, 0.3, 20},     // infant
+	{5 * 365, 1, 40},   // toddler / pre-school
+	{18 * 365, 2, 200}, // child / adolescent
+	{-1, 2, 650},       // adult
+}
+
+//==============================================================================================================================
+//	 Weight - Weight of the member as a decimal value with an explicit unit (kg or g).
+//==============================================================================================================================
+type Weight struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+//==============================================================================================================================
+//	 Kg - Returns the weight converted to kilograms.
+//==============================================================================================================================
+func (w Weight) Kg() float64 {
+	return w.Value * WEIGHT_UNITS[w.Unit]
+}
+
+//==============================================================================================================================
+//	 Validation_Error - Structured error returned when a value passed for a field is rejected. Error() renders it as a
+//						VALIDATION_FAILED Chaincode_Error whose details name the field, the value and the reason.
+//==============================================================================================================================
+type Validation_Error struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (e *Validation_Error) Error() string {
+
+	ce := Chaincode_Error{
+		Code:    VALIDATION_FAILED,
+		Message: "Invalid value passed for " + e.Field + ": " + e.Reason,
+		Details: map[string]interface{}{"field": e.Field, "value": e.Value, "reason": e.Reason},
+	}
+
+	return ce.Error()
+}
+
+func invalid(field string, value string, reason string) error {
+	return &Validation_Error{Field: field, Value: value, Reason: reason}
+}
+
+//==============================================================================================================================
+//	 get_tx_time - Returns the timestamp of the current transaction. The transaction time is used rather than the local
+//				   clock so that every peer reaches the same result.
+//==============================================================================================================================
+func get_tx_time(stub shim.ChaincodeStubInterface) (time.Time, error) {
+
+	ts, err := stub.GetTxTimestamp()
+
+	if err != nil || ts == nil {
+		return time.Time{}, internal("Couldn't get transaction timestamp")
+	}
+
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+//==============================================================================================================================
+//	 parse_DOB - Parses an ISO-8601 calendar date (YYYY-MM-DD).
+//==============================================================================================================================
+func parse_DOB(value string) (time.Time, error) {
+
+	dob, err := time.Parse(DOB_LAYOUT, value)
+
+	if err != nil {
+		return dob, invalid("DOB", value, "must be an ISO-8601 date (YYYY-MM-DD)")
+	}
+
+	return dob, nil
+}
+
+//==============================================================================================================================
+//	 validate_DOB - Checks the DOB is a valid date, is not in the future and is not before the DOB of any of the
+//					member's parents that have one recorded.
+//==============================================================================================================================
+func validate_DOB(stub shim.ChaincodeStubInterface, m Member, value string) error {
+
+	return validate_DOB_with(stub, m, value, func(ILNSID string) (Member, error) { return retrieve_ILNS(stub, ILNSID) })
+}
+
+//==============================================================================================================================
+//	 validate_DOB_with - As validate_DOB, parents are looked up with the function passed so that members that have not
+//						 been written yet (e.g. earlier rows of an import) can be found.
+//==============================================================================================================================
+func validate_DOB_with(stub shim.ChaincodeStubInterface, m Member, value string, lookup func(string) (Member, error)) error {
+
+	dob, err := parse_DOB(value)
+
+	if err != nil {
+		return err
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	if dob.After(now) {
+		return invalid("DOB", value, "must not be in the future")
+	}
+
+	for _, parent_ID := range m.Parents {
+
+		parent, err := lookup(parent_ID)
+
+		if err != nil {
+			return invalid("DOB", value, "parent "+parent_ID+" could not be retrieved")
+		}
+
+		if parent.DOB == UNDEFINED {
+			continue
+		}
+
+		if parent.DOB == ENCRYPTED {
+			return invalid("DOB", value, "the DOB of parent "+parent_ID+" is encrypted, pass its data key in "+TRANSIENT_DATA_KEYS)
+		}
+
+		parent_dob, err := parse_DOB(parent.DOB)
+
+		if err == nil && dob.Before(parent_dob) {
+			return invalid("DOB", value, "must not be before the DOB of parent "+parent_ID+" ("+parent.DOB+")")
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 validate_BloodGrp - Checks the value is an ABO group with an Rh sign e.g. AB+ or O-.
+//==============================================================================================================================
+func validate_BloodGrp(value string) error {
+
+	for _, grp := range BLOOD_GROUPS {
+		if value == grp {
+			return nil
+		}
+	}
+
+	return invalid("BloodGrp", value, "must be one of "+strings.Join(BLOOD_GROUPS, ", "))
+}
+
+//==============================================================================================================================
+//	 validate_gender - Checks the value is a member of the administrative-gender value set.
+//==============================================================================================================================
+func validate_gender(value string) error {
+
+	for _, gender := range GENDERS {
+		if value == gender {
+			return nil
+		}
+	}
+
+	return invalid("gender", value, "must be one of "+strings.Join(GENDERS, ", "))
+}
+
+//==============================================================================================================================
+//	 parse_Weight - Parses a decimal weight followed by its unit e.g. "3.45kg" or "3450 g".
+//==============================================================================================================================
+var weight_format = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?)\s*(kg|g)$`)
+
+func parse_Weight(value string) (Weight, error) {
+
+	var w Weight
+
+	parts := weight_format.FindStringSubmatch(strings.TrimSpace(value))
+
+	if parts == nil {
+		return w, invalid("Weight", value, "must be a decimal number followed by a unit of kg or g")
+	}
+
+	amount, err := strconv.ParseFloat(parts[1], 64)
+
+	if err != nil {
+		return w, invalid("Weight", value, "must be a decimal number followed by a unit of kg or g")
+	}
+
+	w.Value = amount
+	w.Unit = parts[3]
+
+	return w, nil
+}
+
+//==============================================================================================================================
+//	 validate_Weight - Parses the weight and checks it is plausible for the age of the member at the time of the
+//					   transaction.
+//==============================================================================================================================
+func validate_Weight(stub shim.ChaincodeStubInterface, m Member, value string) (Weight, error) {
+
+	w, err := parse_Weight(value)
+
+	if err != nil {
+		return w, err
+	}
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return w, err
+	}
+
+	return w, check_Weight(m, w, now, value)
+}
+
+//==============================================================================================================================
+//	 check_Weight - Checks the weight is within the plausible range for the age of the member at the time given.
+//==============================================================================================================================
+func check_Weight(m Member, w Weight, at time.Time, value string) error {
+
+	bound := WEIGHT_BOUNDS[len(WEIGHT_BOUNDS)-1]
+
+	if m.DOB != UNDEFINED && m.DOB != ENCRYPTED { // Without the DOB the widest range applies
+
+		dob, err := parse_DOB(m.DOB)
+
+		if err == nil {
+
+			age_days := int(at.Sub(dob).Hours() / 24)
+
+			for _, b := range WEIGHT_BOUNDS {
+				if b.Max_Age_Days < 0 || age_days <= b.Max_Age_Days {
+					bound = b
+					break
+				}
+			}
+		}
+	}
+
+	kg := w.Kg()
+
+	if kg < bound.Min_Kg || kg > bound.Max_Kg {
+		return invalid("Weight", value, fmt.Sprintf("must be between %g kg and %g kg for the age of the member", bound.Min_Kg, bound.Max_Kg))
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 parse_diagnoses - Parses a comma separated list of ICD-10 codes. The empty string is no diagnoses.
+//==============================================================================================================================
+func parse_diagnoses(value string) ([]string, error) {
+
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	diagnoses := []string{}
+
+	for _, code := range strings.Split(value, ",") {
+
+		code = strings.TrimSpace(code)
+
+		if !icd10_format.MatchString(code) {
+			return nil, invalid("diagnoses", value, "must be a comma separated list of ICD-10 codes e.g. E11.9,J45")
+		}
+
+		diagnoses = append(diagnoses, code)
+	}
+
+	return diagnoses, nil
+}
+
+//==============================================================================================================================
+//	 validate_notes - Checks the notes are not too long and are not the marker shown in place of encrypted fields.
+//==============================================================================================================================
+func validate_notes(value string) error {
+
+	if utf8.RuneCountInString(value) > MAX_NOTES_LENGTH {
+		return invalid("notes", value, fmt.Sprintf("must be at most %d characters", MAX_NOTES_LENGTH))
+	}
+
+	if value == ENCRYPTED {
+		return invalid("notes", value, "must not be "+ENCRYPTED)
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 ValidateField - Checks a value for one of the PATCH_FIELDS with the rules that need no ledger: the format of DOB,
+//					 Weight and diagnoses, the value sets of gender and BloodGrp and the length of notes. The chaincode
+//					 goes on to check dates and weights against the transaction time and the member's parents.
+//==============================================================================================================================
+func ValidateField(field string, value string) error {
+
+	var err error
+
+	switch field {
+	case "DOB":
+		_, err = parse_DOB(value)
+	case "gender":
+		err = validate_gender(value)
+	case "BloodGrp":
+		err = validate_BloodGrp(value)
+	case "Weight":
+		_, err = parse_Weight(value)
+	case "diagnoses":
+		_, err = parse_diagnoses(value)
+	case "notes":
+		err = validate_notes(value)
+	default:
+		err = invalid(field, value, "unknown or read-only field, must be one of "+strings.Join(PATCH_FIELDS, ", "))
+	}
+
+	return err
+}
@@ -0,0 +1,342 @@
+package chaincode
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+//==============================================================================================================================
+//	 Observation types - Each type of vital sign that can be recorded against a member, with the units accepted for it.
+//==============================================================================================================================
+const OBS_WEIGHT = "weight"
+const OBS_HEIGHT = "height"
+const OBS_HEAD_CIRCUMFERENCE = "head_circumference"
+const OBS_TEMPERATURE = "temperature"
+const OBS_BLOOD_PRESSURE = "blood_pressure"
+const OBS_HEART_RATE = "heart_rate"
+
+var OBSERVATION_UNITS = map[string][]string{
+	OBS_WEIGHT:             {"kg", "g"},
+	OBS_HEIGHT:             {"cm", "m"},
+	OBS_HEAD_CIRCUMFERENCE: {"cm"},
+	OBS_TEMPERATURE:        {"C", "F"},
+	OBS_BLOOD_PRESSURE:     {"mmHg"},
+	OBS_HEART_RATE:         {"bpm"},
+}
+
+//==============================================================================================================================
+//	 Observation - A single timestamped measurement. Blood pressure uses Systolic and Diastolic, every other type uses
+//				   Value. Effective is when the measurement was taken, Recorded_By and Tx_ID record who added it.
+//==============================================================================================================================
+type Observation struct {
+	Type        string  `json:"type"`
+	Value       float64 `json:"value,omitempty" metadata:",optional"`
+	Systolic    float64 `json:"systolic,omitempty" metadata:",optional"`
+	Diastolic   float64 `json:"diastolic,omitempty" metadata:",optional"`
+	Unit        string  `json:"unit"`
+	Effective   string  `json:"effective" metadata:",optional"`
+	Recorded_By string  `json:"recordedBy" metadata:",optional"`
+	Tx_ID       string  `json:"txID" metadata:",optional"`
+}
+
+//==============================================================================================================================
+//	 Vitals_Series - All observations recorded for a member, ordered by effective time. Stored in its clinical collections
+//					 under vitals:<ILNSID>, with its hash on the member's stub. Redacted is set, and no observations
+//					 are given, when the caller may not read the member's details.
+//==============================================================================================================================
+type Vitals_Series struct {
+	ILNSID         string        `json:"ILNSID"`
+	Observations   []Observation `json:"observations"`
+	Redacted       bool          `json:"redacted,omitempty" metadata:",optional"`
+	Schema_Version int           `json:"schemaVersion"`
+}
+
+//==============================================================================================================================
+//	 effective_time - Parses the effective time of an observation. Accepts RFC 3339 timestamps or ISO-8601 dates.
+//==============================================================================================================================
+func effective_time(value string) (time.Time, error) {
+
+	at, err := time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		at, err = time.Parse(DOB_LAYOUT, value)
+	}
+
+	if err != nil {
+		return at, invalid("effective", value, "must be an RFC 3339 timestamp or ISO-8601 date")
+	}
+
+	return at.UTC(), nil
+}
+
+//==============================================================================================================================
+//	 normalised_value - Returns the value of an observation in the base unit for its type (kg, cm, C).
+//==============================================================================================================================
+func normalised_value(o Observation) float64 {
+
+	switch o.Unit {
+	case "g":
+		return o.Value / 1000
+	case "m":
+		return o.Value * 100
+	case "F":
+		return (o.Value - 32) * 5 / 9
+	}
+
+	return o.Value
+}
+
+//==============================================================================================================================
+//	 in_range - Returns a validation error unless min <= value <= max.
+//==============================================================================================================================
+func in_range(field string, value float64, min float64, max float64, unit string) error {
+
+	if value < min || value > max {
+		return invalid(field, fmt.Sprintf("%g", value), fmt.Sprintf("must be between %g %s and %g %s", min, unit, max, unit))
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 validate_observation - Checks the type, unit and effective time of an observation and that its value is
+//							physiologically plausible.
+//==============================================================================================================================
+func validate_observation(m Member, o Observation, at time.Time, now time.Time) error {
+
+	units, ok := OBSERVATION_UNITS[o.Type]
+
+	if !ok {
+		return invalid("type", o.Type, "unknown observation type")
+	}
+
+	unit_ok := false
+
+	for _, unit := range units {
+		if o.Unit == unit {
+			unit_ok = true
+		}
+	}
+
+	if !unit_ok {
+		return invalid("unit", o.Unit, fmt.Sprintf("unit for %s must be one of %v", o.Type, units))
+	}
+
+	if at.After(now) {
+		return invalid("effective", o.Effective, "must not be in the future")
+	}
+
+	if m.DOB != UNDEFINED {
+		dob, err := parse_DOB(m.DOB)
+		if err == nil && at.Before(dob) {
+			return invalid("effective", o.Effective, "must not be before the DOB of the member")
+		}
+	}
+
+	switch o.Type {
+	case OBS_WEIGHT:
+		return check_Weight(m, Weight{Value: o.Value, Unit: o.Unit}, at, fmt.Sprintf("%g%s", o.Value, o.Unit))
+	case OBS_HEIGHT:
+		return in_range("value", normalised_value(o), 20, 272, "cm")
+	case OBS_HEAD_CIRCUMFERENCE:
+		return in_range("value", normalised_value(o), 20, 70, "cm")
+	case OBS_TEMPERATURE:
+		return in_range("value", normalised_value(o), 25, 45, "C")
+	case OBS_HEART_RATE:
+		return in_range("value", o.Value, 20, 300, "bpm")
+	case OBS_BLOOD_PRESSURE:
+		if err := in_range("systolic", o.Systolic, 40, 300, "mmHg"); err != nil {
+			return err
+		}
+		if err := in_range("diastolic", o.Diastolic, 20, 200, "mmHg"); err != nil {
+			return err
+		}
+		if o.Diastolic >= o.Systolic {
+			return invalid("diastolic", fmt.Sprintf("%g", o.Diastolic), "must be lower than systolic")
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 retrieve_vitals - Gets the vitals series for a member. Members without any observations get an empty series, callers
+//					   who may not read the member's details a redacted one.
+//==============================================================================================================================
+func retrieve_vitals(stub shim.ChaincodeStubInterface, m Member) (Vitals_Series, error) {
+
+	series := Vitals_Series{ILNSID: m.ILNSID, Observations: []Observation{}}
+
+	readable, err := read_clinical(stub, m, DOC_VITALS, vitals_key(m.ILNSID), m.Vitals_Hash, &series)
+
+	if err != nil {
+		return series, internal("RETRIEVE_VITALS: " + error_message(err))
+	}
+
+	if !readable {
+		series.Observations = []Observation{}
+		series.Redacted = true
+	}
+
+	return series, nil
+}
+
+//==============================================================================================================================
+//	 save_vitals - Saves the vitals series for a member to its clinical collections, see save_clinical, and sets its hash
+//				   on the member, which the caller then saves. A redacted series may not be written.
+//==============================================================================================================================
+func save_vitals(stub shim.ChaincodeStubInterface, m *Member, series Vitals_Series) error {
+
+	if series.Redacted {
+		return details_denied("save_vitals", *m)
+	}
+
+	series.Schema_Version = schema_version(DOC_VITALS)
+
+	hash, err := save_clinical(stub, *m, DOC_VITALS, vitals_key(m.ILNSID), m.Vitals_Hash, series)
+
+	if err != nil {
+		return err
+	}
+
+	m.Vitals_Hash = hash
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 add_observation - Validates the observation and inserts it into the member's series in effective time order. If it
+//					   is the most recent weight the member's current Weight is updated to match. The member and the
+//					   series are not saved, the caller is responsible for writing both.
+//==============================================================================================================================
+func add_observation(stub shim.ChaincodeStubInterface, m *Member, series *Vitals_Series, caller string, o Observation) error {
+
+	now, err := get_tx_time(stub)
+
+	if err != nil {
+		return err
+	}
+
+	at := now
+
+	if o.Effective != "" {
+		at, err = effective_time(o.Effective)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validate_observation(*m, o, at, now)
+
+	if err != nil {
+		return err
+	}
+
+	o.Effective = at.Format(time.RFC3339)
+	o.Recorded_By = caller
+	o.Tx_ID = stub.GetTxID()
+
+	pos := sort.Search(len(series.Observations), func(i int) bool {
+		other, _ := effective_time(series.Observations[i].Effective)
+		return other.After(at)
+	})
+
+	series.Observations = append(series.Observations, Observation{})
+	copy(series.Observations[pos+1:], series.Observations[pos:])
+	series.Observations[pos] = o
+
+	if o.Type == OBS_WEIGHT {
+
+		latest := true
+
+		for _, other := range series.Observations[pos+1:] {
+			if other.Type == OBS_WEIGHT {
+				latest = false
+			}
+		}
+
+		if latest {
+			m.Weight = Weight{Value: o.Value, Unit: o.Unit}
+		}
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 record_observation - Adds a timestamped vital sign to the member's series. The same people who may update the
+//						  member's weight may record observations.
+//=================================================================================================================================
+func record_observation(stub shim.ChaincodeStubInterface, m *Member, caller string, caller_affiliation string, o Observation) error {
+
+	if m.Dead {
+		return invalid_state("record_observation", *m, -1)
+	}
+
+	if m.Name != caller || caller_affiliation == DEATH {
+		return custodian_denied("record_observation", *m, caller, caller_affiliation)
+	}
+
+	series, err := retrieve_vitals(stub, *m)
+
+	if err != nil {
+		return err
+	}
+
+	err = add_observation(stub, m, &series, caller, o)
+
+	if err != nil {
+		return err
+	}
+
+	err = save_vitals(stub, m, series)
+
+	if err != nil {
+		fmt.Printf("RECORD_OBSERVATION: Error saving vitals: %s", err)
+		return save_failed(err)
+	}
+
+	err = save_changes(stub, *m)
+
+	if err != nil {
+		fmt.Printf("RECORD_OBSERVATION: Error saving changes: %s", err)
+		return save_failed(err)
+	}
+
+	return nil
+}
+
+//=================================================================================================================================
+//	 get_observations - Returns the vitals series of the member, optionally filtered to one observation type. Visible to
+//						the same callers as get_member_details.
+//=================================================================================================================================
+func get_observations(stub shim.ChaincodeStubInterface, m Member, caller string, caller_affiliation string, obs_type string) (*Vitals_Series, error) {
+
+	if !can_view(stub, m, caller, caller_affiliation) {
+		return nil, view_denied("get_observations", m)
+	}
+
+	series, err := retrieve_vitals(stub, m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if obs_type != "" {
+
+		filtered := []Observation{}
+
+		for _, o := range series.Observations {
+			if o.Type == obs_type {
+				filtered = append(filtered, o)
+			}
+		}
+
+		series.Observations = filtered
+	}
+
+	return &series, nil
+}
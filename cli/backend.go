@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/ravivarmakv/SampleChainCode/rest"
+)
+
+//==============================================================================================================================
+//	 Ledger_Backend - Runs transactions on a mock ledger kept in a file, for working offline. The ledger is loaded before
+//					  every transaction and saved after every submit, so each invocation of the CLI carries on from the
+//					  last. Callers are given the role they ask for.
+//==============================================================================================================================
+type Ledger_Backend struct {
+	Path string
+	Mock *rest.Mock_Backend
+}
+
+func NewLedgerBackend(path string) *Ledger_Backend {
+	return &Ledger_Backend{Path: path, Mock: rest.NewMockBackend()}
+}
+
+func (b *Ledger_Backend) Submit(ctx context.Context, caller rest.Caller, function string, args ...string) ([]byte, error) {
+
+	if _, err := b.Mock.Harness.Stub.LoadLedger(b.Path); err != nil {
+		return nil, err
+	}
+
+	payload, err := b.Mock.Submit(ctx, caller, function, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, b.Mock.Harness.Stub.SaveLedger(b.Path)
+}
+
+func (b *Ledger_Backend) Evaluate(ctx context.Context, caller rest.Caller, function string, args ...string) ([]byte, error) {
+
+	if _, err := b.Mock.Harness.Stub.LoadLedger(b.Path); err != nil {
+		return nil, err
+	}
+
+	return b.Mock.Evaluate(ctx, caller, function, args...)
+}
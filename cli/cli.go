@@ -0,0 +1,252 @@
+// Package cli is the medhist command line client. Its subcommands mirror the chaincode's transactions, check their
+// arguments with the chaincode's own rules before anything is sent, and run them on a pluggable rest.Backend: a mock
+// ledger kept in a file for working offline, or a peer through its Fabric Gateway.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ravivarmakv/SampleChainCode/docstore"
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+	"github.com/ravivarmakv/SampleChainCode/rest"
+)
+
+const BACKEND_MOCK = "mock"
+const BACKEND_FABRIC = "fabric"
+
+const OUTPUT_TABLE = "table"
+const OUTPUT_JSON = "json"
+
+//==============================================================================================================================
+//	 Exit codes - 1 when a transaction fails, 2 when the command line is wrong or the backend can not be set up.
+//==============================================================================================================================
+const EXIT_FAILED = 1
+const EXIT_USAGE = 2
+
+const USAGE = `Usage: medhist [flags] <command> [arguments]
+
+Commands:
+  member create [ILNSID] [-parents ID,ID]
+  member show <ILNSID>
+  member transition <ILNSID> <transition> [recipient]
+  member update <ILNSID> <field>=<value>...
+  member erase <ILNSID>
+  member list [-status name]
+  consent grant <ILNSID> <grantee> [-expires RFC3339]
+  document attach <ILNSID> <file> -type type [-mime type] [-episode id]
+  document verify <ILNSID> <file>
+  proof create <ILNSID> <field>... [-o file]
+  proof verify <file>
+  credential issue <ILNSID> <type> [-expires RFC3339] [-o file]
+  credential verify <file>
+  credential revoke <ILNSID> <id> [-reason text]
+  immunization record <ILNSID> <vaccine> <dose> -lot lot -practitioner name -site site [-date YYYY-MM-DD]
+  immunization status <ILNSID>
+  immunization overdue <vaccine>
+  history <ILNSID>
+  export [-o file]
+
+Flags:
+`
+
+//==============================================================================================================================
+//	 CLI - One invocation. Backend and Store are created from the flags unless set beforehand.
+//==============================================================================================================================
+type CLI struct {
+	Backend rest.Backend
+	Store   docstore.Store
+	Caller  rest.Caller
+	Output  string
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+//==============================================================================================================================
+//	 Main - Runs the command line args (without the program name) and returns the exit code.
+//==============================================================================================================================
+func Main(args []string, stdout io.Writer, stderr io.Writer) int {
+
+	c := &CLI{Stdout: stdout, Stderr: stderr}
+
+	return c.Run(args)
+}
+
+func (c *CLI) Run(args []string) int {
+
+	fs := flag.NewFlagSet("medhist", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.Usage = func() {
+		fmt.Fprint(c.Stderr, USAGE)
+		fs.PrintDefaults()
+	}
+
+	backend_type := fs.String("backend", env("MEDHIST_BACKEND", BACKEND_MOCK), "mock for a ledger file, or fabric for a peer")
+	ledger := fs.String("ledger", env("MEDHIST_LEDGER", "medhist-ledger.json"), "ledger file, for the mock backend")
+	config_path := fs.String("config", env("MEDHIST_CONFIG", "medhist.json"), "peer configuration file, for the fabric backend")
+	wallet := fs.String("wallet", env("MEDHIST_WALLET", "wallet"), "directory of <user>/cert.pem and key.pem, for the fabric backend")
+	store_dir := fs.String("store", env("MEDHIST_STORE", "medhist-documents"), "directory the files of attached documents are stored in")
+	fs.StringVar(&c.Caller.Username, "user", os.Getenv("MEDHIST_USER"), "user to run as")
+	fs.StringVar(&c.Caller.Role, "role", os.Getenv("MEDHIST_ROLE"), "role of the user, for the mock backend")
+	key := fs.String("key", os.Getenv("MEDHIST_KEY"), "base64 private key of the user's organisation, to read and write encrypted fields")
+	fs.StringVar(&c.Caller.Signing_Key, "signing-key", os.Getenv("MEDHIST_SIGNING_KEY"), "base64 Ed25519 private key the user's organisation issues credentials with")
+	fs.StringVar(&c.Output, "output", env("MEDHIST_OUTPUT", OUTPUT_TABLE), "table or json")
+
+	if err := fs.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+
+	if c.Output != OUTPUT_TABLE && c.Output != OUTPUT_JSON {
+		return c.usage_error(errors.New("-output must be " + OUTPUT_TABLE + " or " + OUTPUT_JSON))
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+
+	if c.Caller.Username == "" {
+		return c.usage_error(errors.New("no user given, set -user or MEDHIST_USER"))
+	}
+
+	if c.Backend == nil {
+
+		var err error
+
+		c.Backend, err = new_backend(*backend_type, *ledger, *config_path, *wallet, *key)
+
+		if err != nil {
+			return c.usage_error(err)
+		}
+	}
+
+	if c.Store == nil {
+
+		var err error
+
+		c.Store, err = docstore.NewLocal(*store_dir)
+
+		if err != nil {
+			return c.usage_error(err)
+		}
+	}
+
+	command, ok := find_command(fs.Args())
+
+	if !ok {
+		return c.usage_error(errors.New("unknown command " + strings.Join(fs.Args(), " ")))
+	}
+
+	err := command.run(c, fs.Args()[len(strings.Fields(command.name)):])
+
+	if err != nil {
+		return c.fail(err)
+	}
+
+	return 0
+}
+
+func new_backend(backend_type string, ledger string, config_path string, wallet string, key string) (rest.Backend, error) {
+
+	switch backend_type {
+	case BACKEND_MOCK:
+		return rest.NewDataKeyBackend(NewLedgerBackend(ledger), key), nil
+	case BACKEND_FABRIC:
+
+		bytes, err := os.ReadFile(config_path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var config peer.Config
+
+		if err := json.Unmarshal(bytes, &config); err != nil {
+			return nil, fmt.Errorf("%s: %v", config_path, err)
+		}
+
+		backend, err := rest.NewFabricBackend(config, wallet)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return rest.NewDataKeyBackend(backend, key), nil
+	}
+
+	return nil, errors.New("unknown backend " + backend_type + ", must be " + BACKEND_MOCK + " or " + BACKEND_FABRIC)
+}
+
+//==============================================================================================================================
+//	 Usage_Error - The command line is wrong. Checked before anything is sent.
+//==============================================================================================================================
+type Usage_Error struct {
+	Message string
+}
+
+func (e *Usage_Error) Error() string {
+	return e.Message
+}
+
+func usage(format string, args ...interface{}) error {
+	return &Usage_Error{Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *CLI) usage_error(err error) int {
+
+	fmt.Fprintf(c.Stderr, "medhist: %s\n", err)
+
+	return EXIT_USAGE
+}
+
+//==============================================================================================================================
+//	 fail - Reports an error. Chaincode errors, including those found before sending, are printed as their code and
+//			message, or as the JSON error with -output json.
+//==============================================================================================================================
+func (c *CLI) fail(err error) int {
+
+	var ue *Usage_Error
+
+	if errors.As(err, &ue) {
+		return c.usage_error(err)
+	}
+
+	ce, ok := rest.ParseError(err)
+
+	if !ok {
+		fmt.Fprintf(c.Stderr, "medhist: %s\n", err)
+		return EXIT_FAILED
+	}
+
+	if c.Output == OUTPUT_JSON {
+		fmt.Fprintln(c.Stderr, ce.Error())
+		return EXIT_FAILED
+	}
+
+	fmt.Fprintf(c.Stderr, "medhist: %s: %s\n", ce.Code, ce.Message)
+
+	return EXIT_FAILED
+}
+
+func (c *CLI) submit(function string, args ...string) ([]byte, error) {
+	return c.Backend.Submit(context.Background(), c.Caller, function, args...)
+}
+
+func (c *CLI) evaluate(function string, args ...string) ([]byte, error) {
+	return c.Backend.Evaluate(context.Background(), c.Caller, function, args...)
+}
+
+func env(name string, fallback string) string {
+
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
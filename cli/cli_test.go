@@ -0,0 +1,346 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+	"github.com/ravivarmakv/SampleChainCode/cli"
+	"github.com/ravivarmakv/SampleChainCode/docstore"
+	"github.com/ravivarmakv/SampleChainCode/rest"
+)
+
+// medhist runs a command line on the ledger file as user with role, each time with a new backend as separate
+// invocations would, and returns the exit code, stdout and stderr. Documents are stored beside the ledger.
+func medhist(t *testing.T, ledger string, user string, role string, args ...string) (int, string, string) {
+
+	backend := cli.NewLedgerBackend(ledger)
+	backend.Mock.Now = func() time.Time { return time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC) }
+
+	var stdout, stderr bytes.Buffer
+
+	c := &cli.CLI{Backend: backend, Store: &docstore.Local{Dir: filepath.Join(filepath.Dir(ledger), "documents")}, Stdout: &stdout, Stderr: &stderr}
+
+	code := c.Run(append([]string{"-user", user, "-role", role}, args...))
+
+	return code, stdout.String(), stderr.String()
+}
+
+// seed writes to ledger the ledger left by the scenario in testdata/newborns.json, for the tests of commands that need
+// members to work on.
+func seed(t *testing.T, ledger string) {
+
+	sc, err := chaincodetest.LoadScenario(filepath.Join("testdata", "newborns.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := sc.Harness()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range sc.Steps {
+		if err := h.RunStep(step); err != nil {
+			t.Fatalf("%s: %v", step.Label(), err)
+		}
+	}
+
+	if err := h.Stub.SaveLedger(ledger); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemberCommands(t *testing.T) {
+
+	ledger := filepath.Join(t.TempDir(), "ledger.json")
+
+	steps := []struct {
+		user, role string
+		args       []string
+		want       string
+	}{
+		{"alice", chaincode.PARENTS, []string{"member", "create", "AB12345679"}, "carrying"},
+		{"alice", chaincode.PARENTS, []string{"member", "transition", "AB12345679", "ParentsToBirthday", "bob"}, "bob"},
+		{"bob", chaincode.BIRTHDAY, []string{"member", "update", "AB12345679", "DOB=2024-05-20", "gender=female", "BloodGrp=O+", "Weight=3.4kg"}, "DOB,gender,BloodGrp,Weight"},
+		{"bob", chaincode.BIRTHDAY, []string{"member", "list", "-status", "birth"}, "3.4kg"},
+		{"bob", chaincode.BIRTHDAY, []string{"consent", "grant", "AB12345679", "gina", "-expires", "2025-01-01T00:00:00Z"}, "2025-01-01T00:00:00Z"},
+		{"gina", chaincode.HEALTHY, []string{"member", "show", "AB12345679"}, "O+"},
+		{"alice", chaincode.PARENTS, []string{"history", "AB12345679"}, "birth"},
+	}
+
+	for _, step := range steps {
+
+		code, stdout, stderr := medhist(t, ledger, step.user, step.role, step.args...)
+
+		if code != 0 || !strings.Contains(stdout, step.want) {
+			t.Fatalf("%s: exit %d, want %q in:\n%s%s", strings.Join(step.args, " "), code, step.want, stdout, stderr)
+		}
+	}
+
+	code, stdout, _ := medhist(t, ledger, "alice", chaincode.PARENTS, "-output", "json", "history", "AB12345679")
+
+	var revisions []chaincode.Member_Revision
+
+	if err := json.Unmarshal([]byte(stdout), &revisions); code != 0 || err != nil || len(revisions) != 3 {
+		t.Fatalf("history as JSON: exit %d, %v: %s", code, err, stdout)
+	}
+
+	code, stdout, _ = medhist(t, ledger, "bob", chaincode.BIRTHDAY, "-output", "json", "member", "list", "-status", "healthy")
+
+	if code != 0 || strings.TrimSpace(stdout) != "[]" {
+		t.Fatalf("members in healthy: exit %d: %s", code, stdout)
+	}
+}
+
+func TestCreateAllocatesILNSID(t *testing.T) {
+
+	ledger := filepath.Join(t.TempDir(), "ledger.json")
+
+	admin := rest.Caller{Username: "root", Role: chaincode.ADMIN}
+
+	if _, err := cli.NewLedgerBackend(ledger).Submit(context.Background(), admin, "registry:SetIDPrefix", "Org1MSP", "AB"); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "member", "create")
+
+	if code != 0 || !strings.Contains(stdout, "AB00000013") {
+		t.Fatalf("exit %d: %s%s", code, stdout, stderr)
+	}
+}
+
+func TestInvalidInputIsRefusedBeforeSending(t *testing.T) {
+
+	ledger := filepath.Join(t.TempDir(), "ledger.json")
+
+	refused := [][]string{
+		{"member", "create", "A1"},
+		{"member", "create", "AB12345678"},
+		{"member", "create", "AB12345679", "-parents", "nobody"},
+		{"member", "update", "AB12345679", "BloodGrp=Z"},
+		{"member", "update", "AB12345679", "name=mallory"},
+		{"member", "transition", "AB12345679", "Resurrect"},
+		{"member", "list", "-status", "asleep"},
+		{"consent", "grant", "AB12345679", "gina", "-expires", "tomorrow"},
+	}
+
+	for _, args := range refused {
+
+		code, _, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, args...)
+
+		if code == 0 || stderr == "" {
+			t.Errorf("%s was not refused", strings.Join(args, " "))
+		}
+	}
+
+	if _, err := os.Stat(ledger); !os.IsNotExist(err) {
+		t.Errorf("a refused command wrote the ledger")
+	}
+
+	code, _, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "member", "show", "AB12345679")
+
+	if code != cli.EXIT_FAILED || !strings.Contains(stderr, chaincode.NOT_FOUND) {
+		t.Errorf("show of a missing member: exit %d: %s", code, stderr)
+	}
+}
+
+func TestDocumentCommands(t *testing.T) {
+
+	dir := t.TempDir()
+	ledger := filepath.Join(dir, "ledger.json")
+	report := filepath.Join(dir, "report.pdf")
+
+	os.WriteFile(report, []byte("CBC: Hb 14.2 g/dL"), 0o600)
+
+	if code, _, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "member", "create", "AB12345679"); code != 0 {
+		t.Fatal(stderr)
+	}
+
+	code, _, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "document", "attach", "AB12345679", report, "-type", "x-ray")
+
+	if code != cli.EXIT_FAILED || !strings.Contains(stderr, "type") {
+		t.Fatalf("attach with an unknown type: exit %d: %s", code, stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "documents")); !os.IsNotExist(err) {
+		t.Errorf("a refused document was stored")
+	}
+
+	code, stdout, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "-output", "json", "document", "attach", "AB12345679", report, "-type", "lab_report", "-episode", "ADM-1")
+
+	var attached chaincode.Attachment
+
+	if err := json.Unmarshal([]byte(stdout), &attached); code != 0 || err != nil {
+		t.Fatalf("attach: exit %d, %v: %s%s", code, err, stdout, stderr)
+	}
+
+	if attached.MIME != "application/pdf" || attached.Size != 17 || attached.Author != "alice" || attached.Episode != "ADM-1" {
+		t.Errorf("attached %+v", attached)
+	}
+
+	store := &docstore.Local{Dir: filepath.Join(dir, "documents")}
+
+	if err := docstore.Verify(context.Background(), store, attached.URI, attached.SHA256); err != nil {
+		t.Errorf("the stored file: %v", err)
+	}
+
+	if code, stdout, stderr = medhist(t, ledger, "alice", chaincode.PARENTS, "document", "verify", "AB12345679", report); code != 0 || !strings.Contains(stdout, attached.SHA256) {
+		t.Errorf("verify: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	os.WriteFile(report, []byte("CBC: Hb 4.2 g/dL"), 0o600)
+
+	if code, _, stderr = medhist(t, ledger, "alice", chaincode.PARENTS, "document", "verify", "AB12345679", report); code != cli.EXIT_FAILED || !strings.Contains(stderr, "is not attached") {
+		t.Errorf("verify of a changed file: exit %d: %s", code, stderr)
+	}
+}
+
+func TestProofCommands(t *testing.T) {
+
+	dir := t.TempDir()
+	ledger := filepath.Join(dir, "ledger.json")
+	proof := filepath.Join(dir, "proof.json")
+
+	seed(t, ledger)
+
+	if code, stdout, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "proof", "create", "AB12345679", "BloodGrp", "-o", proof); code != 0 || !strings.Contains(stdout, `"O+"`) {
+		t.Fatalf("create: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	if code, stdout, stderr := medhist(t, ledger, "gina", chaincode.HEALTHY, "proof", "verify", proof); code != 0 || !strings.Contains(stdout, "BloodGrp") || strings.Contains(stdout, "female") {
+		t.Errorf("verify: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "member", "update", "AB12345679", "BloodGrp=O-"); code != 0 {
+		t.Fatal(stderr)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "gina", chaincode.HEALTHY, "proof", "verify", proof); code != cli.EXIT_FAILED || !strings.Contains(stderr, "does not verify") {
+		t.Errorf("verify after the blood group changed: exit %d: %s", code, stderr)
+	}
+}
+
+func TestCredentialCommands(t *testing.T) {
+
+	dir := t.TempDir()
+	ledger := filepath.Join(dir, "ledger.json")
+	jwt := filepath.Join(dir, "credential.jwt")
+
+	seed(t, ledger)
+
+	if code, _, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "credential", "issue", "AB12345679", "blood_group"); code != cli.EXIT_FAILED || !strings.Contains(stderr, "signing key") {
+		t.Errorf("issue without a signing key: exit %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "-signing-key", "tnYK+uxPz6lk9/4dzqDXoJ726t2NFLOWrpHkNp3Yvwk=", "-output", "json", "credential", "issue", "AB12345679", "blood_group", "-o", jwt)
+
+	var issued chaincode.Issued_Credential
+
+	if code != 0 || json.Unmarshal([]byte(stdout), &issued) != nil || issued.Credential.Credential_Subject.Blood_Group != "O+" {
+		t.Fatalf("issue: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	if code, stdout, stderr := medhist(t, ledger, "gina", chaincode.HEALTHY, "credential", "verify", jwt); code != 0 || !strings.Contains(stdout, "Org1MSP") {
+		t.Errorf("verify: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "credential", "revoke", "AB12345679", issued.Record.ID, "-reason", "issued in error"); code != 0 {
+		t.Fatalf("revoke %s: %s", issued.Record.ID, stderr)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "gina", chaincode.HEALTHY, "credential", "verify", jwt); code != cli.EXIT_FAILED || !strings.Contains(stderr, "issued in error") {
+		t.Errorf("verify after revocation: exit %d: %s", code, stderr)
+	}
+
+	os.WriteFile(jwt, []byte(issued.JWT[:len(issued.JWT)-4]+"AAAA"), 0o600)
+
+	if code, _, stderr := medhist(t, ledger, "gina", chaincode.HEALTHY, "credential", "verify", jwt); code != cli.EXIT_FAILED || !strings.Contains(stderr, "not anchored") {
+		t.Errorf("verify an altered credential: exit %d: %s", code, stderr)
+	}
+}
+
+func TestImmunizationCommands(t *testing.T) {
+
+	ledger := filepath.Join(t.TempDir(), "ledger.json")
+
+	seed(t, ledger)
+
+	if code, _, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "immunization", "record", "AB12345679", "MMR", "first"); code != cli.EXIT_USAGE || !strings.Contains(stderr, "not a number") {
+		t.Errorf("record with a bad dose: exit %d: %s", code, stderr)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "immunization", "record", "AB12345679", "MMR", "1", "-lot", "MMR-0423", "-practitioner", "Dr Okafor", "-site", "left_thigh", "-date", "2024-01-20"); code != 0 {
+		t.Fatalf("record: exit %d: %s", code, stderr)
+	}
+
+	if code, stdout, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "immunization", "status", "AB12345679"); code != 0 || !strings.Contains(stdout, chaincode.DOSE_GIVEN) || !strings.Contains(stdout, chaincode.DOSE_OVERDUE) {
+		t.Errorf("status: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	code, stdout, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "-output", "json", "immunization", "overdue", "MMR")
+
+	var report chaincode.Overdue_Report
+
+	if code != 0 || json.Unmarshal([]byte(stdout), &report) != nil || len(report.Members) != 2 || report.Members[0].Dose != 2 || report.Members[1].Dose != 1 {
+		t.Errorf("overdue: exit %d: %s%s", code, stdout, stderr)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "bob", chaincode.BIRTHDAY, "immunization", "overdue", "BCG"); code != cli.EXIT_FAILED || !strings.Contains(stderr, "No schedule") {
+		t.Errorf("overdue without a schedule: exit %d: %s", code, stderr)
+	}
+}
+
+func TestExport(t *testing.T) {
+
+	ledger := filepath.Join(t.TempDir(), "ledger.json")
+
+	for _, id := range []string{"AB12345679", "AB12345687", "AB12345703"} {
+		if code, _, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "member", "create", id); code != 0 {
+			t.Fatal(stderr)
+		}
+	}
+
+	root := rest.Caller{Username: "root", Role: chaincode.ADMIN}
+
+	if _, err := cli.NewLedgerBackend(ledger).Submit(context.Background(), root, "registry:SetOrgKey", "Org1MSP", "D/yh6tWtU8C47UPFnVIJaiPo41wmKDW90OjsMf5MWwQ="); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _, stderr := medhist(t, ledger, "alice", chaincode.PARENTS, "member", "update", "AB12345679", "notes=Born at 34 weeks"); code != 0 {
+		t.Fatal(stderr)
+	}
+
+	out := filepath.Join(t.TempDir(), "export.jsonl")
+
+	code, _, stderr := medhist(t, ledger, "root", chaincode.ADMIN, "export", "-page-size", "2", "-o", out)
+
+	if code != 0 {
+		t.Fatal(stderr)
+	}
+
+	bytes, _ := os.ReadFile(out)
+
+	members := strings.Count(string(bytes), `"type":"`+chaincode.ENTRY_MEMBER+`"`)
+
+	if members != 3 {
+		t.Errorf("exported %d members, want 3:\n%s", members, bytes)
+	}
+
+	if histories := strings.Count(string(bytes), `"type":"`+chaincode.ENTRY_HISTORY+`"`); histories != 3 {
+		t.Errorf("exported %d histories, want 3:\n%s", histories, bytes)
+	}
+
+	if data_keys := strings.Count(string(bytes), `"type":"`+chaincode.ENTRY_DATA_KEY+`"`); data_keys != 1 {
+		t.Errorf("exported %d data keys, want 1 for the sealed member:\n%s", data_keys, bytes)
+	}
+}
@@ -0,0 +1,953 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/disclosure"
+	"github.com/ravivarmakv/SampleChainCode/docstore"
+)
+
+//==============================================================================================================================
+//	 command - A subcommand. Name is the words that select it, run is passed the arguments after them.
+//==============================================================================================================================
+type command struct {
+	name string
+	run  func(c *CLI, args []string) error
+}
+
+var COMMANDS = []command{
+	{"member create", member_create},
+	{"member show", member_show},
+	{"member transition", member_transition},
+	{"member update", member_update},
+	{"member erase", member_erase},
+	{"member list", member_list},
+	{"consent grant", consent_grant},
+	{"document attach", document_attach},
+	{"document verify", document_verify},
+	{"proof create", proof_create},
+	{"proof verify", proof_verify},
+	{"credential issue", credential_issue},
+	{"credential verify", credential_verify},
+	{"credential revoke", credential_revoke},
+	{"immunization record", immunization_record},
+	{"immunization status", immunization_status},
+	{"immunization overdue", immunization_overdue},
+	{"history", history},
+	{"export", export},
+}
+
+func find_command(args []string) (command, bool) {
+
+	for _, cmd := range COMMANDS {
+
+		words := strings.Fields(cmd.name)
+
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+//==============================================================================================================================
+//	 parse - Parses the flags of a subcommand, which may come before, between or after its positional arguments, and
+//			 checks the number of positional arguments is between min and max.
+//==============================================================================================================================
+func parse(fs *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+
+	fs.SetOutput(io.Discard)
+
+	positional := []string{}
+
+	for {
+
+		if err := fs.Parse(args); err != nil {
+			return nil, usage("%s: %v", fs.Name(), err)
+		}
+
+		args = fs.Args()
+
+		if len(args) == 0 {
+			break
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) < min || (max >= 0 && len(positional) > max) {
+		return nil, usage("%s: wrong number of arguments, see medhist -h", fs.Name())
+	}
+
+	return positional, nil
+}
+
+//==============================================================================================================================
+//	 validate - Checks the arguments of function with the chaincode's rules so that a bad call is refused before it is
+//				sent.
+//==============================================================================================================================
+func validate(function string, args ...string) error {
+	return chaincode.ValidateArguments(function, args)
+}
+
+//==============================================================================================================================
+//	 member_create - Creates a member with the ILNSID given, or with the next ILNSID of the caller's organisation.
+//==============================================================================================================================
+func member_create(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("member create", flag.ContinueOnError)
+	parents := fs.String("parents", "", "comma separated ILNSIDs of the parents")
+
+	positional, err := parse(fs, args, 0, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+
+		payload, err := c.submit("registry:AllocateILNSID")
+
+		if err != nil {
+			return err
+		}
+
+		var ILNSID string
+
+		if err := json.Unmarshal(payload, &ILNSID); err != nil {
+			ILNSID = string(payload) // Backends that return the string unquoted
+		}
+
+		positional = []string{ILNSID}
+	}
+
+	ids := []string{}
+
+	for _, id := range strings.Split(*parents, ",") {
+
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+
+		if err := validate("registry:CheckUniqueILNS", id); err != nil {
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	encoded, _ := json.Marshal(ids)
+
+	return c.invoke_result("member:CreateMember", positional[0], string(encoded))
+}
+
+func member_show(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("member show", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if err := validate("query:GetMemberDetails", positional...); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:GetMemberDetails", positional...)
+
+	if err != nil {
+		return err
+	}
+
+	var m chaincode.Member
+
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return err
+	}
+
+	return c.print(m, func(t *table) { member_rows(t, []chaincode.Member{m}) })
+}
+
+//==============================================================================================================================
+//	 member_transition - Runs the lifecycle transaction named, e.g. ParentsToBirthday. DeadMember takes no recipient.
+//==============================================================================================================================
+func member_transition(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("member transition", flag.ContinueOnError), args, 2, 3)
+
+	if err != nil {
+		return err
+	}
+
+	function := chaincode.LIFECYCLE_CONTRACT + ":" + positional[1]
+
+	if _, ok := find_function(function); !ok {
+		return usage("unknown transition %s, must be one of %s", positional[1], strings.Join(transitions(), ", "))
+	}
+
+	return c.invoke_result(function, append(positional[:1], positional[2:]...)...)
+}
+
+//==============================================================================================================================
+//	 member_update - Sets fields given as field=value, e.g. DOB=2024-05-20 Weight=3.4kg, in one member:UpdateMember.
+//==============================================================================================================================
+func member_update(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("member update", flag.ContinueOnError), args, 2, -1)
+
+	if err != nil {
+		return err
+	}
+
+	patch := map[string]string{}
+
+	for _, assignment := range positional[1:] {
+
+		field, value, ok := strings.Cut(assignment, "=")
+
+		if !ok {
+			return usage("member update: %s is not field=value", assignment)
+		}
+
+		if err := chaincode.ValidateField(field, value); err != nil {
+			return err
+		}
+
+		patch[field] = value
+	}
+
+	encoded, _ := json.Marshal(patch)
+
+	return c.invoke_result("member:UpdateMember", positional[0], string(encoded))
+}
+
+//==============================================================================================================================
+//	 member_erase - Erases the member with member:EraseMember. The tombstone keeps only its lifecycle.
+//==============================================================================================================================
+func member_erase(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("member erase", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return c.invoke_result("member:EraseMember", positional...)
+}
+
+func member_list(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("member list", flag.ContinueOnError)
+	status_name := fs.String("status", "", "only members in this status, one of "+strings.Join(chaincode.STATUS_NAMES, ", "))
+
+	if _, err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	status := -1
+
+	for i, name := range chaincode.STATUS_NAMES {
+		if strings.EqualFold(*status_name, name) {
+			status = i
+		}
+	}
+
+	if *status_name != "" && status < 0 {
+		return usage("member list: unknown status %s, must be one of %s", *status_name, strings.Join(chaincode.STATUS_NAMES, ", "))
+	}
+
+	payload, err := c.evaluate("query:GetMembers")
+
+	if err != nil {
+		return err
+	}
+
+	var members []chaincode.Member
+
+	if err := json.Unmarshal(payload, &members); err != nil {
+		return err
+	}
+
+	selected := []chaincode.Member{}
+
+	for _, m := range members {
+		if status < 0 || m.Status == status {
+			selected = append(selected, m)
+		}
+	}
+
+	return c.print(selected, func(t *table) { member_rows(t, selected) })
+}
+
+//==============================================================================================================================
+//	 consent_grant - Grants consent and prints the consent as recorded.
+//==============================================================================================================================
+func consent_grant(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("consent grant", flag.ContinueOnError)
+	expires := fs.String("expires", "", "when the consent expires, RFC 3339, never if empty")
+
+	positional, err := parse(fs, args, 2, 2)
+
+	if err != nil {
+		return err
+	}
+
+	args = append(positional, *expires)
+
+	if err := validate("consent:GrantConsent", args...); err != nil {
+		return err
+	}
+
+	if _, err := c.submit("consent:GrantConsent", args...); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("consent:ListConsents", positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	var consents []chaincode.Consent
+
+	if err := json.Unmarshal(payload, &consents); err != nil {
+		return err
+	}
+
+	for _, consent := range consents {
+		if consent.Grantee == positional[1] {
+			return c.print(consent, func(t *table) {
+				t.row("ILNSID", "GRANTEE", "GRANTED BY", "EXPIRES", "TX")
+				t.row(consent.ILNSID, consent.Grantee, consent.Granted_By, or_never(consent.Expires), consent.Tx_ID)
+			})
+		}
+	}
+
+	return fmt.Errorf("consent granted to %s was not found", positional[1])
+}
+
+//==============================================================================================================================
+//	 document_attach - Puts the file in the store and anchors its metadata and hash against the member. The MIME type is
+//					   taken from the file's extension unless given.
+//==============================================================================================================================
+func document_attach(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("document attach", flag.ContinueOnError)
+	document_type := fs.String("type", "", "one of "+strings.Join(chaincode.DOCUMENT_TYPES, ", "))
+	mime_type := fs.String("mime", "", "MIME type of the file, from its extension if empty")
+	episode := fs.String("episode", "", "episode of care the file belongs to")
+
+	positional, err := parse(fs, args, 2, 2)
+
+	if err != nil {
+		return err
+	}
+
+	if *mime_type == "" {
+		*mime_type = "application/octet-stream"
+		if by_extension, _, err := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(positional[1]))); err == nil {
+			*mime_type = by_extension
+		}
+	}
+
+	f, err := os.Open(positional[1])
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	size, hash, err := docstore.Hash(f)
+
+	if err != nil {
+		return err
+	}
+
+	if err := validate("query:GetMemberDetails", positional[0]); err != nil {
+		return err
+	}
+
+	document := chaincode.Attachment{Type: *document_type, MIME: *mime_type, Size: size, SHA256: hash, URI: "file:///", Episode: *episode}
+
+	if err := chaincode.ValidateDocument(document); err != nil { // The URI is checked once the file is stored
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	stored, err := c.Store.Put(context.Background(), f)
+
+	if err != nil {
+		return err
+	}
+
+	document.URI = stored.URI
+	encoded, _ := json.Marshal(document)
+
+	payload, err := c.submit("document:AttachDocument", positional[0], string(encoded))
+
+	if err != nil {
+		return err
+	}
+
+	var attached chaincode.Attachment
+
+	if err := json.Unmarshal(payload, &attached); err != nil {
+		return err
+	}
+
+	return c.print(attached, func(t *table) { document_rows(t, attached) })
+}
+
+//==============================================================================================================================
+//	 document_verify - Hashes the file and checks it against the documents attached to the member. A file that is not
+//					   attached is a failure.
+//==============================================================================================================================
+func document_verify(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("document verify", flag.ContinueOnError), args, 2, 2)
+
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(positional[1])
+
+	if err != nil {
+		return err
+	}
+
+	_, hash, err := docstore.Hash(f)
+	f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if err := validate("document:VerifyDocument", positional[0], hash); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("document:VerifyDocument", positional[0], hash)
+
+	if err != nil {
+		return err
+	}
+
+	var verification chaincode.Document_Verification
+
+	if err := json.Unmarshal(payload, &verification); err != nil {
+		return err
+	}
+
+	if !verification.Verified {
+		return fmt.Errorf("%s (sha256 %s) is not attached to %s", positional[1], hash, positional[0])
+	}
+
+	return c.print(verification, func(t *table) { document_rows(t, *verification.Document) })
+}
+
+//==============================================================================================================================
+//	 proof_create - Gets a proof of the named fields of the member, written to the file given by -o for the party it is
+//					shown to.
+//==============================================================================================================================
+func proof_create(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("proof create", flag.ContinueOnError)
+	out := fs.String("o", "", "file to write the proof to")
+
+	positional, err := parse(fs, args, 2, -1)
+
+	if err != nil {
+		return err
+	}
+
+	fields, _ := json.Marshal(positional[1:])
+
+	if err := validate("query:ProveFields", positional[0], string(fields)); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:ProveFields", positional[0], string(fields))
+
+	if err != nil {
+		return err
+	}
+
+	var proof disclosure.Proof
+
+	if err := json.Unmarshal(payload, &proof); err != nil {
+		return err
+	}
+
+	if *out != "" {
+
+		bytes, _ := json.MarshalIndent(proof, "", "  ")
+
+		if err := os.WriteFile(*out, append(bytes, '\n'), 0o600); err != nil {
+			return err
+		}
+	}
+
+	return c.print(proof, func(t *table) { proof_rows(t, proof) })
+}
+
+//==============================================================================================================================
+//	 proof_verify - Checks a proof read from a file against the fields root of its member on the ledger. A proof that does
+//					not verify is a failure.
+//==============================================================================================================================
+func proof_verify(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("proof verify", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	bytes, err := os.ReadFile(positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	var proof disclosure.Proof
+
+	if err := json.Unmarshal(bytes, &proof); err != nil {
+		return fmt.Errorf("%s is not a proof: %v", positional[0], err)
+	}
+
+	if err := validate("query:GetFieldsRoot", proof.ILNSID); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:GetFieldsRoot", proof.ILNSID)
+
+	if err != nil {
+		return err
+	}
+
+	var root chaincode.Member_Root
+
+	if err := json.Unmarshal(payload, &root); err != nil {
+		return err
+	}
+
+	if err := disclosure.Verify(proof, root.Root); err != nil {
+		return fmt.Errorf("%s does not verify against the fields root of %s: %v", positional[0], proof.ILNSID, err)
+	}
+
+	return c.print(proof, func(t *table) { proof_rows(t, proof) })
+}
+
+//==============================================================================================================================
+//	 credential_issue - Issues a credential of a fact about the member, signed with the key given by -signing-key. The JWT
+//						is written to the file given by -o for the holder.
+//==============================================================================================================================
+func credential_issue(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("credential issue", flag.ContinueOnError)
+	expires := fs.String("expires", "", "when the credential expires, RFC 3339, never if empty")
+	out := fs.String("o", "", "file to write the JWT to")
+
+	positional, err := parse(fs, args, 2, 2)
+
+	if err != nil {
+		return err
+	}
+
+	args = append(positional, *expires)
+
+	if err := validate("credential:IssueCredential", args...); err != nil {
+		return err
+	}
+
+	payload, err := c.submit("credential:IssueCredential", args...)
+
+	if err != nil {
+		return err
+	}
+
+	var issued chaincode.Issued_Credential
+
+	if err := json.Unmarshal(payload, &issued); err != nil {
+		return err
+	}
+
+	if *out != "" {
+
+		if err := os.WriteFile(*out, []byte(issued.JWT+"\n"), 0o600); err != nil {
+			return err
+		}
+	}
+
+	return c.print(issued, func(t *table) { credential_rows(t, issued.Record) })
+}
+
+//==============================================================================================================================
+//	 credential_verify - Checks a credential JWT read from a file against its anchor on the ledger. A credential that is
+//						 not anchored, revoked or expired is a failure.
+//==============================================================================================================================
+func credential_verify(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("credential verify", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	bytes, err := os.ReadFile(positional[0])
+
+	if err != nil {
+		return err
+	}
+
+	jwt := strings.TrimSpace(string(bytes))
+
+	if err := validate("query:VerifyCredential", jwt); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:VerifyCredential", jwt)
+
+	if err != nil {
+		return err
+	}
+
+	var verification chaincode.Credential_Verification
+
+	if err := json.Unmarshal(payload, &verification); err != nil {
+		return err
+	}
+
+	switch {
+	case !verification.Anchored:
+		return fmt.Errorf("%s is not anchored on the ledger", positional[0])
+	case verification.Revoked:
+		return fmt.Errorf("%s was revoked: %s", verification.ID, verification.Record.Reason)
+	case verification.Expired:
+		return fmt.Errorf("%s expired at %s", verification.ID, verification.Record.Expires)
+	case !verification.Verified:
+		return fmt.Errorf("%s is not signed with the key it was issued with", positional[0])
+	}
+
+	return c.print(verification, func(t *table) { credential_rows(t, *verification.Record) })
+}
+
+//==============================================================================================================================
+//	 credential_revoke - Revokes a credential issued to the member.
+//==============================================================================================================================
+func credential_revoke(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("credential revoke", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the credential is revoked")
+
+	positional, err := parse(fs, args, 2, 2)
+
+	if err != nil {
+		return err
+	}
+
+	args = append(positional, *reason)
+
+	if err := validate("credential:RevokeCredential", args...); err != nil {
+		return err
+	}
+
+	payload, err := c.submit("credential:RevokeCredential", args...)
+
+	if err != nil {
+		return err
+	}
+
+	var revoked chaincode.Credential_Record
+
+	if err := json.Unmarshal(payload, &revoked); err != nil {
+		return err
+	}
+
+	return c.print(revoked, func(t *table) { credential_rows(t, revoked) })
+}
+
+//==============================================================================================================================
+//	 immunization_record - Records a dose of a vaccine given to the member, by default today.
+//==============================================================================================================================
+func immunization_record(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("immunization record", flag.ContinueOnError)
+	lot := fs.String("lot", "", "lot number of the vaccine")
+	practitioner := fs.String("practitioner", "", "practitioner who gave the dose")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "date the dose was given, YYYY-MM-DD")
+	site := fs.String("site", "", "one of "+strings.Join(chaincode.IMMUNIZATION_SITES, ", "))
+
+	positional, err := parse(fs, args, 3, 3)
+
+	if err != nil {
+		return err
+	}
+
+	dose, err := strconv.Atoi(positional[2])
+
+	if err != nil {
+		return usage("immunization record: dose %s is not a number", positional[2])
+	}
+
+	encoded, _ := json.Marshal(chaincode.Immunization{Vaccine: positional[1], Dose: dose, Lot: *lot, Practitioner: *practitioner, Date: *date, Site: *site})
+
+	if err := validate("immunization:RecordImmunization", positional[0], string(encoded)); err != nil {
+		return err
+	}
+
+	payload, err := c.submit("immunization:RecordImmunization", positional[0], string(encoded))
+
+	if err != nil {
+		return err
+	}
+
+	var recorded chaincode.Immunization
+
+	if err := json.Unmarshal(payload, &recorded); err != nil {
+		return err
+	}
+
+	return c.print(recorded, func(t *table) { immunization_rows(t, positional[0], []chaincode.Immunization{recorded}) })
+}
+
+//==============================================================================================================================
+//	 immunization_status - Shows the member's scheduled doses and where each stands.
+//==============================================================================================================================
+func immunization_status(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("immunization status", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if err := validate("query:GetImmunizationStatus", positional...); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:GetImmunizationStatus", positional...)
+
+	if err != nil {
+		return err
+	}
+
+	var status chaincode.Immunization_Status
+
+	if err := json.Unmarshal(payload, &status); err != nil {
+		return err
+	}
+
+	return c.print(status, func(t *table) { immunization_status_rows(t, status) })
+}
+
+//==============================================================================================================================
+//	 immunization_overdue - Lists the members overdue for a dose of the vaccine.
+//==============================================================================================================================
+func immunization_overdue(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("immunization overdue", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if err := validate("query:GetOverdueMembers", positional...); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:GetOverdueMembers", positional...)
+
+	if err != nil {
+		return err
+	}
+
+	var report chaincode.Overdue_Report
+
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return err
+	}
+
+	return c.print(report, func(t *table) { overdue_rows(t, report) })
+}
+
+func history(c *CLI, args []string) error {
+
+	positional, err := parse(flag.NewFlagSet("history", flag.ContinueOnError), args, 1, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if err := validate("query:GetMemberHistory", positional...); err != nil {
+		return err
+	}
+
+	payload, err := c.evaluate("query:GetMemberHistory", positional...)
+
+	if err != nil {
+		return err
+	}
+
+	var revisions []chaincode.Member_Revision
+
+	if err := json.Unmarshal(payload, &revisions); err != nil {
+		return err
+	}
+
+	return c.print(revisions, func(t *table) {
+
+		t.row("TX", "TIMESTAMP", "STATUS", "CUSTODIAN", "DEAD", "DOB", "GENDER", "BLOODGRP", "WEIGHT")
+
+		for _, r := range revisions {
+
+			if r.Member == nil {
+				t.row(r.Tx_ID, r.Timestamp, "deleted")
+				continue
+			}
+
+			m := r.Member
+			t.row(r.Tx_ID, r.Timestamp, status_name(m.Status), m.Name, fmt.Sprint(m.Dead), m.DOB, m.Gender, m.BloodGrp, weight(m.Weight))
+		}
+	})
+}
+
+//==============================================================================================================================
+//	 export - Writes every page of query:ExportState as JSON lines, whatever the output format, to -o or stdout. The
+//			  result can be written back with registry:ImportState.
+//==============================================================================================================================
+func export(c *CLI, args []string) error {
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("o", "", "file to write, stdout if empty")
+	page_size := fs.Int("page-size", 0, "entries per page, 0 for the default")
+
+	if _, err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	w := c.Stdout
+
+	if *out != "" {
+
+		f, err := os.Create(*out)
+
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		w = f
+	}
+
+	bookmark := ""
+
+	for {
+
+		args := []string{bookmark, fmt.Sprint(*page_size)}
+
+		if err := validate("query:ExportState", args...); err != nil {
+			return err
+		}
+
+		payload, err := c.evaluate("query:ExportState", args...)
+
+		if err != nil {
+			return err
+		}
+
+		var page string
+
+		if err := json.Unmarshal(payload, &page); err != nil {
+			page = string(payload) // Backends that return the string unquoted
+		}
+
+		var header chaincode.Export_Header
+
+		first, _, _ := strings.Cut(page, "\n")
+
+		if err := json.Unmarshal([]byte(first), &header); err != nil {
+			return fmt.Errorf("unreadable export page: %v", err)
+		}
+
+		if _, err := io.WriteString(w, page); err != nil {
+			return err
+		}
+
+		if header.Next == "" {
+			return nil
+		}
+
+		bookmark = header.Next
+	}
+}
+
+//==============================================================================================================================
+//	 invoke_result - Validates and submits an invoke that returns an Invoke_Result, and prints the result.
+//==============================================================================================================================
+func (c *CLI) invoke_result(function string, args ...string) error {
+
+	if err := validate(function, args...); err != nil {
+		return err
+	}
+
+	payload, err := c.submit(function, args...)
+
+	if err != nil {
+		return err
+	}
+
+	var result chaincode.Invoke_Result
+
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return err
+	}
+
+	return c.print(result, func(t *table) {
+		t.row("ILNSID", "STATUS", "CUSTODIAN", "DEAD", "TX", "CHANGED", "EVENTS")
+		t.row(result.ILNSID, status_name(result.Status), result.Custodian, fmt.Sprint(result.Dead), result.Tx_ID, strings.Join(result.Changed, ","), strings.Join(result.Events, ","))
+	})
+}
+
+func find_function(name string) (chaincode.Function, bool) {
+
+	for _, f := range chaincode.Functions() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return chaincode.Function{}, false
+}
+
+func transitions() []string {
+
+	names := []string{}
+
+	for _, f := range chaincode.Functions() {
+		if strings.HasPrefix(f.Name, chaincode.LIFECYCLE_CONTRACT+":") {
+			names = append(names, strings.TrimPrefix(f.Name, chaincode.LIFECYCLE_CONTRACT+":"))
+		}
+	}
+
+	return names
+}
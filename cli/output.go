@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/disclosure"
+)
+
+//==============================================================================================================================
+//	 table - Rows written as aligned columns.
+//==============================================================================================================================
+type table struct {
+	w *tabwriter.Writer
+}
+
+func (t *table) row(cells ...string) {
+	fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+}
+
+//==============================================================================================================================
+//	 print - Writes v as indented JSON with -output json, or as the table rows writes.
+//==============================================================================================================================
+func (c *CLI) print(v interface{}, rows func(t *table)) error {
+
+	if c.Output == OUTPUT_JSON {
+
+		bytes, err := json.MarshalIndent(v, "", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(c.Stdout, string(bytes))
+
+		return err
+	}
+
+	t := &table{w: tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)}
+
+	rows(t)
+
+	return t.w.Flush()
+}
+
+func member_rows(t *table, members []chaincode.Member) {
+
+	t.row("ILNSID", "STATUS", "CUSTODIAN", "DEAD", "DOB", "GENDER", "BLOODGRP", "WEIGHT", "PARENTS")
+
+	for _, m := range members {
+		t.row(m.ILNSID, status_name(m.Status), m.Name, fmt.Sprint(m.Dead), m.DOB, m.Gender, m.BloodGrp, weight(m.Weight), strings.Join(m.Parents, ","))
+	}
+}
+
+func document_rows(t *table, a chaincode.Attachment) {
+	t.row("ILNSID", "TYPE", "MIME", "SIZE", "SHA256", "EPISODE", "AUTHOR", "TIMESTAMP", "URI")
+	t.row(a.ILNSID, a.Type, a.MIME, fmt.Sprint(a.Size), a.SHA256, a.Episode, a.Author, a.Timestamp, a.URI)
+}
+
+func proof_rows(t *table, p disclosure.Proof) {
+
+	t.row("ILNSID", "ROOT", "FIELD", "VALUE")
+
+	for _, f := range p.Fields {
+		t.row(p.ILNSID, p.Root, f.Field, f.Value)
+	}
+}
+
+func credential_rows(t *table, r chaincode.Credential_Record) {
+	t.row("ID", "ILNSID", "TYPE", "ISSUER", "ISSUED", "EXPIRES", "REVOKED", "SHA256")
+	t.row(r.ID, r.ILNSID, r.Type, r.Issuer, r.Issued, r.Expires, fmt.Sprint(r.Revoked), r.SHA256)
+}
+
+func immunization_rows(t *table, ILNSID string, given []chaincode.Immunization) {
+
+	t.row("ILNSID", "VACCINE", "DOSE", "DATE", "LOT", "SITE", "PRACTITIONER", "RECORDED BY")
+
+	for _, i := range given {
+		t.row(ILNSID, i.Vaccine, fmt.Sprint(i.Dose), i.Date, i.Lot, i.Site, i.Practitioner, i.Recorded_By)
+	}
+}
+
+func immunization_status_rows(t *table, s chaincode.Immunization_Status) {
+
+	t.row("ILNSID", "VACCINE", "DOSE", "DUE", "STATUS", "GIVEN")
+
+	for _, v := range s.Vaccines {
+		for _, d := range v.Doses {
+
+			given := ""
+
+			if d.Given != nil {
+				given = d.Given.Date
+			}
+
+			t.row(s.ILNSID, v.Vaccine, fmt.Sprint(d.Dose), d.Due, d.Status, given)
+		}
+	}
+}
+
+func overdue_rows(t *table, r chaincode.Overdue_Report) {
+
+	t.row("ILNSID", "DOB", "VACCINE", "DOSE", "DUE", "DAYS OVERDUE")
+
+	for _, m := range r.Members {
+		t.row(m.ILNSID, m.DOB, r.Vaccine, fmt.Sprint(m.Dose), m.Due, fmt.Sprint(m.Days_Overdue))
+	}
+}
+
+func status_name(status int) string {
+
+	if status >= 0 && status < len(chaincode.STATUS_NAMES) {
+		return chaincode.STATUS_NAMES[status]
+	}
+
+	return strconv.Itoa(status)
+}
+
+func weight(w chaincode.Weight) string {
+
+	if w.Value == 0 {
+		return chaincode.UNDEFINED
+	}
+
+	return strconv.FormatFloat(w.Value, 'f', -1, 64) + w.Unit
+}
+
+func or_never(expires string) string {
+
+	if expires == "" {
+		return "never"
+	}
+
+	return expires
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ravivarmakv/SampleChainCode/listener"
+)
+
+//=================================================================================================================================
+//	 Main - main - Forwards the chaincode events of the medical history chaincode to webhooks until interrupted
+//=================================================================================================================================
+func main() {
+
+	config_path := flag.String("config", "medhist-events.json", "configuration file")
+	flag.Parse()
+
+	config, err := listener.LoadConfig(*config_path)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %s\n", err)
+		os.Exit(2)
+	}
+
+	l, err := listener.New(config)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating listener: %s\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = l.Run(ctx)
+
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Error forwarding events: %s\n", err)
+		os.Exit(1)
+	}
+}
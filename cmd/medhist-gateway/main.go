@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+	"github.com/ravivarmakv/SampleChainCode/rest"
+)
+
+//=================================================================================================================================
+//	 Main - main - Serves the REST gateway of the medical history chaincode until interrupted
+//=================================================================================================================================
+func main() {
+
+	addr := flag.String("addr", ":8080", "address to listen on")
+	backend_type := flag.String("backend", "mock", "mock for an in-process ledger, or fabric for a peer")
+	config_path := flag.String("config", "medhist-gateway.json", "peer configuration file, for the fabric backend")
+	wallet := flag.String("wallet", "wallet", "directory of <username>/cert.pem and key.pem, for the fabric backend")
+	key_path := flag.String("key", "", "file holding the base64 X25519 private key of the gateway's organisation, to open encrypted fields")
+	openapi := flag.Bool("openapi", false, "print the OpenAPI description and exit")
+	flag.Parse()
+
+	if *openapi {
+
+		bytes, _ := json.MarshalIndent(rest.OpenAPI(), "", "  ")
+		fmt.Println(string(bytes))
+
+		return
+	}
+
+	key, err := read_key(*key_path)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the organisation key: %s\n", err)
+		os.Exit(2)
+	}
+
+	backend, err := new_backend(*backend_type, *config_path, *wallet, key)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating backend: %s\n", err)
+		os.Exit(2)
+	}
+
+	server := &http.Server{Addr: *addr, Handler: (&rest.Server{Backend: backend}).Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Serving the %s backend on %s\n", *backend_type, *addr)
+
+	err = server.ListenAndServe()
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error serving: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+//=================================================================================================================================
+//	 read_key - The organisation key held in the file at path, none if no file is given. Clients never send the key, the
+//				gateway unwraps the data keys of members with it itself.
+//=================================================================================================================================
+func read_key(path string) (string, error) {
+
+	if path == "" {
+		return "", nil
+	}
+
+	bytes, err := os.ReadFile(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(bytes)), nil
+}
+
+func new_backend(backend_type string, config_path string, wallet string, key string) (rest.Backend, error) {
+
+	switch backend_type {
+	case "mock":
+		return rest.NewDataKeyBackend(rest.NewMockBackend(), key), nil
+	case "fabric":
+
+		bytes, err := os.ReadFile(config_path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var config peer.Config
+
+		if err := json.Unmarshal(bytes, &config); err != nil {
+			return nil, fmt.Errorf("%s: %v", config_path, err)
+		}
+
+		backend, err := rest.NewFabricBackend(config, wallet)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return rest.NewDataKeyBackend(backend, key), nil
+	}
+
+	return nil, errors.New("unknown backend " + backend_type + ", must be mock or fabric")
+}
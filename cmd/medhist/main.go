@@ -0,0 +1,18 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ravivarmakv/SampleChainCode/cli"
+)
+
+//=================================================================================================================================
+//	 Main - main - Runs a medhist command, see cli.USAGE
+//=================================================================================================================================
+func main() {
+
+	stdout := os.Stdout
+	os.Stdout = os.Stderr // The chaincode logs with fmt.Printf, keep that out of the output when it runs in process
+
+	os.Exit(cli.Main(os.Args[1:], stdout, os.Stderr))
+}
@@ -0,0 +1,225 @@
+// Package disclosure builds and checks Merkle proofs over the salted fields of a member, so that a single fact, a blood
+// group say, can be shown to a third party without the rest of the record. The chaincode stores the root of the tree
+// on the member as fieldsRoot and query:ProveFields returns a Proof of the fields asked for. A verifier needs only this
+// package and the root read from the ledger with query:GetFieldsRoot.
+//
+// Each leaf is the SHA-256 of a zero byte followed by the field's salt, name and JSON value, each of the last three
+// terminated by a zero byte. Each node is the SHA-256 of a one byte followed by its two children, and a level with an
+// odd number of nodes carries its last node up unchanged. The salts are made from random bytes the client passes and
+// are kept private, so the root and the paths of a proof reveal nothing of the fields left out of it.
+package disclosure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+//==============================================================================================================================
+//	 Step - One level of a path from a leaf to the root: the hash of the sibling, and whether it is on the left.
+//==============================================================================================================================
+type Step struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+//==============================================================================================================================
+//	 Field - A disclosed field: its name, its value as JSON, its hex salt and the path from its leaf to the root.
+//==============================================================================================================================
+type Field struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	Salt  string `json:"salt"`
+	Path  []Step `json:"path"`
+}
+
+//==============================================================================================================================
+//	 Proof - Fields of the member ILNSID proven against Root. Every proof discloses the ILNSID field, which binds it to
+//			 the member.
+//==============================================================================================================================
+type Proof struct {
+	ILNSID string  `json:"ILNSID"`
+	Root   string  `json:"root"`
+	Fields []Field `json:"fields"`
+}
+
+var ErrRootMismatch = errors.New("disclosure: the proof is not for the root on the ledger")
+var ErrNotBound = errors.New("disclosure: the proof does not disclose the ILNSID it is for")
+
+//==============================================================================================================================
+//	 Leaf - The leaf of a field with the given salt and JSON value.
+//==============================================================================================================================
+func Leaf(salt []byte, field string, value string) []byte {
+
+	hash := sha256.New()
+
+	hash.Write([]byte{0})
+
+	for _, part := range [][]byte{salt, []byte(field), []byte(value)} {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+
+	return hash.Sum(nil)
+}
+
+func node(left []byte, right []byte) []byte {
+
+	hash := sha256.New()
+
+	hash.Write([]byte{1})
+	hash.Write(left)
+	hash.Write(right)
+
+	return hash.Sum(nil)
+}
+
+//==============================================================================================================================
+//	 parents - The level of the tree above level.
+//==============================================================================================================================
+func parents(level [][]byte) [][]byte {
+
+	next := [][]byte{}
+
+	for i := 0; i < len(level); i += 2 {
+		if i+1 < len(level) {
+			next = append(next, node(level[i], level[i+1]))
+		} else {
+			next = append(next, level[i])
+		}
+	}
+
+	return next
+}
+
+//==============================================================================================================================
+//	 Root - The root of the tree over leaves, in order. nil if there are none.
+//==============================================================================================================================
+func Root(leaves [][]byte) []byte {
+
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	level := leaves
+
+	for len(level) > 1 {
+		level = parents(level)
+	}
+
+	return level[0]
+}
+
+//==============================================================================================================================
+//	 Path - The path from the leaf at index to the root of the tree over leaves.
+//==============================================================================================================================
+func Path(leaves [][]byte, index int) []Step {
+
+	path := []Step{}
+	level := leaves
+
+	for len(level) > 1 {
+
+		sibling := index ^ 1
+
+		if sibling < len(level) {
+			path = append(path, Step{Hash: hex.EncodeToString(level[sibling]), Left: sibling < index})
+		}
+
+		level = parents(level)
+		index /= 2
+	}
+
+	return path
+}
+
+//==============================================================================================================================
+//	 Root - The root the field's path leads to.
+//==============================================================================================================================
+func (f Field) Root() ([]byte, error) {
+
+	salt, err := hex.DecodeString(f.Salt)
+
+	if err != nil {
+		return nil, fmt.Errorf("disclosure: the salt of %s is not hex", f.Field)
+	}
+
+	hash := Leaf(salt, f.Field, f.Value)
+
+	for _, step := range f.Path {
+
+		sibling, err := hex.DecodeString(step.Hash)
+
+		if err != nil || len(sibling) != sha256.Size {
+			return nil, fmt.Errorf("disclosure: the path of %s holds a hash that is not a hex SHA-256", f.Field)
+		}
+
+		if step.Left {
+			hash = node(sibling, hash)
+		} else {
+			hash = node(hash, sibling)
+		}
+	}
+
+	return hash, nil
+}
+
+//==============================================================================================================================
+//	 Decode - Unmarshals the value of the field into v.
+//==============================================================================================================================
+func (f Field) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(f.Value), v)
+}
+
+//==============================================================================================================================
+//	 Field - The disclosed field named, if the proof holds it.
+//==============================================================================================================================
+func (p Proof) Field(name string) (Field, bool) {
+
+	for _, f := range p.Fields {
+		if f.Field == name {
+			return f, true
+		}
+	}
+
+	return Field{}, false
+}
+
+//==============================================================================================================================
+//	 Verify - Checks every field of the proof leads to root, the hex root read from the ledger, and that the proof
+//			  discloses the ILNSID it claims to be for.
+//==============================================================================================================================
+func Verify(p Proof, root string) error {
+
+	want, err := hex.DecodeString(root)
+
+	if err != nil || len(want) != sha256.Size || p.Root != hex.EncodeToString(want) {
+		return ErrRootMismatch
+	}
+
+	id, ok := p.Field("ILNSID")
+
+	var ILNSID string
+
+	if !ok || id.Decode(&ILNSID) != nil || ILNSID != p.ILNSID {
+		return ErrNotBound
+	}
+
+	for _, f := range p.Fields {
+
+		got, err := f.Root()
+
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("disclosure: %s does not lead to the root: %w", f.Field, ErrRootMismatch)
+		}
+	}
+
+	return nil
+}
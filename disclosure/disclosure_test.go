@@ -0,0 +1,129 @@
+package disclosure_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/disclosure"
+)
+
+func leaves(n int) [][]byte {
+
+	leaves := [][]byte{}
+
+	for i := 0; i < n; i++ {
+		leaves = append(leaves, disclosure.Leaf([]byte{byte(i)}, fmt.Sprint("field", i), `"value"`))
+	}
+
+	return leaves
+}
+
+func TestEveryPathLeadsToTheRoot(t *testing.T) {
+
+	for n := 1; n <= 9; n++ {
+
+		tree := leaves(n)
+		root := disclosure.Root(tree)
+
+		for i := range tree {
+
+			f := disclosure.Field{Field: fmt.Sprint("field", i), Value: `"value"`, Salt: hex.EncodeToString([]byte{byte(i)}), Path: disclosure.Path(tree, i)}
+
+			got, err := f.Root()
+
+			if err != nil || !bytes.Equal(got, root) {
+				t.Errorf("%d leaves: the path of leaf %d leads to %x, %v", n, i, got, err)
+			}
+		}
+	}
+
+	if disclosure.Root(nil) != nil {
+		t.Errorf("an empty tree has a root")
+	}
+}
+
+func proof() (disclosure.Proof, string) {
+
+	salts := [][]byte{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	fields := []string{"ILNSID", "BloodGrp", "notes"}
+	values := []string{`"AB12345679"`, `"O+"`, `"Follow-up in June"`}
+
+	tree := [][]byte{}
+
+	for i := range fields {
+		tree = append(tree, disclosure.Leaf(salts[i], fields[i], values[i]))
+	}
+
+	root := hex.EncodeToString(disclosure.Root(tree))
+
+	p := disclosure.Proof{ILNSID: "AB12345679", Root: root}
+
+	for _, i := range []int{0, 1} {
+		p.Fields = append(p.Fields, disclosure.Field{Field: fields[i], Value: values[i], Salt: hex.EncodeToString(salts[i]), Path: disclosure.Path(tree, i)})
+	}
+
+	return p, root
+}
+
+func TestVerify(t *testing.T) {
+
+	p, root := proof()
+
+	if err := disclosure.Verify(p, root); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	f, ok := p.Field("BloodGrp")
+
+	var group string
+
+	if !ok || f.Decode(&group) != nil || group != "O+" {
+		t.Errorf("BloodGrp is %q", group)
+	}
+
+	if _, ok := p.Field("notes"); ok {
+		t.Errorf("notes was disclosed")
+	}
+}
+
+func TestVerifyRefusesAlteredProofs(t *testing.T) {
+
+	for name, test := range map[string]struct {
+		alter func(p *disclosure.Proof) string
+		want  error
+	}{
+		"another root": {func(p *disclosure.Proof) string {
+			return "0000000000000000000000000000000000000000000000000000000000000000"
+		}, disclosure.ErrRootMismatch},
+		"a changed value": {func(p *disclosure.Proof) string {
+			p.Fields[1].Value = `"AB-"`
+			return p.Root
+		}, disclosure.ErrRootMismatch},
+		"a changed salt": {func(p *disclosure.Proof) string {
+			p.Fields[1].Salt = "040506ff"
+			return p.Root
+		}, disclosure.ErrRootMismatch},
+		"a renamed field": {func(p *disclosure.Proof) string {
+			p.Fields[1].Field = "gender"
+			return p.Root
+		}, disclosure.ErrRootMismatch},
+		"another member": {func(p *disclosure.Proof) string {
+			p.ILNSID = "AB12345687"
+			return p.Root
+		}, disclosure.ErrNotBound},
+		"no ILNSID": {func(p *disclosure.Proof) string {
+			p.Fields = p.Fields[1:]
+			return p.Root
+		}, disclosure.ErrNotBound},
+	} {
+		p, _ := proof()
+		root := test.alter(&p)
+
+		if err := disclosure.Verify(p, root); !errors.Is(err, test.want) {
+			t.Errorf("%s: %v, want %v", name, err, test.want)
+		}
+	}
+}
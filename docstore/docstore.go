@@ -0,0 +1,156 @@
+// Package docstore keeps the files of documents attached to members outside the ledger. The chaincode anchors the
+// metadata and SHA-256 of each file with document:AttachDocument, a Store holds the bytes. Local keeps them in a
+// directory, for working offline and for tests; object stores and archives implement the same interface.
+package docstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+//==============================================================================================================================
+//	 Stored - Where a file was stored, with the size and lower case hex SHA-256 the ledger anchors.
+//==============================================================================================================================
+type Stored struct {
+	URI    string `json:"uri"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+//==============================================================================================================================
+//	 Store - Holds the files of documents. Put stores a file and returns its URI, Open reads back a file Put returned.
+//==============================================================================================================================
+type Store interface {
+	Put(ctx context.Context, r io.Reader) (Stored, error)
+	Open(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+var ErrMismatch = errors.New("docstore: the stored file does not match its hash")
+
+//==============================================================================================================================
+//	 Hash - The size and SHA-256 of a file, as Put reports them.
+//==============================================================================================================================
+func Hash(r io.Reader) (int64, string, error) {
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, r)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+//==============================================================================================================================
+//	 Verify - Reads the file at uri back from the store and checks it still has the hash anchored on the ledger.
+//==============================================================================================================================
+func Verify(ctx context.Context, store Store, uri string, sha256 string) error {
+
+	f, err := store.Open(ctx, uri)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, hash, err := Hash(f)
+
+	if err != nil {
+		return err
+	}
+
+	if hash != sha256 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+var hash_format = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+//==============================================================================================================================
+//	 Local - A store in a directory. Files are named by their SHA-256, so a file stored twice is kept once, and are
+//			 addressed by file:// URIs. The directory is created by the first Put.
+//==============================================================================================================================
+type Local struct {
+	Dir string
+}
+
+//==============================================================================================================================
+//	 NewLocal - A store in dir, which is made absolute so that the URIs returned do not depend on the working directory.
+//==============================================================================================================================
+func NewLocal(dir string) (*Local, error) {
+
+	abs, err := filepath.Abs(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Local{Dir: abs}, nil
+}
+
+func (l *Local) Put(ctx context.Context, r io.Reader) (Stored, error) {
+
+	if err := os.MkdirAll(l.Dir, 0o700); err != nil {
+		return Stored{}, err
+	}
+
+	tmp, err := os.CreateTemp(l.Dir, ".upload-*")
+
+	if err != nil {
+		return Stored{}, err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	size, hash, err := Hash(io.TeeReader(r, tmp))
+
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+
+	if err != nil {
+		return Stored{}, err
+	}
+
+	path := filepath.Join(l.Dir, hash)
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return Stored{}, err
+	}
+
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+
+	return Stored{URI: uri.String(), Size: size, SHA256: hash}, nil
+}
+
+func (l *Local) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil || u.Scheme != "file" {
+		return nil, fmt.Errorf("docstore: %s is not a file URI", uri)
+	}
+
+	path := filepath.FromSlash(u.Path)
+
+	if filepath.Dir(path) != l.Dir || !hash_format.MatchString(filepath.Base(path)) {
+		return nil, fmt.Errorf("docstore: %s is not in %s", uri, l.Dir)
+	}
+
+	return os.Open(path)
+}
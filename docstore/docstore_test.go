@@ -0,0 +1,90 @@
+package docstore_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/docstore"
+)
+
+func TestLocalStoresFilesByHash(t *testing.T) {
+
+	ctx := context.Background()
+
+	store, err := docstore.NewLocal(t.TempDir())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := store.Put(ctx, strings.NewReader("CBC: Hb 14.2 g/dL"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := docstore.Stored{
+		URI:    "file://" + filepath.ToSlash(filepath.Join(store.Dir, "edfea949798474aa50ca79259443cc574f9f835c19a7809879169569a331c6a9")),
+		Size:   17,
+		SHA256: "edfea949798474aa50ca79259443cc574f9f835c19a7809879169569a331c6a9",
+	}
+
+	if stored != want {
+		t.Fatalf("stored %+v, want %+v", stored, want)
+	}
+
+	again, err := store.Put(ctx, strings.NewReader("CBC: Hb 14.2 g/dL"))
+
+	if err != nil || again != stored {
+		t.Fatalf("stored again as %+v, %v", again, err)
+	}
+
+	entries, _ := os.ReadDir(store.Dir)
+
+	if len(entries) != 1 {
+		t.Errorf("%d files in the store, want 1", len(entries))
+	}
+
+	f, err := store.Open(ctx, stored.URI)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bytes, _ := io.ReadAll(f)
+	f.Close()
+
+	if string(bytes) != "CBC: Hb 14.2 g/dL" {
+		t.Errorf("read back %q", bytes)
+	}
+
+	if err := docstore.Verify(ctx, store, stored.URI, stored.SHA256); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(store.Dir, stored.SHA256), []byte("CBC: Hb 4.2 g/dL"), 0o600)
+
+	if err := docstore.Verify(ctx, store, stored.URI, stored.SHA256); err != docstore.ErrMismatch {
+		t.Errorf("verify of a changed file: %v", err)
+	}
+}
+
+func TestLocalOpensOnlyItsOwnFiles(t *testing.T) {
+
+	store, _ := docstore.NewLocal(t.TempDir())
+
+	for _, uri := range []string{
+		"s3://bucket/edfea949798474aa50ca79259443cc574f9f835c19a7809879169569a331c6a9",
+		"file:///etc/passwd",
+		"file://" + filepath.ToSlash(filepath.Join(store.Dir, "..", "edfea949798474aa50ca79259443cc574f9f835c19a7809879169569a331c6a9")),
+		"file://" + filepath.ToSlash(filepath.Join(store.Dir, ".upload-1")),
+	} {
+		if _, err := store.Open(context.Background(), uri); err == nil {
+			t.Errorf("opened %s", uri)
+		}
+	}
+}
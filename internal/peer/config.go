@@ -0,0 +1,18 @@
+// Package peer connects the companion services to a peer's Fabric Gateway service. Connecting needs the Fabric
+// Gateway client and is only built with -tags gateway, the configuration is always available so that configuration
+// files can be read and checked without it.
+package peer
+
+//==============================================================================================================================
+//	 Config - How to reach the peer and the identity to connect as. Paths are to PEM files.
+//==============================================================================================================================
+type Config struct {
+	Endpoint      string `json:"endpoint"`
+	Host_Override string `json:"hostOverride,omitempty"`
+	TLS_Cert      string `json:"tlsCert"`
+	MSP_ID        string `json:"mspID"`
+	Cert          string `json:"cert"`
+	Key           string `json:"key"`
+	Channel       string `json:"channel"`
+	Chaincode     string `json:"chaincode"`
+}
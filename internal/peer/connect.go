@@ -0,0 +1,89 @@
+//go:build gateway
+
+package peer
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+//==============================================================================================================================
+//	 Dial - Opens a TLS connection to the peer. Several gateways, one for each identity, may share it.
+//==============================================================================================================================
+func Dial(c Config) (*grpc.ClientConn, error) {
+
+	pem, err := os.ReadFile(c.TLS_Cert)
+
+	if err != nil {
+		return nil, fmt.Errorf("reading TLS certificate: %v", err)
+	}
+
+	cert, err := identity.CertificateFromPEM(pem)
+
+	if err != nil {
+		return nil, fmt.Errorf("TLS certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	creds := credentials.NewClientTLSFromCert(pool, c.Host_Override)
+
+	return grpc.NewClient(c.Endpoint, grpc.WithTransportCredentials(creds))
+}
+
+//==============================================================================================================================
+//	 Connect - Opens a gateway on conn as the identity in the certificate and key files given.
+//==============================================================================================================================
+func Connect(conn *grpc.ClientConn, msp_ID string, cert_path string, key_path string) (*client.Gateway, error) {
+
+	pem, err := os.ReadFile(cert_path)
+
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate: %v", err)
+	}
+
+	cert, err := identity.CertificateFromPEM(pem)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := identity.NewX509Identity(msp_ID, cert)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pem, err = os.ReadFile(key_path)
+
+	if err != nil {
+		return nil, fmt.Errorf("reading private key: %v", err)
+	}
+
+	key, err := identity.PrivateKeyFromPEM(pem)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := identity.NewPrivateKeySign(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gw, err := client.Connect(id, client.WithSign(sign), client.WithClientConnection(conn))
+
+	if err != nil {
+		return nil, fmt.Errorf("connecting to gateway: %v", err)
+	}
+
+	return gw, nil
+}
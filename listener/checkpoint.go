@@ -0,0 +1,94 @@
+package listener
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//==============================================================================================================================
+//	 Checkpoint - Where to carry on reading. Block is the block of the last event delivered and Tx_ID its transaction,
+//				  reading starts at Block and skips events up to and including Tx_ID. The zero checkpoint reads from
+//				  the first block.
+//==============================================================================================================================
+type Checkpoint struct {
+	Block uint64 `json:"block"`
+	Tx_ID string `json:"txID"`
+}
+
+//==============================================================================================================================
+//	 Checkpointer - Stores the checkpoint between runs.
+//==============================================================================================================================
+type Checkpointer interface {
+	Load() (Checkpoint, error)
+	Save(cp Checkpoint) error
+}
+
+//==============================================================================================================================
+//	 File_Checkpoint - Keeps the checkpoint in a JSON file. A missing file is the zero checkpoint. The file is replaced
+//					   by renaming a synced temporary file so a crash never leaves it half written.
+//==============================================================================================================================
+type File_Checkpoint struct {
+	Path string
+}
+
+func (f *File_Checkpoint) Load() (Checkpoint, error) {
+
+	var cp Checkpoint
+
+	bytes, err := os.ReadFile(f.Path)
+
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+
+	if err != nil {
+		return cp, fmt.Errorf("reading checkpoint: %v", err)
+	}
+
+	err = json.Unmarshal(bytes, &cp)
+
+	if err != nil {
+		return cp, fmt.Errorf("checkpoint %s is corrupt: %v", f.Path, err)
+	}
+
+	return cp, nil
+}
+
+func (f *File_Checkpoint) Save(cp Checkpoint) error {
+
+	bytes, err := json.Marshal(cp)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*")
+
+	if err != nil {
+		return fmt.Errorf("saving checkpoint: %v", err)
+	}
+
+	defer os.Remove(tmp.Name()) // Fails harmlessly once the file has been renamed
+
+	_, err = tmp.Write(bytes)
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.Path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("saving checkpoint: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package listener
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+)
+
+const SOURCE_GATEWAY = "gateway"
+const SOURCE_SCENARIO = "scenario"
+
+//==============================================================================================================================
+//	 Config - The configuration file of the listener daemon. Secrets may be given as ${VARIABLE} and are then read from
+//			  the environment.
+//
+//	{
+//	  "checkpoint": "/var/lib/medhist-events/checkpoint.json",
+//	  "source": {"type": "gateway", "peer": {"endpoint": "localhost:7051", "mspID": "Org1MSP", ...}},
+//	  "webhooks": [{"name": "notify", "url": "https://notify.example.com/hooks/medhist", "secret": "${NOTIFY_SECRET}",
+//	                "filter": {"events": ["MemberDied"]}, "maxAttempts": 8, "backoff": "2s"}]
+//	}
+//==============================================================================================================================
+type Config struct {
+	Checkpoint string        `json:"checkpoint"`
+	Source     Source_Config `json:"source"`
+	Webhooks   []Webhook     `json:"webhooks"`
+}
+
+//==============================================================================================================================
+//	 Source_Config - Type is gateway to follow a peer, or scenario to replay the events of a scenario file run on a mock
+//					 ledger.
+//==============================================================================================================================
+type Source_Config struct {
+	Type     string      `json:"type"`
+	Scenario string      `json:"scenario,omitempty"`
+	Peer     peer.Config `json:"peer,omitempty"`
+}
+
+//==============================================================================================================================
+//	 LoadConfig - Reads and checks a configuration file.
+//==============================================================================================================================
+func LoadConfig(path string) (*Config, error) {
+
+	bytes, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+
+	err = json.Unmarshal(bytes, &config)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if config.Checkpoint == "" {
+		return nil, errors.New(path + ": checkpoint must name a file")
+	}
+
+	if len(config.Webhooks) == 0 {
+		return nil, errors.New(path + ": no webhooks configured")
+	}
+
+	for i, w := range config.Webhooks {
+
+		if w.Name == "" || w.URL == "" {
+			return nil, fmt.Errorf("%s: webhook %d must have a name and url", path, i+1)
+		}
+
+		config.Webhooks[i].Secret = os.ExpandEnv(w.Secret)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,83 @@
+package listener
+
+import (
+	"encoding/json"
+)
+
+//==============================================================================================================================
+//	 Event - A chaincode event as read from the ledger. Payload is the JSON set by the chaincode, see the Events section
+//			 of the README for its schema.
+//==============================================================================================================================
+type Event struct {
+	Block   uint64          `json:"block"`
+	Tx_ID   string          `json:"txID"`
+	Name    string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+//==============================================================================================================================
+//	 Filter - Selects the events a webhook receives. Each list that is not empty must contain the event's name, one of
+//			  its ILNSIDs or the org that submitted it.
+//==============================================================================================================================
+type Filter struct {
+	Events  []string `json:"events,omitempty"`
+	ILNSIDs []string `json:"ILNSIDs,omitempty"`
+	Orgs    []string `json:"orgs,omitempty"`
+}
+
+//==============================================================================================================================
+//	 event_subject - The fields of a payload a filter looks at. Member events carry one ILNSID, MembersImported several.
+//==============================================================================================================================
+type event_subject struct {
+	ILNSID  string   `json:"ILNSID"`
+	ILNSIDs []string `json:"ILNSIDs"`
+	Org     string   `json:"org"`
+}
+
+//==============================================================================================================================
+//	 Match - Whether the filter selects e.
+//==============================================================================================================================
+func (f Filter) Match(e Event) bool {
+
+	if len(f.Events) > 0 && !contains(f.Events, e.Name) {
+		return false
+	}
+
+	if len(f.ILNSIDs) == 0 && len(f.Orgs) == 0 {
+		return true
+	}
+
+	var subject event_subject
+
+	if err := json.Unmarshal(e.Payload, &subject); err != nil {
+		return false
+	}
+
+	if len(f.Orgs) > 0 && !contains(f.Orgs, subject.Org) {
+		return false
+	}
+
+	if len(f.ILNSIDs) > 0 {
+
+		for _, ILNSID := range append(subject.ILNSIDs, subject.ILNSID) {
+			if ILNSID != "" && contains(f.ILNSIDs, ILNSID) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
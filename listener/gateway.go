@@ -0,0 +1,71 @@
+//go:build gateway
+
+package listener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+)
+
+//==============================================================================================================================
+//	 Gateway_Source - Follows the chaincode events of a channel through a peer's Fabric Gateway service. Built with
+//					  -tags gateway so that the chaincode and its tests do not depend on the gateway client.
+//==============================================================================================================================
+type Gateway_Source struct {
+	Peer peer.Config
+}
+
+func new_gateway_source(config peer.Config) (Source, error) {
+
+	if config.Endpoint == "" || config.MSP_ID == "" || config.Channel == "" || config.Chaincode == "" {
+		return nil, errors.New("gateway source needs endpoint, mspID, channel and chaincode")
+	}
+
+	return &Gateway_Source{Peer: config}, nil
+}
+
+func (s *Gateway_Source) Read(ctx context.Context, start uint64, deliver func(Event) error) error {
+
+	conn, err := peer.Dial(s.Peer)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	gw, err := peer.Connect(conn, s.Peer.MSP_ID, s.Peer.Cert, s.Peer.Key)
+
+	if err != nil {
+		return err
+	}
+
+	defer gw.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Closes the event stream when deliver fails
+
+	events, err := gw.GetNetwork(s.Peer.Channel).ChaincodeEvents(ctx, s.Peer.Chaincode, client.WithStartBlock(start))
+
+	if err != nil {
+		return errors.New("reading chaincode events: " + err.Error())
+	}
+
+	for e := range events {
+
+		err = deliver(Event{Block: e.BlockNumber, Tx_ID: e.TransactionID, Name: e.EventName, Payload: e.Payload})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("chaincode event stream closed by the peer")
+}
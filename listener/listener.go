@@ -0,0 +1,127 @@
+// Package listener forwards the chaincode events of the medical history chaincode to HTTP webhooks. Events are read
+// from a Source, a peer's event stream or a replayed mock ledger, matched against the filter of each webhook and
+// delivered, signed, with retries. A checkpoint is saved after every event so a restarted listener carries on where
+// it stopped: delivery is at least once and receivers should use the X-Medhist-Delivery header to drop repeats.
+package listener
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+//==============================================================================================================================
+//	 Listener - Reads events from Source and delivers each to the webhooks whose filter it matches. An event that can not
+//				be delivered to every matching webhook stops the listener before the checkpoint moves past it, so it
+//				is read again on the next run.
+//==============================================================================================================================
+type Listener struct {
+	Source     Source
+	Webhooks   []Webhook
+	Checkpoint Checkpointer
+	Client     *http.Client
+	Log        *log.Logger
+}
+
+//==============================================================================================================================
+//	 New - Creates a listener from a configuration.
+//==============================================================================================================================
+func New(config *Config) (*Listener, error) {
+
+	source, err := NewSource(config.Source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{
+		Source:     source,
+		Webhooks:   config.Webhooks,
+		Checkpoint: &File_Checkpoint{Path: config.Checkpoint},
+		Client:     &http.Client{Timeout: DEFAULT_TIMEOUT},
+		Log:        log.New(os.Stderr, "medhist-events: ", log.LstdFlags),
+	}, nil
+}
+
+//==============================================================================================================================
+//	 Run - Delivers events from the checkpoint on until the source ends, ctx is cancelled or an event can not be
+//		   delivered.
+//==============================================================================================================================
+func (l *Listener) Run(ctx context.Context) error {
+
+	cp, err := l.Checkpoint.Load()
+
+	if err != nil {
+		return err
+	}
+
+	l.logf("starting at block %d after transaction %q", cp.Block, cp.Tx_ID)
+
+	skipping := cp.Tx_ID != ""
+
+	return l.Source.Read(ctx, cp.Block, func(e Event) error {
+
+		if skipping && e.Block == cp.Block { // The checkpoint is part way through this block, skip what was delivered
+
+			if e.Tx_ID == cp.Tx_ID {
+				skipping = false
+			}
+
+			return nil
+		}
+
+		skipping = false
+
+		err := l.deliver(ctx, e)
+
+		if err != nil {
+			return err
+		}
+
+		cp = Checkpoint{Block: e.Block, Tx_ID: e.Tx_ID}
+
+		return l.Checkpoint.Save(cp)
+	})
+}
+
+//==============================================================================================================================
+//	 deliver - Sends the event to every webhook whose filter it matches.
+//==============================================================================================================================
+func (l *Listener) deliver(ctx context.Context, e Event) error {
+
+	for _, w := range l.Webhooks {
+
+		if !w.Filter.Match(e) {
+			continue
+		}
+
+		attempts, err := w.Deliver(ctx, l.client(), e)
+
+		if err != nil {
+			l.logf("block %d transaction %s: %s to %s failed after %d attempts: %s", e.Block, e.Tx_ID, e.Name, w.Name, attempts, err)
+			return fmt.Errorf("delivering %s of transaction %s to %s: %v", e.Name, e.Tx_ID, w.Name, err)
+		}
+
+		l.logf("block %d transaction %s: %s delivered to %s", e.Block, e.Tx_ID, e.Name, w.Name)
+	}
+
+	return nil
+}
+
+func (l *Listener) client() *http.Client {
+
+	if l.Client == nil {
+		return http.DefaultClient
+	}
+
+	return l.Client
+}
+
+func (l *Listener) logf(format string, args ...interface{}) {
+
+	if l.Log != nil {
+		l.Log.Printf(format, args...)
+	}
+}
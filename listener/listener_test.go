@@ -0,0 +1,298 @@
+package listener_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+	"github.com/ravivarmakv/SampleChainCode/listener"
+)
+
+const SECRET = "s3cret"
+
+// receiver is a webhook endpoint that checks signatures and answers with the statuses queued, then 200 to every event
+// it does not refuse.
+type receiver struct {
+	sync.Mutex
+	t        *testing.T
+	statuses []int
+	refuse   map[string]int
+	attempts int
+	events   []listener.Event
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	r.Lock()
+	defer r.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+
+	if err := listener.Verify(SECRET, req.Header.Get(listener.HEADER_SIGNATURE), body, time.Minute, time.Now()); err != nil {
+		r.t.Errorf("delivery %s: %v", req.Header.Get(listener.HEADER_DELIVERY), err)
+	}
+
+	r.attempts++
+
+	var e listener.Event
+
+	if err := json.Unmarshal(body, &e); err != nil {
+		r.t.Errorf("delivery is not an event: %s", body)
+	}
+
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	if status, ok := r.refuse[e.Name]; ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	if req.Header.Get(listener.HEADER_EVENT) != e.Name || req.Header.Get(listener.HEADER_DELIVERY) != e.Tx_ID {
+		r.t.Errorf("headers do not match event %s of %s", e.Name, e.Tx_ID)
+	}
+
+	r.events = append(r.events, e)
+}
+
+func (r *receiver) names() []string {
+
+	r.Lock()
+	defer r.Unlock()
+
+	names := []string{}
+
+	for _, e := range r.events {
+		names = append(names, e.Name)
+	}
+
+	return names
+}
+
+func ledger(t *testing.T) (*chaincodetest.Harness, func(username string, function string, args ...string)) {
+
+	h := chaincodetest.New(time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC))
+	h.Transient = map[string][]byte{"medhist.entropy": []byte("f3a91c07d25e48b6a0c4e7d19b3f5a28")}
+
+	h.AddIdentity("alice", "parents")
+	h.AddIdentity("bob", "birthday")
+
+	return h, func(username string, function string, args ...string) {
+		if _, err := h.Invoke(h.Identities[username], function, args...); err != nil {
+			t.Fatalf("%s %s: %v", username, function, err)
+		}
+	}
+}
+
+func run(t *testing.T, h *chaincodetest.Harness, checkpoint string, webhooks ...listener.Webhook) error {
+
+	l := &listener.Listener{
+		Source:     &listener.Stub_Source{Stub: h.Stub},
+		Webhooks:   webhooks,
+		Checkpoint: &listener.File_Checkpoint{Path: checkpoint},
+	}
+
+	return l.Run(context.Background())
+}
+
+func hook(name string, url string, filter listener.Filter) listener.Webhook {
+	return listener.Webhook{Name: name, URL: url, Secret: SECRET, Filter: filter, Max_Attempts: 3, Backoff: listener.Duration(time.Millisecond)}
+}
+
+func equal(a []string, b ...string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestForwardsFilteredEventsAndResumes(t *testing.T) {
+
+	h, invoke := ledger(t)
+
+	invoke("alice", "member:CreateMember", "AB12345679", "[]")
+	invoke("alice", "member:CreateMember", "CD76543215", "[]")
+	invoke("alice", "lifecycle:ParentsToBirthday", "AB12345679", "bob")
+
+	transitions := &receiver{t: t}
+	member := &receiver{t: t}
+	other_org := &receiver{t: t}
+
+	servers := map[*receiver]*httptest.Server{}
+
+	for _, r := range []*receiver{transitions, member, other_org} {
+		servers[r] = httptest.NewServer(r)
+		defer servers[r].Close()
+	}
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	webhooks := []listener.Webhook{
+		hook("transitions", servers[transitions].URL, listener.Filter{Events: []string{"MemberTransitioned"}}),
+		hook("member", servers[member].URL, listener.Filter{ILNSIDs: []string{"AB12345679"}}),
+		hook("other-org", servers[other_org].URL, listener.Filter{Orgs: []string{"Org2MSP"}}),
+	}
+
+	if err := run(t, h, checkpoint, webhooks...); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(transitions.names(), "MemberTransitioned") {
+		t.Errorf("transitions received %v", transitions.names())
+	}
+
+	if !equal(member.names(), "MemberCreated", "MemberTransitioned") {
+		t.Errorf("member received %v", member.names())
+	}
+
+	if len(other_org.names()) != 0 {
+		t.Errorf("other org received %v", other_org.names())
+	}
+
+	cp, err := (&listener.File_Checkpoint{Path: checkpoint}).Load()
+
+	if err != nil || cp.Block != 3 {
+		t.Fatalf("checkpoint after three events is %+v, %v", cp, err)
+	}
+
+	// A restarted listener delivers only what happened since
+
+	invoke("bob", "member:UpdateDOB", "AB12345679", "2024-05-20")
+
+	if err := run(t, h, checkpoint, webhooks...); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(member.names(), "MemberCreated", "MemberTransitioned", "MemberUpdated") {
+		t.Errorf("member received %v after restart", member.names())
+	}
+
+	if !equal(transitions.names(), "MemberTransitioned") {
+		t.Errorf("transitions received %v after restart", transitions.names())
+	}
+}
+
+func TestForwardsObservationsThatChangeNoField(t *testing.T) {
+
+	h, invoke := ledger(t)
+
+	invoke("alice", "member:CreateMember", "AB12345679", "[]")
+	invoke("alice", "lifecycle:ParentsToBirthday", "AB12345679", "bob")
+	invoke("bob", "member:RecordObservation", "AB12345679", `{"type": "heart_rate", "value": 140, "unit": "bpm"}`)
+
+	r := &receiver{t: t}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	if err := run(t, h, filepath.Join(t.TempDir(), "checkpoint.json"), hook("vitals", server.URL, listener.Filter{Events: []string{"MemberUpdated"}})); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(r.names(), "MemberUpdated") {
+		t.Fatalf("received %v, want the update of the heart rate", r.names())
+	}
+
+	var payload struct {
+		ILNSID  string   `json:"ILNSID"`
+		Changed []string `json:"changed"`
+	}
+
+	if err := json.Unmarshal(r.events[0].Payload, &payload); err != nil || payload.ILNSID != "AB12345679" || payload.Changed == nil || len(payload.Changed) != 0 {
+		t.Errorf("payload %s, want the member with no changed fields (%v)", r.events[0].Payload, err)
+	}
+}
+
+func TestRetriesFailedDeliveries(t *testing.T) {
+
+	h, invoke := ledger(t)
+
+	invoke("alice", "member:CreateMember", "AB12345679", "[]")
+
+	r := &receiver{t: t, statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	if err := run(t, h, filepath.Join(t.TempDir(), "checkpoint.json"), hook("retry", server.URL, listener.Filter{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.attempts != 3 || !equal(r.names(), "MemberCreated") {
+		t.Errorf("delivered %v in %d attempts, want one event in 3", r.names(), r.attempts)
+	}
+}
+
+func TestUndeliveredEventIsNotCheckpointed(t *testing.T) {
+
+	h, invoke := ledger(t)
+
+	invoke("alice", "member:CreateMember", "AB12345679", "[]")
+	invoke("alice", "lifecycle:ParentsToBirthday", "AB12345679", "bob")
+
+	// The first event is delivered, the second is refused until the receiver is fixed
+
+	r := &receiver{t: t, refuse: map[string]int{"MemberTransitioned": http.StatusBadRequest}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	if err := run(t, h, checkpoint, hook("r", server.URL, listener.Filter{})); err == nil {
+		t.Fatal("a refused delivery did not stop the listener")
+	}
+
+	if r.attempts != 2 {
+		t.Errorf("a 400 was retried, %d attempts", r.attempts)
+	}
+
+	r.refuse = nil
+
+	if err := run(t, h, checkpoint, hook("r", server.URL, listener.Filter{})); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(r.names(), "MemberCreated", "MemberTransitioned") {
+		t.Errorf("received %v, the refused event was lost or repeated", r.names())
+	}
+}
+
+func TestVerifyRejectsTamperedAndStaleDeliveries(t *testing.T) {
+
+	body := []byte(`{"event":"MemberDied"}`)
+	now := time.Now()
+	header := listener.Sign(SECRET, body, now)
+
+	if err := listener.Verify(SECRET, header, body, time.Minute, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if listener.Verify(SECRET, header, []byte(`{"event":"MemberCreated"}`), time.Minute, now) == nil {
+		t.Error("a changed body verified")
+	}
+
+	if listener.Verify("other", header, body, time.Minute, now) == nil {
+		t.Error("a delivery verified with the wrong secret")
+	}
+
+	if listener.Verify(SECRET, header, body, time.Minute, now.Add(2*time.Minute)) == nil {
+		t.Error("a stale delivery verified")
+	}
+}
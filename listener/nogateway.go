@@ -0,0 +1,13 @@
+//go:build !gateway
+
+package listener
+
+import (
+	"errors"
+
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+)
+
+func new_gateway_source(config peer.Config) (Source, error) {
+	return nil, errors.New("built without the gateway source, rebuild with -tags gateway to follow a peer")
+}
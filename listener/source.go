@@ -0,0 +1,91 @@
+package listener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+)
+
+//==============================================================================================================================
+//	 Source - A stream of chaincode events in ledger order. Read calls deliver for every event from block start on and
+//			  returns when the stream ends, ctx is cancelled or deliver fails.
+//==============================================================================================================================
+type Source interface {
+	Read(ctx context.Context, start uint64, deliver func(Event) error) error
+}
+
+//==============================================================================================================================
+//	 NewSource - Creates the source a configuration names.
+//==============================================================================================================================
+func NewSource(config Source_Config) (Source, error) {
+
+	switch config.Type {
+	case SOURCE_GATEWAY:
+		return new_gateway_source(config.Peer)
+	case SOURCE_SCENARIO:
+		return NewScenarioSource(config.Scenario)
+	}
+
+	return nil, errors.New("unknown source type " + config.Type + ", must be " + SOURCE_GATEWAY + " or " + SOURCE_SCENARIO)
+}
+
+//==============================================================================================================================
+//	 Stub_Source - Replays the events committed to a mock ledger. Every event is its own block, numbered from 1 in the
+//				   order the transactions committed. Read ends once it has delivered the last event, the stub must not
+//				   be invoked while it runs.
+//==============================================================================================================================
+type Stub_Source struct {
+	Stub *chaincodetest.MockStub
+}
+
+func (s *Stub_Source) Read(ctx context.Context, start uint64, deliver func(Event) error) error {
+
+	if start == 0 {
+		start = 1
+	}
+
+	for i := start - 1; i < uint64(len(s.Stub.Events)); i++ {
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e := s.Stub.Events[i]
+
+		err := deliver(Event{Block: i + 1, Tx_ID: e.Tx_ID, Name: e.Name, Payload: e.Payload})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 NewScenarioSource - Runs a scenario file (see chaincode/testdata/scenarios) on a mock ledger and replays the events
+//						 it emitted, for trying out webhooks without a network.
+//==============================================================================================================================
+func NewScenarioSource(path string) (*Stub_Source, error) {
+
+	sc, err := chaincodetest.LoadScenario(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := sc.Harness()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range sc.Steps {
+		if err := h.RunStep(step); err != nil {
+			return nil, errors.New(path + ": step " + step.Label() + ": " + err.Error())
+		}
+	}
+
+	return &Stub_Source{Stub: h.Stub}, nil
+}
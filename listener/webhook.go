@@ -0,0 +1,224 @@
+package listener
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DEFAULT_ATTEMPTS = 5
+const DEFAULT_BACKOFF = time.Second
+const MAX_BACKOFF = time.Minute
+const DEFAULT_TIMEOUT = 10 * time.Second
+
+//==============================================================================================================================
+//	 Headers - Sent with every delivery. The signature is t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>"> keyed
+//			   with the webhook's secret, see Verify.
+//==============================================================================================================================
+const HEADER_EVENT = "X-Medhist-Event"
+const HEADER_DELIVERY = "X-Medhist-Delivery"
+const HEADER_SIGNATURE = "X-Medhist-Signature"
+
+//==============================================================================================================================
+//	 Webhook - An HTTP endpoint that receives events as a POST of the Event JSON. Deliveries that fail with a network
+//			   error, a 5xx or a 429 are retried up to Max_Attempts times, waiting Backoff and doubling it each time.
+//			   Other responses outside 2xx fail at once. An empty Secret sends deliveries unsigned.
+//==============================================================================================================================
+type Webhook struct {
+	Name         string   `json:"name"`
+	URL          string   `json:"url"`
+	Secret       string   `json:"secret,omitempty"`
+	Filter       Filter   `json:"filter"`
+	Max_Attempts int      `json:"maxAttempts,omitempty"`
+	Backoff      Duration `json:"backoff,omitempty"`
+}
+
+//==============================================================================================================================
+//	 Duration - A time.Duration written in JSON as a string e.g. "500ms".
+//==============================================================================================================================
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New("duration must be a string e.g. \"2s\"")
+	}
+
+	parsed, err := time.ParseDuration(s)
+
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+//==============================================================================================================================
+//	 Deliver - Posts e to the webhook, retrying as configured. Returns the number of attempts made.
+//==============================================================================================================================
+func (w Webhook) Deliver(ctx context.Context, client *http.Client, e Event) (int, error) {
+
+	body, err := json.Marshal(e)
+
+	if err != nil {
+		return 0, err
+	}
+
+	attempts := w.Max_Attempts
+
+	if attempts <= 0 {
+		attempts = DEFAULT_ATTEMPTS
+	}
+
+	backoff := time.Duration(w.Backoff)
+
+	if backoff <= 0 {
+		backoff = DEFAULT_BACKOFF
+	}
+
+	for attempt := 1; ; attempt++ {
+
+		retry, err := w.post(ctx, client, e, body)
+
+		if err == nil {
+			return attempt, nil
+		}
+
+		if !retry || attempt >= attempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+
+		if backoff > MAX_BACKOFF {
+			backoff = MAX_BACKOFF
+		}
+	}
+}
+
+//==============================================================================================================================
+//	 post - Makes one delivery attempt. Returns whether a failure is worth retrying.
+//==============================================================================================================================
+func (w Webhook) post(ctx context.Context, client *http.Client, e Event, body []byte) (bool, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_EVENT, e.Name)
+	req.Header.Set(HEADER_DELIVERY, e.Tx_ID)
+
+	if w.Secret != "" {
+		req.Header.Set(HEADER_SIGNATURE, Sign(w.Secret, body, time.Now()))
+	}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) // Drain so the connection can be reused
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+
+	return retry, fmt.Errorf("%s responded %s", w.URL, resp.Status)
+}
+
+//==============================================================================================================================
+//	 Sign - The signature header for body sent at t.
+//==============================================================================================================================
+func Sign(secret string, body []byte, t time.Time) string {
+
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret string, ts string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//==============================================================================================================================
+//	 Verify - Checks the signature header of a delivery received at now. Deliveries signed more than tolerance before
+//			  or after now are rejected so that a captured delivery can not be replayed later.
+//==============================================================================================================================
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+
+	var ts, sig string
+
+	for _, part := range strings.Split(header, ",") {
+
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+
+	if ts == "" || sig == "" {
+		return errors.New("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+
+	if age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
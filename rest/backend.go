@@ -0,0 +1,214 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/chaincode/chaincodetest"
+)
+
+//==============================================================================================================================
+//	 Caller - The user a request is made for. Role is only used by backends that do not hold the user's certificate.
+//			  Data_Keys are the unwrapped data keys by ILNSID, passed in the transient map to open encrypted fields, and
+//			  Signing_Key the base64 Ed25519 key it issues credentials with.
+//==============================================================================================================================
+type Caller struct {
+	Username    string
+	Role        string
+	Data_Keys   map[string][]byte
+	Signing_Key string
+}
+
+//==============================================================================================================================
+//	 transient - The transient map of a transaction made for the caller. Every transaction is passed fresh random entropy
+//				 so that the data keys of members sealed by it can not be derived again once they are erased.
+//==============================================================================================================================
+func (c Caller) transient() map[string][]byte {
+
+	entropy := make([]byte, 32)
+	rand.Read(entropy) // Never fails, it crashes the program if randomness is unavailable
+
+	transient := map[string][]byte{chaincode.TRANSIENT_ENTROPY: entropy}
+
+	if len(c.Data_Keys) > 0 {
+		transient[chaincode.TRANSIENT_DATA_KEYS], _ = json.Marshal(c.Data_Keys) // Byte slices are encoded base64
+	}
+
+	if c.Signing_Key != "" {
+		transient[chaincode.TRANSIENT_SIGNING_KEY] = []byte(c.Signing_Key)
+	}
+
+	return transient
+}
+
+//==============================================================================================================================
+//	 ALL_MEMBER_FUNCTIONS - Transactions that read every member the caller may see rather than the members they name.
+//==============================================================================================================================
+var ALL_MEMBER_FUNCTIONS = map[string]bool{"query:GetMembers": true, "query:GetOverdueMembers": true, "registry:RotateKeys": true}
+
+//==============================================================================================================================
+//	 Data_Key_Backend - Wraps a backend for a process holding its organisation's key, Key, the base64 X25519 private key
+//						registered with registry:SetOrgKey. Before each transaction that reads members the wrapped data
+//						keys of the members it names, or of every member for ALL_MEMBER_FUNCTIONS, are fetched with
+//						query:GetDataKeys and unwrapped with the key, and only the data keys are passed on. The key is
+//						configured where the process runs and is never taken from, or sent to, anyone else.
+//==============================================================================================================================
+type Data_Key_Backend struct {
+	Backend Backend
+	Key     string
+}
+
+//==============================================================================================================================
+//	 NewDataKeyBackend - Wraps backend so that the organisation key, key, is used to unwrap data keys. With no key the
+//						 transactions are passed on as they are and encrypted fields stay encrypted.
+//==============================================================================================================================
+func NewDataKeyBackend(backend Backend, key string) *Data_Key_Backend {
+	return &Data_Key_Backend{Backend: backend, Key: key}
+}
+
+func (b *Data_Key_Backend) Submit(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error) {
+
+	caller, err := b.data_keys(ctx, caller, function, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Backend.Submit(ctx, caller, function, args...)
+}
+
+func (b *Data_Key_Backend) Evaluate(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error) {
+
+	caller, err := b.data_keys(ctx, caller, function, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Backend.Evaluate(ctx, caller, function, args...)
+}
+
+//==============================================================================================================================
+//	 data_keys - The caller with the data keys of the members the transaction reads that the organisation's key unwraps.
+//				 Members not wrapped for the key are left out and stay encrypted.
+//==============================================================================================================================
+func (b *Data_Key_Backend) data_keys(ctx context.Context, caller Caller, function string, args []string) (Caller, error) {
+
+	if b.Key == "" {
+		return caller, nil
+	}
+
+	ILNSIDs := member_arguments(function, args)
+
+	if len(ILNSIDs) == 0 && !ALL_MEMBER_FUNCTIONS[function] {
+		return caller, nil
+	}
+
+	request, _ := json.Marshal(ILNSIDs)
+
+	payload, err := b.Backend.Evaluate(ctx, Caller{Username: caller.Username, Role: caller.Role}, "query:GetDataKeys", string(request))
+
+	if err != nil {
+		return caller, err
+	}
+
+	var envelopes []chaincode.Data_Key
+
+	if err = json.Unmarshal(payload, &envelopes); err != nil {
+		return caller, errors.New("unable to read the data keys: " + err.Error())
+	}
+
+	caller.Data_Keys = map[string][]byte{}
+
+	for _, envelope := range envelopes {
+		if dk, err := chaincode.UnwrapDataKey(envelope, b.Key); err == nil {
+			caller.Data_Keys[envelope.ILNSID] = dk
+		}
+	}
+
+	return caller, nil
+}
+
+//==============================================================================================================================
+//	 member_arguments - The ILNSIDs among the arguments of a transaction, found by the argument patterns of the registry.
+//==============================================================================================================================
+func member_arguments(function string, args []string) []string {
+
+	ILNSIDs := []string{}
+
+	f, _ := find_function(function)
+
+	for i, a := range f.Arguments {
+		if a.Pattern == chaincode.ILNSID_PATTERN && i < len(args) && args[i] != "" {
+			ILNSIDs = append(ILNSIDs, args[i])
+		}
+	}
+
+	return ILNSIDs
+}
+
+//==============================================================================================================================
+//	 Backend - Runs chaincode transactions for the gateway. Function is <contract>:<Transaction> and arguments are passed
+//			   as the chaincode receives them. Submit commits the transaction, Evaluate only queries. A failed
+//			   transaction returns an error whose text holds the chaincode's JSON error.
+//==============================================================================================================================
+type Backend interface {
+	Submit(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error)
+	Evaluate(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error)
+}
+
+//==============================================================================================================================
+//	 Mock_Backend - Runs transactions in process against the mock ledger of chaincodetest, for local development and
+//					tests. Callers are given the role they ask for. The ledger clock follows Now, the wall clock unless
+//					set.
+//==============================================================================================================================
+type Mock_Backend struct {
+	Harness *chaincodetest.Harness
+	Now     func() time.Time
+
+	lock sync.Mutex
+}
+
+//==============================================================================================================================
+//	 NewMockBackend - Creates a mock backend over an empty ledger. The clock is set by the first transaction.
+//==============================================================================================================================
+func NewMockBackend() *Mock_Backend {
+	return &Mock_Backend{Harness: chaincodetest.New(time.Time{})}
+}
+
+func (b *Mock_Backend) Submit(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error) {
+	return b.transact(caller, true, function, args)
+}
+
+func (b *Mock_Backend) Evaluate(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error) {
+	return b.transact(caller, false, function, args)
+}
+
+func (b *Mock_Backend) transact(caller Caller, submit bool, function string, args []string) ([]byte, error) {
+
+	if caller.Username == "" || caller.Role == "" {
+		return nil, errors.New("the mock backend needs the username and role of the caller")
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now
+
+	if b.Now != nil {
+		now = b.Now
+	}
+
+	if clock := now().UTC(); clock.After(b.Harness.Stub.Clock) {
+		b.Harness.Stub.Clock = clock
+	}
+
+	id := b.Harness.AddIdentity(caller.Username, caller.Role)
+
+	return b.Harness.Transact(id, caller.transient(), submit, function, args...)
+}
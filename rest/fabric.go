@@ -0,0 +1,116 @@
+//go:build gateway
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+	"google.golang.org/grpc"
+)
+
+//==============================================================================================================================
+//	 Fabric_Backend - Runs transactions on a peer through its Fabric Gateway service, as the caller. Each user's
+//					  certificate and key are read from <wallet>/<username>/cert.pem and key.pem, so the chaincode sees
+//					  the caller's own identity and role. Built with -tags gateway.
+//==============================================================================================================================
+type Fabric_Backend struct {
+	Peer   peer.Config
+	Wallet string
+
+	conn     *grpc.ClientConn
+	lock     sync.Mutex
+	gateways map[string]*client.Gateway
+}
+
+//==============================================================================================================================
+//	 NewFabricBackend - Connects to the peer. Gateways for each user are opened on first use.
+//==============================================================================================================================
+func NewFabricBackend(config peer.Config, wallet string) (Backend, error) {
+
+	if config.Endpoint == "" || config.MSP_ID == "" || config.Channel == "" || config.Chaincode == "" {
+		return nil, errors.New("fabric backend needs endpoint, mspID, channel and chaincode")
+	}
+
+	conn, err := peer.Dial(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Fabric_Backend{Peer: config, Wallet: wallet, conn: conn, gateways: map[string]*client.Gateway{}}, nil
+}
+
+func (b *Fabric_Backend) Submit(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error) {
+
+	contract, err := b.contract(caller)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return contract.SubmitWithContext(ctx, function, client.WithArguments(args...), client.WithTransient(caller.transient()))
+}
+
+func (b *Fabric_Backend) Evaluate(ctx context.Context, caller Caller, function string, args ...string) ([]byte, error) {
+
+	contract, err := b.contract(caller)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return contract.EvaluateWithContext(ctx, function, client.WithArguments(args...), client.WithTransient(caller.transient()))
+}
+
+//==============================================================================================================================
+//	 contract - The chaincode as seen through the caller's gateway.
+//==============================================================================================================================
+func (b *Fabric_Backend) contract(caller Caller) (*client.Contract, error) {
+
+	if caller.Username == "" || strings.ContainsAny(caller.Username, `/\`) || strings.HasPrefix(caller.Username, ".") {
+		return nil, errors.New("invalid username " + caller.Username)
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	gw, ok := b.gateways[caller.Username]
+
+	if !ok {
+
+		dir := filepath.Join(b.Wallet, caller.Username)
+
+		var err error
+
+		gw, err = peer.Connect(b.conn, b.Peer.MSP_ID, filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+
+		if err != nil {
+			return nil, errors.New("no identity for " + caller.Username + " in the wallet: " + err.Error())
+		}
+
+		b.gateways[caller.Username] = gw
+	}
+
+	return gw.GetNetwork(b.Peer.Channel).GetContract(b.Peer.Chaincode), nil
+}
+
+//==============================================================================================================================
+//	 Close - Closes every gateway and the connection to the peer.
+//==============================================================================================================================
+func (b *Fabric_Backend) Close() error {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for _, gw := range b.gateways {
+		gw.Close()
+	}
+
+	return b.conn.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !gateway
+
+package rest
+
+import (
+	"errors"
+
+	"github.com/ravivarmakv/SampleChainCode/internal/peer"
+)
+
+func NewFabricBackend(config peer.Config, wallet string) (Backend, error) {
+	return nil, errors.New("built without the fabric backend, rebuild with -tags gateway to use a peer")
+}
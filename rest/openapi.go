@@ -0,0 +1,279 @@
+package rest
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+)
+
+const OPENAPI_VERSION = "3.0.3"
+
+//==============================================================================================================================
+//	 Schema - The part of an OpenAPI schema object the gateway uses.
+//==============================================================================================================================
+type Schema struct {
+	Ref            string             `json:"$ref,omitempty"`
+	Type           string             `json:"type,omitempty"`
+	Description    string             `json:"description,omitempty"`
+	Pattern        string             `json:"pattern,omitempty"`
+	Enum           []string           `json:"enum,omitempty"`
+	Items          *Schema            `json:"items,omitempty"`
+	Properties     map[string]*Schema `json:"properties,omitempty"`
+	Additional     *Schema            `json:"additionalProperties,omitempty"`
+	Required       []string           `json:"required,omitempty"`
+	Min_Properties int                `json:"minProperties,omitempty"`
+}
+
+//==============================================================================================================================
+//	 OpenAPI - Describes the gateway as an OpenAPI 3 document. Arguments, their patterns and descriptions come from the
+//			   chaincode's function registry and response schemas from the chaincode's types, so the description
+//			   follows the chaincode it is built with.
+//==============================================================================================================================
+func OpenAPI() map[string]interface{} {
+
+	create, _ := find_function("member:CreateMember")
+	update, _ := find_function("member:UpdateMember")
+	erase, _ := find_function("member:EraseMember")
+	get, _ := find_function("query:GetMemberDetails")
+	list, _ := find_function("query:GetMembers")
+
+	id := path_parameter("id", chaincode.ILNSID_ARG)
+
+	patch := &Schema{Type: "object", Description: argument(update, "patch").Description, Properties: map[string]*Schema{}, Min_Properties: 1}
+
+	for _, field := range chaincode.PATCH_FIELDS {
+		patch.Properties[field] = &Schema{Type: "string"}
+	}
+
+	transitions := []string{}
+	descriptions := []string{}
+
+	for _, f := range chaincode.Functions() {
+		if strings.HasPrefix(f.Name, chaincode.LIFECYCLE_CONTRACT+":") {
+			name := strings.TrimPrefix(f.Name, chaincode.LIFECYCLE_CONTRACT+":")
+			transitions = append(transitions, name)
+			descriptions = append(descriptions, name+": "+f.Description)
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": OPENAPI_VERSION,
+		"info": map[string]interface{}{
+			"title":       "Medical history gateway",
+			"version":     "1.0.0",
+			"description": "Resource API over the medical history chaincode. Callers are named by the " + HEADER_USER + " and " + HEADER_ROLE + " headers. Encrypted fields are opened with the key of the gateway's organisation, configured where the gateway runs and never sent by clients or to the peers.",
+		},
+		"paths": map[string]interface{}{
+			"/members": map[string]interface{}{
+				"post": operation(create, nil, arguments_schema(create), "201", ref("Invoke_Result")),
+				"get": operation(list, []interface{}{map[string]interface{}{
+					"name":        "status",
+					"in":          "query",
+					"description": "Only members in this status, by name or number",
+					"schema":      &Schema{Type: "string", Enum: chaincode.STATUS_NAMES},
+				}}, nil, "200", &Schema{Type: "array", Items: ref("Member")}),
+			},
+			"/members/{id}": map[string]interface{}{
+				"get":    operation(get, []interface{}{id}, nil, "200", ref("Member")),
+				"patch":  operation(update, []interface{}{id}, patch, "200", ref("Invoke_Result")),
+				"delete": operation(erase, []interface{}{id}, nil, "200", ref("Invoke_Result")),
+			},
+			"/members/{id}/transitions/{name}": map[string]interface{}{
+				"post": map[string]interface{}{
+					"operationId": "transition",
+					"summary":     "Runs a lifecycle transition. " + strings.Join(descriptions, ". "),
+					"parameters": []interface{}{id, map[string]interface{}{
+						"name":     "name",
+						"in":       "path",
+						"required": true,
+						"schema":   &Schema{Type: "string", Enum: transitions},
+					}},
+					"requestBody": request_body(&Schema{Type: "object", Properties: map[string]*Schema{
+						"recipient": schema(chaincode.RECIPIENT_ARG),
+					}}, false),
+					"responses": responses("200", ref("Invoke_Result")),
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"schemas": map[string]*Schema{
+				"Member":          type_schema(reflect.TypeOf(chaincode.Member{})),
+				"Invoke_Result":   type_schema(reflect.TypeOf(chaincode.Invoke_Result{})),
+				"Chaincode_Error": error_schema(),
+			},
+		},
+	}
+}
+
+func find_function(name string) (chaincode.Function, bool) {
+
+	for _, f := range chaincode.Functions() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return chaincode.Function{}, false
+}
+
+func argument(f chaincode.Function, name string) chaincode.Argument {
+
+	for _, a := range f.Arguments {
+		if a.Name == name {
+			return a
+		}
+	}
+
+	return chaincode.Argument{Name: name}
+}
+
+//==============================================================================================================================
+//	 schema - The schema of a registry argument.
+//==============================================================================================================================
+func schema(a chaincode.Argument) *Schema {
+
+	s := &Schema{Type: a.Type, Description: a.Description, Pattern: a.Pattern}
+
+	if a.Type == chaincode.ARG_ARRAY {
+		s.Items = &Schema{Type: "string"}
+	}
+
+	return s
+}
+
+//==============================================================================================================================
+//	 arguments_schema - A request body holding every argument of f as a property, required unless optional.
+//==============================================================================================================================
+func arguments_schema(f chaincode.Function) *Schema {
+
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for _, a := range f.Arguments {
+
+		s.Properties[a.Name] = schema(a)
+
+		if !a.Optional {
+			s.Required = append(s.Required, a.Name)
+		}
+	}
+
+	return s
+}
+
+func path_parameter(name string, a chaincode.Argument) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          "path",
+		"required":    true,
+		"description": a.Description,
+		"schema":      &Schema{Type: a.Type, Pattern: a.Pattern},
+	}
+}
+
+func operation(f chaincode.Function, parameters []interface{}, body *Schema, status string, response *Schema) map[string]interface{} {
+
+	op := map[string]interface{}{
+		"operationId": f.Name,
+		"summary":     f.Description,
+		"responses":   responses(status, response),
+	}
+
+	if parameters != nil {
+		op["parameters"] = parameters
+	}
+
+	if body != nil {
+		op["requestBody"] = request_body(body, true)
+	}
+
+	return op
+}
+
+func request_body(s *Schema, required bool) map[string]interface{} {
+	return map[string]interface{}{
+		"required": required,
+		"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": s}},
+	}
+}
+
+//==============================================================================================================================
+//	 responses - The success response and every error status the gateway may answer with.
+//==============================================================================================================================
+func responses(status string, s *Schema) map[string]interface{} {
+
+	failed := map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("Chaincode_Error")}}
+
+	return map[string]interface{}{
+		status: map[string]interface{}{"description": "Success", "content": map[string]interface{}{"application/json": map[string]interface{}{"schema": s}}},
+		"400":  map[string]interface{}{"description": chaincode.VALIDATION_FAILED, "content": failed},
+		"403":  map[string]interface{}{"description": chaincode.PERMISSION_DENIED, "content": failed},
+		"404":  map[string]interface{}{"description": chaincode.NOT_FOUND, "content": failed},
+		"409":  map[string]interface{}{"description": chaincode.INVALID_STATE + " or " + chaincode.CONFLICT, "content": failed},
+		"500":  map[string]interface{}{"description": chaincode.INTERNAL, "content": failed},
+		"502":  map[string]interface{}{"description": "The backend failed without a chaincode error", "content": failed},
+	}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+//==============================================================================================================================
+//	 type_schema - The schema of a chaincode type, from its JSON tags.
+//==============================================================================================================================
+func type_schema(t reflect.Type) *Schema {
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: type_schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", Additional: type_schema(t.Elem())}
+	case reflect.Ptr:
+		return type_schema(t.Elem())
+	case reflect.Struct:
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+		for i := 0; i < t.NumField(); i++ {
+
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+
+			s.Properties[name] = type_schema(field.Type)
+		}
+
+		return s
+	}
+
+	return &Schema{}
+}
+
+func error_schema() *Schema {
+
+	s := type_schema(reflect.TypeOf(chaincode.Chaincode_Error{}))
+
+	s.Properties["code"].Enum = []string{
+		chaincode.PERMISSION_DENIED, chaincode.INVALID_STATE, chaincode.NOT_FOUND,
+		chaincode.VALIDATION_FAILED, chaincode.CONFLICT, chaincode.INTERNAL,
+	}
+	s.Required = []string{"code", "message"}
+
+	return s
+}
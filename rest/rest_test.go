@@ -0,0 +1,233 @@
+package rest_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+	"github.com/ravivarmakv/SampleChainCode/rest"
+)
+
+type client struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+// do sends a request as username with role and checks the status it is answered with. The body is decoded into out if
+// given.
+func (c *client) do(method string, path string, username string, role string, body string, status int, out interface{}) {
+
+	req, _ := http.NewRequest(method, c.server.URL+path, strings.NewReader(body))
+
+	if username != "" {
+		req.Header.Set(rest.HEADER_USER, username)
+		req.Header.Set(rest.HEADER_ROLE, role)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+
+	defer resp.Body.Close()
+
+	payload, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != status {
+		c.t.Fatalf("%s %s answered %d, want %d: %s", method, path, resp.StatusCode, status, payload)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(payload, out); err != nil {
+			c.t.Fatalf("%s %s: %v: %s", method, path, err, payload)
+		}
+	}
+}
+
+func new_client(t *testing.T) *client {
+
+	server := httptest.NewServer((&rest.Server{Backend: rest.NewMockBackend()}).Handler())
+	t.Cleanup(server.Close)
+
+	return &client{t: t, server: server}
+}
+
+func TestMemberResources(t *testing.T) {
+
+	c := new_client(t)
+
+	var result chaincode.Invoke_Result
+
+	c.do("POST", "/members", "alice", chaincode.PARENTS, `{"ILNSID":"AB12345679","parents":[]}`, http.StatusCreated, &result)
+
+	if result.Custodian != "alice" || result.Status != chaincode.STATE_CARRYING {
+		t.Fatalf("created %+v", result)
+	}
+
+	c.do("POST", "/members/AB12345679/transitions/ParentsToBirthday", "alice", chaincode.PARENTS, `{"recipient":"bob"}`, http.StatusOK, &result)
+
+	if result.Custodian != "bob" || result.Status != chaincode.STATE_BIRTH {
+		t.Fatalf("transitioned %+v", result)
+	}
+
+	c.do("PATCH", "/members/AB12345679", "bob", chaincode.BIRTHDAY, `{"DOB":"2024-05-20","gender":"female","BloodGrp":"O+","Weight":"3.4kg"}`, http.StatusOK, &result)
+
+	if strings.Join(result.Changed, ",") != "DOB,gender,BloodGrp,Weight" {
+		t.Fatalf("patched %+v", result)
+	}
+
+	var members []chaincode.Member
+
+	c.do("GET", "/members?status=birth", "bob", chaincode.BIRTHDAY, "", http.StatusOK, &members)
+
+	if len(members) != 1 || members[0].DOB != "2024-05-20" {
+		t.Fatalf("members in birth %+v", members)
+	}
+
+	c.do("GET", "/members?status=2", "bob", chaincode.BIRTHDAY, "", http.StatusOK, &members)
+
+	if len(members) != 0 {
+		t.Fatalf("members in healthy %+v", members)
+	}
+
+	var m chaincode.Member
+
+	c.do("GET", "/members/AB12345679", "bob", chaincode.BIRTHDAY, "", http.StatusOK, &m)
+
+	if m.ILNSID != "AB12345679" || m.BloodGrp != "O+" {
+		t.Fatalf("member %+v", m)
+	}
+}
+
+func TestDataKeys(t *testing.T) {
+
+	ctx := context.Background()
+	inner := rest.NewMockBackend()
+
+	key, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	backend := rest.NewDataKeyBackend(inner, base64.StdEncoding.EncodeToString(key.Bytes()))
+
+	root := rest.Caller{Username: "root", Role: chaincode.ADMIN}
+	alice := rest.Caller{Username: "alice", Role: chaincode.PARENTS}
+	bob := rest.Caller{Username: "bob", Role: chaincode.BIRTHDAY}
+
+	steps := []struct {
+		caller   rest.Caller
+		function string
+		args     []string
+	}{
+		{root, "registry:SetOrgKey", []string{"Org1MSP", base64.StdEncoding.EncodeToString(key.PublicKey().Bytes())}},
+		{alice, "member:CreateMember", []string{"AB12345679", "[]"}},
+		{alice, "lifecycle:ParentsToBirthday", []string{"AB12345679", "bob"}},
+		{bob, "member:UpdateMember", []string{"AB12345679", `{"DOB":"2024-05-20","notes":"Follow-up in June"}`}},
+		{bob, "member:UpdateMember", []string{"AB12345679", `{"notes":"Discharged"}`}},
+	}
+
+	for _, step := range steps {
+		if _, err := backend.Submit(ctx, step.caller, step.function, step.args...); err != nil {
+			t.Fatalf("%s: %v", step.function, err)
+		}
+	}
+
+	for _, keyed := range []bool{true, false} {
+
+		var m chaincode.Member
+
+		reader := backend
+
+		if !keyed {
+			reader = rest.NewDataKeyBackend(inner, "")
+		}
+
+		payload, err := reader.Evaluate(ctx, bob, "query:GetMemberDetails", "AB12345679")
+
+		if err != nil {
+			t.Fatalf("GetMemberDetails: %v", err)
+		}
+
+		json.Unmarshal(payload, &m)
+
+		if keyed != (m.DOB == "2024-05-20" && m.Notes == "Discharged") {
+			t.Fatalf("read with the organisation key %t: %+v", keyed, m)
+		}
+	}
+}
+
+func TestErrorStatuses(t *testing.T) {
+
+	c := new_client(t)
+
+	c.do("POST", "/members", "alice", chaincode.PARENTS, `{"ILNSID":"AB12345679","parents":[]}`, http.StatusCreated, nil)
+
+	var ce chaincode.Chaincode_Error
+
+	c.do("POST", "/members", "alice", chaincode.PARENTS, `{"ILNSID":"AB12345679","parents":[]}`, http.StatusConflict, &ce)
+
+	if ce.Code != chaincode.CONFLICT {
+		t.Fatalf("duplicate member %+v", ce)
+	}
+
+	c.do("POST", "/members", "alice", chaincode.PARENTS, `{"ILNSID":"A1","parents":[]}`, http.StatusBadRequest, &ce)
+
+	if ce.Code != chaincode.VALIDATION_FAILED || ce.Details["field"] != "ILNSID" {
+		t.Fatalf("invalid ILNSID %+v", ce)
+	}
+
+	c.do("POST", "/members", "alice", chaincode.PARENTS, `{"ILNSID":`, http.StatusBadRequest, nil)
+	c.do("POST", "/members", "", "", `{"ILNSID":"AB12345687","parents":[]}`, http.StatusUnauthorized, nil)
+	c.do("PATCH", "/members/AB12345679", "mallory", chaincode.HEALTHY, `{"DOB":"2024-05-20"}`, http.StatusForbidden, nil)
+	c.do("POST", "/members/AB12345679/transitions/HealthyToIllness", "alice", chaincode.PARENTS, `{"recipient":"dave"}`, http.StatusConflict, &ce)
+
+	if ce.Code != chaincode.INVALID_STATE {
+		t.Fatalf("wrong state %+v", ce)
+	}
+
+	c.do("POST", "/members/AB12345679/transitions/Resurrect", "alice", chaincode.PARENTS, `{}`, http.StatusNotFound, nil)
+	c.do("GET", "/members/AB76543219", "alice", chaincode.PARENTS, "", http.StatusNotFound, nil)
+	c.do("GET", "/members?status=asleep", "alice", chaincode.PARENTS, "", http.StatusBadRequest, nil)
+}
+
+func TestOpenAPI(t *testing.T) {
+
+	c := new_client(t)
+
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+
+	c.do("GET", "/openapi.json", "", "", "", http.StatusOK, &doc)
+
+	want := map[string][]string{
+		"/members":                         {"post", "get"},
+		"/members/{id}":                    {"get", "patch"},
+		"/members/{id}/transitions/{name}": {"post"},
+	}
+
+	for path, methods := range want {
+		for _, method := range methods {
+			if _, ok := doc.Paths[path][method]; !ok {
+				t.Errorf("no %s %s in the OpenAPI description", method, path)
+			}
+		}
+	}
+
+	post := string(doc.Paths["/members"]["post"])
+
+	if !strings.Contains(post, chaincode.ILNSID_ARG.Pattern) {
+		t.Errorf("POST /members does not carry the ILNSID pattern of the registry: %s", post)
+	}
+
+	if !strings.Contains(string(doc.Paths["/members/{id}/transitions/{name}"]["post"]), "DeadMember") {
+		t.Errorf("transitions are not listed")
+	}
+}
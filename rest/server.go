@@ -0,0 +1,355 @@
+// Package rest is an HTTP gateway to the medical history chaincode. It maps resource style requests on /members to
+// chaincode transactions run by a pluggable Backend, and serves an OpenAPI description generated from the chaincode's
+// function registry at /openapi.json.
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ravivarmakv/SampleChainCode/chaincode"
+)
+
+//==============================================================================================================================
+//	 Caller headers - Who a request is made for. Authenticating the user is left to a proxy in front of the gateway.
+//					  Encrypted fields are opened by the gateway's own organisation key, see Data_Key_Backend.
+//==============================================================================================================================
+const HEADER_USER = "X-Medhist-User"
+const HEADER_ROLE = "X-Medhist-Role"
+
+const MAX_BODY = 1 << 20
+
+//==============================================================================================================================
+//	 STATUS_CODES - The HTTP status each chaincode error code is answered with.
+//==============================================================================================================================
+var STATUS_CODES = map[string]int{
+	chaincode.PERMISSION_DENIED: http.StatusForbidden,
+	chaincode.INVALID_STATE:     http.StatusConflict,
+	chaincode.NOT_FOUND:         http.StatusNotFound,
+	chaincode.VALIDATION_FAILED: http.StatusBadRequest,
+	chaincode.CONFLICT:          http.StatusConflict,
+	chaincode.INTERNAL:          http.StatusInternalServerError,
+}
+
+//==============================================================================================================================
+//	 Server - The gateway's HTTP handlers.
+//==============================================================================================================================
+type Server struct {
+	Backend Backend
+}
+
+//==============================================================================================================================
+//	 Handler - Routes requests to the handlers.
+//==============================================================================================================================
+func (s *Server) Handler() http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /members", identified(s.create_member))
+	mux.HandleFunc("GET /members", identified(s.list_members))
+	mux.HandleFunc("GET /members/{id}", identified(s.get_member))
+	mux.HandleFunc("PATCH /members/{id}", identified(s.update_member))
+	mux.HandleFunc("DELETE /members/{id}", identified(s.erase_member))
+	mux.HandleFunc("POST /members/{id}/transitions/{name}", identified(s.transition))
+	mux.HandleFunc("GET /openapi.json", s.openapi)
+
+	return mux
+}
+
+//==============================================================================================================================
+//	 Create_Member_Request - The body of POST /members.
+//==============================================================================================================================
+type Create_Member_Request struct {
+	ILNSID  string   `json:"ILNSID"`
+	Parents []string `json:"parents"`
+}
+
+func (s *Server) create_member(w http.ResponseWriter, r *http.Request) {
+
+	var req Create_Member_Request
+
+	if !read_body(w, r, &req) {
+		return
+	}
+
+	if req.Parents == nil {
+		req.Parents = []string{}
+	}
+
+	parents, _ := json.Marshal(req.Parents)
+
+	payload, err := s.Backend.Submit(r.Context(), caller(r), "member:CreateMember", req.ILNSID, string(parents))
+
+	respond(w, http.StatusCreated, payload, err)
+}
+
+//==============================================================================================================================
+//	 list_members - GET /members returns the members the caller may see, only those in a status if status is given by
+//					name or number.
+//==============================================================================================================================
+func (s *Server) list_members(w http.ResponseWriter, r *http.Request) {
+
+	status := -1
+
+	if value := r.URL.Query().Get("status"); value != "" {
+
+		var ok bool
+
+		status, ok = parse_status(value)
+
+		if !ok {
+			write_error(w, http.StatusBadRequest, &chaincode.Chaincode_Error{
+				Code:    chaincode.VALIDATION_FAILED,
+				Message: "Invalid value passed for status: must be one of " + strings.Join(chaincode.STATUS_NAMES, ", ") + " or its number",
+				Details: map[string]interface{}{"field": "status", "value": value},
+			})
+			return
+		}
+	}
+
+	payload, err := s.Backend.Evaluate(r.Context(), caller(r), "query:GetMembers")
+
+	if err != nil || status < 0 {
+		respond(w, http.StatusOK, payload, err)
+		return
+	}
+
+	var members []chaincode.Member
+
+	if err := json.Unmarshal(payload, &members); err != nil {
+		respond(w, 0, nil, errors.New("unreadable response from the chaincode: "+err.Error()))
+		return
+	}
+
+	selected := []chaincode.Member{}
+
+	for _, m := range members {
+		if m.Status == status {
+			selected = append(selected, m)
+		}
+	}
+
+	payload, _ = json.Marshal(selected)
+
+	respond(w, http.StatusOK, payload, nil)
+}
+
+func (s *Server) get_member(w http.ResponseWriter, r *http.Request) {
+
+	payload, err := s.Backend.Evaluate(r.Context(), caller(r), "query:GetMemberDetails", r.PathValue("id"))
+
+	respond(w, http.StatusOK, payload, err)
+}
+
+//==============================================================================================================================
+//	 update_member - PATCH /members/{id} sets the fields in the body, see member:UpdateMember.
+//==============================================================================================================================
+func (s *Server) update_member(w http.ResponseWriter, r *http.Request) {
+
+	var patch map[string]string
+
+	if !read_body(w, r, &patch) {
+		return
+	}
+
+	body, _ := json.Marshal(patch)
+
+	payload, err := s.Backend.Submit(r.Context(), caller(r), "member:UpdateMember", r.PathValue("id"), string(body))
+
+	respond(w, http.StatusOK, payload, err)
+}
+
+//==============================================================================================================================
+//	 erase_member - DELETE /members/{id} erases the member, see member:EraseMember.
+//==============================================================================================================================
+func (s *Server) erase_member(w http.ResponseWriter, r *http.Request) {
+
+	payload, err := s.Backend.Submit(r.Context(), caller(r), "member:EraseMember", r.PathValue("id"))
+
+	respond(w, http.StatusOK, payload, err)
+}
+
+//==============================================================================================================================
+//	 Transition_Request - The body of POST /members/{id}/transitions/{name}. DeadMember takes no recipient.
+//==============================================================================================================================
+type Transition_Request struct {
+	Recipient string `json:"recipient"`
+}
+
+func (s *Server) transition(w http.ResponseWriter, r *http.Request) {
+
+	f, ok := transition_function(r.PathValue("name"))
+
+	if !ok {
+		write_error(w, http.StatusNotFound, &chaincode.Chaincode_Error{
+			Code:    chaincode.NOT_FOUND,
+			Message: "No transition named " + r.PathValue("name"),
+			Details: map[string]interface{}{"transitions": transition_names()},
+		})
+		return
+	}
+
+	args := []string{r.PathValue("id")}
+
+	if len(f.Arguments) > 1 {
+
+		var req Transition_Request
+
+		if !read_body(w, r, &req) {
+			return
+		}
+
+		args = append(args, req.Recipient)
+	}
+
+	payload, err := s.Backend.Submit(r.Context(), caller(r), f.Name, args...)
+
+	respond(w, http.StatusOK, payload, err)
+}
+
+func (s *Server) openapi(w http.ResponseWriter, r *http.Request) {
+
+	payload, _ := json.Marshal(OpenAPI())
+
+	respond(w, http.StatusOK, payload, nil)
+}
+
+//==============================================================================================================================
+//	 transition_function - The lifecycle transaction a transition is named after, e.g. ParentsToBirthday.
+//==============================================================================================================================
+func transition_function(name string) (chaincode.Function, bool) {
+	return find_function(chaincode.LIFECYCLE_CONTRACT + ":" + name)
+}
+
+func transition_names() []string {
+
+	names := []string{}
+
+	for _, f := range chaincode.Functions() {
+		if strings.HasPrefix(f.Name, chaincode.LIFECYCLE_CONTRACT+":") {
+			names = append(names, strings.TrimPrefix(f.Name, chaincode.LIFECYCLE_CONTRACT+":"))
+		}
+	}
+
+	return names
+}
+
+func parse_status(value string) (int, bool) {
+
+	for i, name := range chaincode.STATUS_NAMES {
+		if strings.EqualFold(value, name) {
+			return i, true
+		}
+	}
+
+	status, err := strconv.Atoi(value)
+
+	if err != nil || status < 0 || status >= len(chaincode.STATUS_NAMES) {
+		return 0, false
+	}
+
+	return status, true
+}
+
+//==============================================================================================================================
+//	 identified - Answers 401 to requests that do not name their caller.
+//==============================================================================================================================
+func identified(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Header.Get(HEADER_USER) == "" {
+			write_error(w, http.StatusUnauthorized, &chaincode.Chaincode_Error{
+				Code:    chaincode.PERMISSION_DENIED,
+				Message: "No caller given, set " + HEADER_USER,
+				Details: map[string]interface{}{"header": HEADER_USER},
+			})
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func caller(r *http.Request) Caller {
+	return Caller{Username: r.Header.Get(HEADER_USER), Role: r.Header.Get(HEADER_ROLE)}
+}
+
+//==============================================================================================================================
+//	 read_body - Decodes the JSON body of a request into v, answering 400 if it can not.
+//==============================================================================================================================
+func read_body(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+
+	err := json.NewDecoder(io.LimitReader(r.Body, MAX_BODY)).Decode(v)
+
+	if err != nil {
+		write_error(w, http.StatusBadRequest, &chaincode.Chaincode_Error{
+			Code:    chaincode.VALIDATION_FAILED,
+			Message: "Invalid request body: " + err.Error(),
+			Details: map[string]interface{}{"field": "body"},
+		})
+		return false
+	}
+
+	return true
+}
+
+//==============================================================================================================================
+//	 respond - Writes the payload of a transaction with status, or its error.
+//==============================================================================================================================
+func respond(w http.ResponseWriter, status int, payload []byte, err error) {
+
+	if err != nil {
+
+		ce, ok := ParseError(err)
+
+		if !ok {
+			write_error(w, http.StatusBadGateway, &chaincode.Chaincode_Error{Code: chaincode.INTERNAL, Message: err.Error()})
+			return
+		}
+
+		write_error(w, STATUS_CODES[ce.Code], ce)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload)
+}
+
+func write_error(w http.ResponseWriter, status int, ce *chaincode.Chaincode_Error) {
+
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(ce.Error()))
+}
+
+//==============================================================================================================================
+//	 ParseError - Finds the chaincode's JSON error in the text of a failed transaction. Peers and the gateway client wrap
+//				  the chaincode's message in their own.
+//==============================================================================================================================
+func ParseError(err error) (*chaincode.Chaincode_Error, bool) {
+
+	text := err.Error()
+
+	i := strings.Index(text, `{"code":`)
+
+	if i < 0 {
+		return nil, false
+	}
+
+	var ce chaincode.Chaincode_Error
+
+	if json.NewDecoder(strings.NewReader(text[i:])).Decode(&ce) != nil || ce.Code == "" {
+		return nil, false
+	}
+
+	return &ce, true
+}